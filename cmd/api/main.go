@@ -3,7 +3,9 @@ package main
 import (
 	"log"
 	"os"
+	"strings"
 	_ "tracking/docs"
+	"tracking/internal/auth"
 	"tracking/internal/db"
 	"tracking/internal/routes"
 
@@ -27,16 +29,44 @@ func main() {
 		log.Println("Aviso: No se encontró el archivo .env, usando variables de entorno del sistema")
 	}
 	log.Printf("DEBUG: La URL de la base es: %s", os.Getenv("DB_URL"))
+	if err := auth.LoadKeys(); err != nil {
+		log.Fatal("No se pudieron cargar las claves JWT:", err)
+	}
 	pool, err := db.ConnectPostgres()
 	if err != nil {
 		log.Fatal("No se pudo conectar a la BD:", err)
 	}
 	rdb := db.ConnectRedis()
 	r := gin.Default()
+	// Sin proxies de confianza ClientIP ignora X-Forwarded-For; lo usan el bloqueo de login por IP y allowed_ips de las API keys
+	if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatal("TRUSTED_PROXIES inválido:", err)
+	}
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	routes.RegisterUserRoutes(r, pool, rdb)
 	routes.RegisterOrderRoutes(r, pool, rdb)
 	routes.RegisterProductRoutes(r, pool)
+	routes.RegisterWebhookRoutes(r, pool)
+	routes.RegisterNotificationRoutes(r, pool)
+	routes.RegisterAddressRoutes(r, pool)
+	routes.RegisterGeoRoutes(r, rdb)
+	routes.RegisterStoreRoutes(r, pool)
+	routes.RegisterReviewRoutes(r, pool)
+	routes.RegisterEarningRoutes(r, pool)
+	routes.RegisterAPIKeyRoutes(r, pool)
+	routes.RegisterPermissionRoutes(r, pool)
+	routes.StartEventWorkers(pool, rdb)
 
 	r.Run(":8081")
 }
+
+// trustedProxies lee TRUSTED_PROXIES (IPs o CIDRs separados por comas, ej. la del balanceador)
+func trustedProxies() []string {
+	var proxies []string
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
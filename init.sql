@@ -49,4 +49,452 @@ CREATE TABLE IF NOT EXISTS products (
 ALTER TABLE products ADD COLUMN IF NOT EXISTS is_active BOOLEAN NOT NULL DEFAULT true;
 
 -- Índice para búsquedas geográficas rápidas
-CREATE INDEX IF NOT EXISTS idx_drivers_location ON drivers USING GIST(last_location);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_drivers_location ON drivers USING GIST(last_location);
+
+-- 5. Timeline de pedidos (eventos como la llegada del driver al local o al cliente)
+CREATE TABLE IF NOT EXISTS order_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    type VARCHAR(40) NOT NULL,
+    actor_id UUID REFERENCES users(id),
+    data JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (order_id, type)
+);
+
+-- 6. Log de anomalías de GPS (saltos imposibles, timestamps viejos, posiciones repetidas)
+CREATE TABLE IF NOT EXISTS driver_location_anomalies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    driver_id UUID NOT NULL REFERENCES users(id),
+    order_id UUID REFERENCES orders(id) ON DELETE SET NULL,
+    type VARCHAR(40) NOT NULL,
+    lat DOUBLE PRECISION NOT NULL,
+    lng DOUBLE PRECISION NOT NULL,
+    prev_lat DOUBLE PRECISION,
+    prev_lng DOUBLE PRECISION,
+    speed_kmh DOUBLE PRECISION,
+    rejected BOOLEAN NOT NULL DEFAULT false,
+    recorded_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_location_anomalies_driver ON driver_location_anomalies(driver_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_location_anomalies_order ON driver_location_anomalies(order_id, created_at DESC);
+
+-- 7. Historial de ubicaciones de cada driver (incluye los fixes subidos en lote tras perder señal)
+CREATE TABLE IF NOT EXISTS driver_location_history (
+    id BIGSERIAL PRIMARY KEY,
+    driver_id UUID NOT NULL REFERENCES users(id),
+    order_id UUID REFERENCES orders(id) ON DELETE SET NULL,
+    lat DOUBLE PRECISION NOT NULL,
+    lng DOUBLE PRECISION NOT NULL,
+    recorded_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (driver_id, recorded_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_location_history_order ON driver_location_history(order_id, recorded_at);
+
+-- 8. Links públicos para compartir el tracking de un pedido sin login
+CREATE TABLE IF NOT EXISTS order_share_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    created_by UUID NOT NULL REFERENCES users(id),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_links_order ON order_share_links(order_id);
+
+-- 9. Webhooks salientes para eventos del ciclo de vida de los pedidos
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT[] NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subscription_id UUID NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id UUID NOT NULL,
+    event_type VARCHAR(40) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'DELIVERED', 'DEAD')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_status_code INT,
+    last_error TEXT,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'PENDING';
+
+-- 10. Outbox transaccional: cada evento del pedido se escribe en la misma transacción
+-- que el cambio de estado y un relay lo publica después en Redis Streams
+CREATE TABLE IF NOT EXISTS outbox (
+    id UUID PRIMARY KEY,
+    aggregate_id UUID NOT NULL,
+    event_type VARCHAR(40) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'PUBLISHED')),
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(created_at) WHERE status = 'PENDING';
+
+-- 11. Notificaciones: preferencias por usuario y registro de cada envío con su estado
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    locale VARCHAR(5) NOT NULL DEFAULT 'es',
+    email_enabled BOOLEAN NOT NULL DEFAULT true,
+    sms_enabled BOOLEAN NOT NULL DEFAULT false,
+    push_enabled BOOLEAN NOT NULL DEFAULT false,
+    phone VARCHAR(30),
+    push_token TEXT,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    order_id UUID REFERENCES orders(id) ON DELETE SET NULL,
+    event_id UUID,
+    channel VARCHAR(10) NOT NULL CHECK (channel IN ('email', 'sms', 'push')),
+    template VARCHAR(50) NOT NULL,
+    recipient TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'SENT', 'FAILED', 'SKIPPED')),
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (event_id, user_id, channel)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, created_at DESC);
+
+-- 12. Libreta de direcciones del cliente, geocodificadas una sola vez al guardarlas
+CREATE TABLE IF NOT EXISTS customer_addresses (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    label VARCHAR(50) NOT NULL,
+    address TEXT NOT NULL,
+    apartment VARCHAR(100),
+    instructions TEXT,
+    lat DOUBLE PRECISION NOT NULL,
+    lng DOUBLE PRECISION NOT NULL,
+    pin_adjusted BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, label)
+);
+
+-- El pedido guarda una copia del depto y las indicaciones por si después se edita la dirección
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS address_id UUID REFERENCES customer_addresses(id) ON DELETE SET NULL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS apartment VARCHAR(100);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS delivery_instructions TEXT;
+
+-- 13. Pedidos programados: franja de entrega y momento en que se liberan a los repartidores
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS scheduled_start TIMESTAMP WITH TIME ZONE;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS scheduled_end TIMESTAMP WITH TIME ZONE;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS released_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_orders_scheduled_unreleased ON orders(scheduled_start)
+    WHERE scheduled_start IS NOT NULL AND released_at IS NULL;
+
+-- 14. Horarios del local: semana tipo, excepciones (feriados u horarios especiales) y pausa manual
+CREATE TABLE IF NOT EXISTS store_hours (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6), -- 0 = domingo
+    opens_at TIME NOT NULL,
+    closes_at TIME NOT NULL,
+    CHECK (closes_at > opens_at)
+);
+
+INSERT INTO store_hours (weekday, opens_at, closes_at)
+SELECT d, '11:00', '23:30' FROM generate_series(0, 6) AS d
+WHERE NOT EXISTS (SELECT 1 FROM store_hours);
+
+CREATE TABLE IF NOT EXISTS store_exceptions (
+    date DATE PRIMARY KEY,
+    closed BOOLEAN NOT NULL DEFAULT true,
+    opens_at TIME,
+    closes_at TIME,
+    reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (closed OR (opens_at IS NOT NULL AND closes_at IS NOT NULL AND closes_at > opens_at))
+);
+
+CREATE TABLE IF NOT EXISTS store_pause (
+    id SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    paused BOOLEAN NOT NULL DEFAULT false,
+    reason TEXT,
+    paused_by UUID REFERENCES users(id),
+    paused_at TIMESTAMP WITH TIME ZONE
+);
+
+INSERT INTO store_pause (id, paused) VALUES (1, false) ON CONFLICT (id) DO NOTHING;
+
+-- 15. Reseñas de pedidos entregados y puntajes agregados por repartidor y producto
+CREATE TABLE IF NOT EXISTS order_reviews (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+    customer_id UUID NOT NULL REFERENCES users(id),
+    driver_id UUID REFERENCES users(id),
+    driver_score SMALLINT NOT NULL CHECK (driver_score BETWEEN 1 AND 5),
+    product_score SMALLINT NOT NULL CHECK (product_score BETWEEN 1 AND 5),
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    comment TEXT,
+    is_hidden BOOLEAN NOT NULL DEFAULT false,
+    hidden_reason TEXT,
+    hidden_by UUID REFERENCES users(id),
+    hidden_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_reviews_driver ON order_reviews(driver_id, created_at DESC);
+
+-- Los agregados solo cuentan reseñas visibles; ocultar una reseña descuenta su puntaje
+CREATE TABLE IF NOT EXISTS driver_ratings (
+    driver_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    rating_count INT NOT NULL DEFAULT 0,
+    rating_sum INT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS product_ratings (
+    product_id UUID PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    rating_count INT NOT NULL DEFAULT 0,
+    rating_sum INT NOT NULL DEFAULT 0
+);
+
+-- 16. Propinas: se guardan aparte de total_price. tip_added_at marca la propina post-entrega (una sola vez)
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tip_amount NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (tip_amount >= 0);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tip_added_at TIMESTAMP WITH TIME ZONE;
+
+-- 17. Ganancias de repartidores: libro de movimientos por pedido (entrega y propinas) y liquidaciones
+CREATE TABLE IF NOT EXISTS payout_batches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    period_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    total_amount NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS driver_payouts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    batch_id UUID NOT NULL REFERENCES payout_batches(id) ON DELETE CASCADE,
+    driver_id UUID NOT NULL REFERENCES users(id),
+    amount NUMERIC(12, 2) NOT NULL,
+    entry_count INT NOT NULL,
+    UNIQUE (batch_id, driver_id)
+);
+
+CREATE TABLE IF NOT EXISTS driver_earnings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    driver_id UUID NOT NULL REFERENCES users(id),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('DELIVERY', 'TIP')),
+    distance_km NUMERIC(8, 2) NOT NULL DEFAULT 0,
+    base_amount NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    distance_amount NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    tip_amount NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    amount NUMERIC(10, 2) NOT NULL,
+    payout_id UUID REFERENCES driver_payouts(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_driver_earnings_delivery ON driver_earnings(order_id) WHERE kind = 'DELIVERY';
+CREATE INDEX IF NOT EXISTS idx_driver_earnings_driver ON driver_earnings(driver_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_driver_earnings_unpaid ON driver_earnings(created_at) WHERE payout_id IS NULL;
+
+-- 18. Auditoría de intentos de login
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    ip VARCHAR(64),
+    user_agent TEXT,
+    outcome VARCHAR(20) NOT NULL CHECK (outcome IN ('SUCCESS', 'FAILED', 'BLOCKED', 'UNLOCKED')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_email ON login_attempts(email, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip ON login_attempts(ip, created_at DESC);
+
+-- 19. Verificación de email: sin verificar no se pueden crear pedidos.
+-- Las cuentas que ya existían al agregar la columna se dan por verificadas (solo esa vez), para no
+-- bloquear a clientes que ya venían pidiendo y nunca recibieron el link.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'email_verified_at'
+    ) THEN
+        ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+        UPDATE users SET email_verified_at = COALESCE(created_at, NOW());
+    END IF;
+END $$;
+
+-- 20. Doble factor (TOTP) y códigos de recuperación de un solo uso
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    enabled_at TIMESTAMP WITH TIME ZONE,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user ON mfa_recovery_codes(user_id) WHERE used_at IS NULL;
+
+-- 21. API keys para integraciones (POS de comercios). Se guarda solo el hash; la key actúa como user_id
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    scopes TEXT[] NOT NULL,
+    allowed_ips TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    last_used_ip VARCHAR(64),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys(user_id);
+
+CREATE TABLE IF NOT EXISTS api_key_usage (
+    api_key_id UUID NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    errors BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (api_key_id, day)
+);
+
+-- 22. Permisos por rol. Los roles se editan desde la API; los permisos los define el código
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    is_system BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    name VARCHAR(100) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role VARCHAR(50) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission VARCHAR(100) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (name, description, is_system) VALUES
+    ('admin', 'Acceso total', true),
+    ('customer', 'Cliente que hace pedidos', true),
+    ('driver', 'Repartidor', true)
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (name, description) VALUES
+    ('orders:create', 'Crear pedidos y ver franjas de entrega'),
+    ('orders:read:own', 'Ver pedidos propios (como cliente o repartidor)'),
+    ('orders:read:any', 'Ver cualquier pedido'),
+    ('orders:cancel:own', 'Cancelar pedidos propios pendientes'),
+    ('orders:cancel:any', 'Cancelar cualquier pedido no entregado'),
+    ('orders:deliver', 'Tomar, retirar y entregar pedidos; reportar ubicación'),
+    ('orders:tip', 'Dejar propina en pedidos propios'),
+    ('orders:share', 'Compartir el seguimiento de pedidos propios'),
+    ('addresses:manage', 'Administrar direcciones propias'),
+    ('products:read', 'Ver el catálogo'),
+    ('products:write', 'Editar el catálogo'),
+    ('reviews:create', 'Reseñar pedidos propios'),
+    ('reviews:read:own', 'Ver reseñas de pedidos propios y la calificación propia'),
+    ('reviews:read:any', 'Ver cualquier reseña, incluidas las ocultas'),
+    ('reviews:moderate', 'Ocultar y mostrar reseñas'),
+    ('earnings:read:own', 'Ver ganancias propias'),
+    ('earnings:read:any', 'Ver ganancias de cualquier repartidor'),
+    ('payouts:manage', 'Liquidar pagos a repartidores'),
+    ('users:manage', 'Administrar usuarios, bloqueos y doble factor'),
+    ('store:manage', 'Horarios y pausas del local'),
+    ('webhooks:manage', 'Administrar webhooks'),
+    ('notifications:manage', 'Ver notificaciones enviadas'),
+    ('locations:audit', 'Ver anomalías de GPS'),
+    ('api_keys:manage', 'Administrar API keys'),
+    ('roles:manage', 'Administrar roles y permisos')
+ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description;
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('customer', 'orders:create'),
+    ('customer', 'orders:read:own'),
+    ('customer', 'orders:cancel:own'),
+    ('customer', 'orders:tip'),
+    ('customer', 'orders:share'),
+    ('customer', 'addresses:manage'),
+    ('customer', 'products:read'),
+    ('customer', 'reviews:create'),
+    ('customer', 'reviews:read:own'),
+    ('driver', 'orders:read:own'),
+    ('driver', 'orders:deliver'),
+    ('driver', 'products:read'),
+    ('driver', 'reviews:read:own'),
+    ('driver', 'earnings:read:own')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) SELECT 'admin', name FROM permissions ON CONFLICT DO NOTHING;
+
+-- El rol del usuario pasa a referenciar la tabla roles en lugar de una lista fija
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ALTER COLUMN role TYPE VARCHAR(50);
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'users_role_fkey') THEN
+        ALTER TABLE users ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES roles(name);
+    END IF;
+END $$;
+
+-- 23. Deduplicación de eventos por clave en lugar de por tipo: los eventos que se pueden repetir
+-- (ej. TIP_ADDED) se guardan sin clave y no se descartan
+ALTER TABLE order_events ADD COLUMN IF NOT EXISTS idempotency_key VARCHAR(100);
+UPDATE order_events SET idempotency_key = type WHERE idempotency_key IS NULL AND type <> 'TIP_ADDED';
+ALTER TABLE order_events DROP CONSTRAINT IF EXISTS order_events_order_id_type_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_order_events_idempotency ON order_events(order_id, idempotency_key);
+
+-- El relay reclama filas (PROCESSING), confirma y recién después publica en Redis
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE outbox DROP CONSTRAINT IF EXISTS outbox_status_check;
+ALTER TABLE outbox ADD CONSTRAINT outbox_status_check CHECK (status IN ('PENDING', 'PROCESSING', 'PUBLISHED'));
+
+-- 24. Reintento de notificaciones que quedaron en PENDING (el proceso cayó entre el registro y el envío)
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS last_attempt_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_notifications_pending ON notifications(created_at) WHERE status = 'PENDING';
+
+-- 25. Horarios nocturnos: un cierre igual o anterior a la apertura es del día siguiente (ej. 20:00 a 01:00)
+ALTER TABLE store_hours DROP CONSTRAINT IF EXISTS store_hours_check;
+ALTER TABLE store_exceptions DROP CONSTRAINT IF EXISTS store_exceptions_check;
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'store_exceptions_hours_check') THEN
+        ALTER TABLE store_exceptions ADD CONSTRAINT store_exceptions_hours_check
+            CHECK (closed OR (opens_at IS NOT NULL AND closes_at IS NOT NULL));
+    END IF;
+END $$;
@@ -4,7 +4,6 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
-	"fmt"
 	"os"
 	"strconv"
 	"time"
@@ -18,39 +17,53 @@ const (
 )
 
 type TokenClaims struct {
-	UserID string `json:"user_id"`
-	Role   string `json:"role,omitempty"`
-	Type   string `json:"type"`
+	UserID    string `json:"user_id"`
+	Role      string `json:"role,omitempty"`
+	Type      string `json:"type"`
+	SessionID string `json:"sid,omitempty"`
+	Version   int64  `json:"ver,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateAccessToken(userID, role string) (string, error) {
+// GenerateAccessToken emite el access token con su jti (para revocarlo en el logout) y la versión
+// de tokens del usuario (para invalidar todos sus tokens de una vez).
+func GenerateAccessToken(userID, role, sessionID string, version int64) (string, error) {
 	now := time.Now()
+	jti, err := generateJTI()
+	if err != nil {
+		return "", err
+	}
+
 	claims := TokenClaims{
-		UserID: userID,
-		Role:   role,
-		Type:   accessTokenType,
+		UserID:    userID,
+		Role:      role,
+		Type:      accessTokenType,
+		SessionID: sessionID,
+		Version:   version,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(getAccessTokenTTL())),
 			IssuedAt:  jwt.NewNumericDate(now),
 			Subject:   userID,
+			ID:        jti,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(getJWTSecret()))
+	return signToken(claims, accessTokenType)
 }
 
-func GenerateRefreshToken(userID string) (string, error) {
+// GenerateRefreshToken emite un refresh token de la sesión y devuelve también su jti,
+// que la sesión guarda para detectar el reuso de tokens ya rotados.
+func GenerateRefreshToken(userID, sessionID string) (string, string, error) {
 	now := time.Now()
 	jti, err := generateJTI()
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	claims := TokenClaims{
-		UserID: userID,
-		Type:   refreshTokenType,
+		UserID:    userID,
+		Type:      refreshTokenType,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(getRefreshTokenTTL())),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -59,31 +72,31 @@ func GenerateRefreshToken(userID string) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(getRefreshJWTSecret()))
+	signed, err := signToken(claims, refreshTokenType)
+	return signed, jti, err
+}
+
+// GenerateSessionID identifica una sesión (un dispositivo) y a toda su familia de refresh tokens
+func GenerateSessionID() (string, error) {
+	return generateJTI()
 }
 
 func ValidateAccessToken(tokenString string) (*TokenClaims, error) {
-	return validateToken(tokenString, getJWTSecret(), accessTokenType)
+	return validateToken(tokenString, accessTokenType)
 }
 
 func ValidateRefreshToken(tokenString string) (*TokenClaims, error) {
-	return validateToken(tokenString, getRefreshJWTSecret(), refreshTokenType)
+	return validateToken(tokenString, refreshTokenType)
 }
 
 func GetRefreshTokenTTL() time.Duration {
 	return getRefreshTokenTTL()
 }
 
-func validateToken(tokenString, secret, expectedType string) (*TokenClaims, error) {
+func validateToken(tokenString, expectedType string) (*TokenClaims, error) {
 	claims := &TokenClaims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("método de firma inesperado")
-		}
-		return []byte(secret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, keyFunc(expectedType))
 	if err != nil {
 		return nil, err
 	}
@@ -103,18 +116,6 @@ func validateToken(tokenString, secret, expectedType string) (*TokenClaims, erro
 	return claims, nil
 }
 
-func getJWTSecret() string {
-	return os.Getenv("JWT_SECRET")
-}
-
-func getRefreshJWTSecret() string {
-	secret := os.Getenv("JWT_REFRESH_SECRET")
-	if secret != "" {
-		return secret
-	}
-	return getJWTSecret()
-}
-
 func getAccessTokenTTL() time.Duration {
 	minutes, err := strconv.Atoi(os.Getenv("JWT_ACCESS_TOKEN_TTL_MINUTES"))
 	if err == nil && minutes > 0 {
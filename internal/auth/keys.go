@@ -0,0 +1,321 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const minRSAKeyBits = 2048
+
+// keySet son las claves con las que se firman y verifican los tokens. Con una clave asimétrica los
+// tokens llevan kid y cualquier servicio puede verificarlos con /.well-known/jwks.json. Sin ella se
+// usa HS256 con JWT_SECRET.
+type keySet struct {
+	signing      *signingKey
+	verification map[string]verificationKey
+
+	// HS256; con clave asimétrica solo se usan para verificar tokens emitidos antes de migrar,
+	// y únicamente hasta hmacUntil
+	accessSecret  []byte
+	refreshSecret []byte
+	shareSecret   []byte
+	hmacUntil     time.Time
+}
+
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private interface{}
+}
+
+type verificationKey struct {
+	kid    string
+	method jwt.SigningMethod
+	public interface{}
+}
+
+var keys atomic.Pointer[keySet]
+
+// LoadKeys carga las claves desde el entorno. Se llama al arrancar; sin clave ni secreto el
+// servidor no debe levantar, porque firmaría tokens con una clave vacía.
+//   - JWT_SIGNING_KEY_FILE: clave privada PEM (RSA de 2048 bits o más → RS256, Ed25519 → EdDSA)
+//   - JWT_VERIFICATION_KEY_FILES: claves públicas PEM separadas por coma que se siguen aceptando
+//     (la anterior durante una rotación)
+//   - JWT_SECRET / JWT_REFRESH_SECRET / SHARE_TOKEN_SECRET: HS256 si no hay clave asimétrica
+//   - JWT_HMAC_ACCEPT_UNTIL: con clave asimétrica, fecha RFC 3339 hasta la que se siguen aceptando
+//     tokens HS256 emitidos antes de migrar. Sin ella no se aceptan.
+func LoadKeys() error {
+	ks := &keySet{verification: map[string]verificationKey{}}
+
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		ks.accessSecret = []byte(secret)
+		ks.refreshSecret = []byte(secret)
+		ks.shareSecret = []byte(secret)
+		if refresh := os.Getenv("JWT_REFRESH_SECRET"); refresh != "" {
+			ks.refreshSecret = []byte(refresh)
+		}
+	}
+	if share := os.Getenv("SHARE_TOKEN_SECRET"); share != "" {
+		ks.shareSecret = []byte(share)
+	}
+
+	if path := os.Getenv("JWT_SIGNING_KEY_FILE"); path != "" {
+		signing, err := loadSigningKey(path)
+		if err != nil {
+			return err
+		}
+		ks.signing = signing
+		ks.verification[signing.kid] = verificationKey{kid: signing.kid, method: signing.method, public: publicKey(signing.private)}
+	}
+
+	for _, path := range strings.Split(os.Getenv("JWT_VERIFICATION_KEY_FILES"), ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		vk, err := loadVerificationKey(path)
+		if err != nil {
+			return err
+		}
+		ks.verification[vk.kid] = vk
+	}
+
+	if ks.signing == nil && ks.accessSecret == nil {
+		return errors.New("no hay clave para firmar tokens: configurá JWT_SIGNING_KEY_FILE o JWT_SECRET")
+	}
+	if until := os.Getenv("JWT_HMAC_ACCEPT_UNTIL"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return fmt.Errorf("JWT_HMAC_ACCEPT_UNTIL debe ser una fecha RFC 3339: %w", err)
+		}
+		ks.hmacUntil = t
+	}
+	if ks.signing != nil && ks.accessSecret != nil {
+		if ks.hmacUntil.After(time.Now()) {
+			slog.Warn("se aceptan tokens HS256 durante la migración a clave asimétrica", "until", ks.hmacUntil)
+		} else {
+			slog.Warn("JWT_SECRET se ignora: hay clave asimétrica y no hay JWT_HMAC_ACCEPT_UNTIL vigente")
+		}
+	}
+	if ks.signing == nil && len(ks.accessSecret) < 32 {
+		slog.Warn("JWT_SECRET tiene menos de 32 caracteres; conviene uno más largo o una clave asimétrica")
+	}
+
+	keys.Store(ks)
+	if ks.signing != nil {
+		slog.Info("tokens firmados con clave asimétrica", "alg", ks.signing.method.Alg(), "kid", ks.signing.kid, "verification_keys", len(ks.verification))
+	}
+	return nil
+}
+
+// signToken firma con la clave asimétrica si hay una; si no, con el secreto HS256 del tipo de token
+func signToken(claims jwt.Claims, tokenType string) (string, error) {
+	ks := keys.Load()
+	if ks == nil {
+		return "", errors.New("claves JWT no cargadas")
+	}
+
+	if ks.signing != nil {
+		token := jwt.NewWithClaims(ks.signing.method, claims)
+		token.Header["kid"] = ks.signing.kid
+		return token.SignedString(ks.signing.private)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(ks.secretFor(tokenType))
+}
+
+// keyFunc elige la clave por kid. Los tokens sin kid son HS256 y solo valen si hay secreto configurado;
+// con clave asimétrica, además, solo hasta JWT_HMAC_ACCEPT_UNTIL.
+func keyFunc(tokenType string) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		ks := keys.Load()
+		if ks == nil {
+			return nil, errors.New("claves JWT no cargadas")
+		}
+
+		if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+			vk, ok := ks.verification[kid]
+			if !ok {
+				return nil, fmt.Errorf("clave de firma desconocida")
+			}
+			if token.Method.Alg() != vk.method.Alg() {
+				return nil, fmt.Errorf("método de firma inesperado")
+			}
+			return vk.public, nil
+		}
+
+		secret := ks.secretFor(tokenType)
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || secret == nil {
+			return nil, fmt.Errorf("método de firma inesperado")
+		}
+		if ks.signing != nil && !time.Now().Before(ks.hmacUntil) {
+			return nil, fmt.Errorf("los tokens HS256 ya no se aceptan")
+		}
+		return secret, nil
+	}
+}
+
+func (ks *keySet) secretFor(tokenType string) []byte {
+	switch tokenType {
+	case refreshTokenType:
+		return ks.refreshSecret
+	case shareTokenType:
+		return ks.shareSecret
+	}
+	return ks.accessSecret
+}
+
+// JWK es una clave pública en formato RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWKS devuelve las claves públicas de verificación. Los secretos HS256 nunca se publican.
+func PublicJWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	ks := keys.Load()
+	if ks == nil {
+		return set
+	}
+	for _, vk := range ks.verification {
+		jwk := toJWK(vk.public)
+		jwk.Kid = vk.kid
+		jwk.Use = "sig"
+		jwk.Alg = vk.method.Alg()
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+func loadSigningKey(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer JWT_SIGNING_KEY_FILE: %w", err)
+	}
+
+	var private interface{}
+	if rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		private = rsaKey
+	} else if edKey, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+		private = edKey
+	} else {
+		return nil, fmt.Errorf("%s: la clave privada debe ser RSA o Ed25519 en PEM", path)
+	}
+
+	method, err := methodFor(path, publicKey(private))
+	if err != nil {
+		return nil, err
+	}
+	return &signingKey{kid: keyID(publicKey(private)), method: method, private: private}, nil
+}
+
+func loadVerificationKey(path string) (verificationKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return verificationKey{}, fmt.Errorf("no se pudo leer la clave de verificación: %w", err)
+	}
+
+	var public interface{}
+	if rsaKey, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		public = rsaKey
+	} else if edKey, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+		public = edKey
+	} else {
+		return verificationKey{}, fmt.Errorf("%s: la clave pública debe ser RSA o Ed25519 en PEM", path)
+	}
+
+	method, err := methodFor(path, public)
+	if err != nil {
+		return verificationKey{}, err
+	}
+	return verificationKey{kid: keyID(public), method: method, public: public}, nil
+}
+
+func methodFor(path string, public interface{}) (jwt.SigningMethod, error) {
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("%s: la clave RSA debe tener al menos %d bits", path, minRSAKeyBits)
+		}
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("%s: tipo de clave no soportado", path)
+}
+
+func publicKey(private interface{}) interface{} {
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		return &k.PublicKey
+	case ed25519.PrivateKey:
+		return k.Public()
+	}
+	return nil
+}
+
+// keyID es el thumbprint RFC 7638 de la clave pública: el mismo archivo siempre da el mismo kid,
+// así la clave vieja conserva su kid cuando pasa a JWT_VERIFICATION_KEY_FILES.
+func keyID(public interface{}) string {
+	jwk := toJWK(public)
+
+	var canonical []byte
+	switch jwk.Kty {
+	case "RSA":
+		canonical, _ = json.Marshal(struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N})
+	case "OKP":
+		canonical, _ = json.Marshal(struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X})
+	}
+
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func toJWK(public interface{}) JWK {
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(k)}
+	}
+	return JWK{}
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// useKeys reemplaza las claves cargadas durante el test
+func useKeys(t *testing.T, ks *keySet) {
+	t.Helper()
+	prev := keys.Load()
+	keys.Store(ks)
+	t.Cleanup(func() { keys.Store(prev) })
+}
+
+func newEd25519KeySet(t *testing.T, secret string) *keySet {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("error generando clave: %v", err)
+	}
+	kid := keyID(public)
+	ks := &keySet{
+		signing:      &signingKey{kid: kid, method: jwt.SigningMethodEdDSA, private: private},
+		verification: map[string]verificationKey{kid: {kid: kid, method: jwt.SigningMethodEdDSA, public: public}},
+	}
+	if secret != "" {
+		ks.accessSecret = []byte(secret)
+		ks.refreshSecret = []byte(secret + "-refresh")
+		ks.shareSecret = []byte(secret + "-share")
+		ks.hmacUntil = time.Now().Add(time.Hour)
+	}
+	return ks
+}
+
+func mustB64(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatalf("base64 inválido: %v", err)
+	}
+	return b
+}
+
+func TestKeyIDRFC7638(t *testing.T) {
+	// Ejemplo de la sección 3.1 de la RFC 7638
+	rsaKey := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(mustB64(t, "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")),
+		E: 65537,
+	}
+	if got := keyID(rsaKey); got != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("kid RSA = %s", got)
+	}
+
+	// Ejemplo del apéndice A.3 de la RFC 8037
+	edKey := ed25519.PublicKey(mustB64(t, "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"))
+	if got := keyID(edKey); got != "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k" {
+		t.Errorf("kid Ed25519 = %s", got)
+	}
+}
+
+func TestToJWK(t *testing.T) {
+	rsaKey := &rsa.PublicKey{N: big.NewInt(0xC0FFEE), E: 65537}
+	jwk := toJWK(rsaKey)
+	if jwk.Kty != "RSA" || jwk.E != "AQAB" || !bytes.Equal(mustB64(t, jwk.N), rsaKey.N.Bytes()) {
+		t.Errorf("JWK RSA = %+v", jwk)
+	}
+	if jwk.Crv != "" || jwk.X != "" {
+		t.Errorf("la JWK RSA no debería tener crv ni x: %+v", jwk)
+	}
+
+	edKey := ed25519.PublicKey(bytes.Repeat([]byte{7}, ed25519.PublicKeySize))
+	jwk = toJWK(edKey)
+	if jwk.Kty != "OKP" || jwk.Crv != "Ed25519" || !bytes.Equal(mustB64(t, jwk.X), edKey) {
+		t.Errorf("JWK Ed25519 = %+v", jwk)
+	}
+	if jwk.N != "" || jwk.E != "" {
+		t.Errorf("la JWK Ed25519 no debería tener n ni e: %+v", jwk)
+	}
+}
+
+func TestKeyFunc(t *testing.T) {
+	ks := newEd25519KeySet(t, "secreto-de-pruebas")
+	kid := ks.signing.kid
+	asymmetricOnly := newEd25519KeySet(t, "")
+	migrationOver := newEd25519KeySet(t, "secreto-de-pruebas")
+	migrationOver.hmacUntil = time.Now().Add(-time.Minute)
+	noWindow := newEd25519KeySet(t, "secreto-de-pruebas")
+	noWindow.hmacUntil = time.Time{}
+	hmacOnly := &keySet{accessSecret: []byte("secreto-de-pruebas")}
+
+	token := func(method jwt.SigningMethod, kid string) *jwt.Token {
+		tok := jwt.New(method)
+		if kid != "" {
+			tok.Header["kid"] = kid
+		}
+		return tok
+	}
+
+	tests := []struct {
+		name      string
+		ks        *keySet
+		token     *jwt.Token
+		tokenType string
+		want      interface{}
+		wantErr   bool
+	}{
+		{"kid conocido", ks, token(jwt.SigningMethodEdDSA, kid), accessTokenType, ks.verification[kid].public, false},
+		{"kid desconocido", ks, token(jwt.SigningMethodEdDSA, "otro"), accessTokenType, nil, true},
+		{"kid con HS256", ks, token(jwt.SigningMethodHS256, kid), accessTokenType, nil, true},
+		{"kid con otro algoritmo asimétrico", ks, token(jwt.SigningMethodRS256, kid), accessTokenType, nil, true},
+		{"sin kid con HS256", ks, token(jwt.SigningMethodHS256, ""), accessTokenType, ks.accessSecret, false},
+		{"sin kid para refresh", ks, token(jwt.SigningMethodHS256, ""), refreshTokenType, ks.refreshSecret, false},
+		{"sin kid con algoritmo asimétrico", ks, token(jwt.SigningMethodEdDSA, ""), accessTokenType, nil, true},
+		{"sin kid y sin secreto", asymmetricOnly, token(jwt.SigningMethodHS256, ""), accessTokenType, nil, true},
+		{"sin kid con la migración vencida", migrationOver, token(jwt.SigningMethodHS256, ""), accessTokenType, nil, true},
+		{"sin kid sin plazo de migración", noWindow, token(jwt.SigningMethodHS256, ""), accessTokenType, nil, true},
+		{"sin kid solo con secreto", hmacOnly, token(jwt.SigningMethodHS256, ""), accessTokenType, hmacOnly.accessSecret, false},
+		{"claves no cargadas", nil, token(jwt.SigningMethodHS256, ""), accessTokenType, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useKeys(t, tt.ks)
+
+			got, err := keyFunc(tt.tokenType)(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, se esperaba error: %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			switch want := tt.want.(type) {
+			case []byte:
+				if b, ok := got.([]byte); !ok || !bytes.Equal(b, want) {
+					t.Errorf("clave = %v, se esperaba el secreto del tipo %s", got, tt.tokenType)
+				}
+			case ed25519.PublicKey:
+				if k, ok := got.(ed25519.PublicKey); !ok || !k.Equal(want) {
+					t.Errorf("clave = %v, se esperaba la pública del kid", got)
+				}
+			}
+		})
+	}
+}
+
+func TestAccessTokenWithAsymmetricKey(t *testing.T) {
+	ks := newEd25519KeySet(t, "secreto-de-pruebas")
+	useKeys(t, ks)
+
+	signed, err := GenerateAccessToken("user-1", "driver", "session-1", 3)
+	if err != nil {
+		t.Fatalf("error al firmar: %v", err)
+	}
+	claims, err := ValidateAccessToken(signed)
+	if err != nil {
+		t.Fatalf("error al validar: %v", err)
+	}
+	if claims.UserID != "user-1" || claims.SessionID != "session-1" || claims.Version != 3 {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	// Un token HS256 emitido antes de migrar sigue valiendo hasta JWT_HMAC_ACCEPT_UNTIL
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.accessSecret)
+	if err != nil {
+		t.Fatalf("error al firmar con HS256: %v", err)
+	}
+	if _, err := ValidateAccessToken(legacy); err != nil {
+		t.Errorf("token HS256 previo rechazado: %v", err)
+	}
+
+	// Con el secreto de refresh no sirve como access token
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.refreshSecret)
+	if _, err := ValidateAccessToken(forged); err == nil {
+		t.Error("se aceptó un access token firmado con el secreto de refresh")
+	}
+
+	// Con una clave asimétrica rotada afuera el token deja de valer
+	rotated := newEd25519KeySet(t, "")
+	useKeys(t, rotated)
+	if _, err := ValidateAccessToken(signed); err == nil {
+		t.Error("se aceptó un token firmado con una clave que ya no está")
+	}
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const shareTokenType = "share"
+
+// ShareClaims solo lleva el ID del link: el pedido se resuelve en la base para no exponerlo
+type ShareClaims struct {
+	Type string `json:"type"`
+	jwt.RegisteredClaims
+}
+
+func GenerateShareToken(linkID string, expiresAt time.Time) (string, error) {
+	claims := ShareClaims{
+		Type: shareTokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        linkID,
+		},
+	}
+
+	return signToken(claims, shareTokenType)
+}
+
+// ValidateShareToken verifica firma y vencimiento y devuelve el ID del link
+func ValidateShareToken(tokenString string) (string, error) {
+	claims := &ShareClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, keyFunc(shareTokenType))
+	if err != nil {
+		return "", err
+	}
+
+	if !token.Valid || claims.Type != shareTokenType || claims.ID == "" {
+		return "", errors.New("token de seguimiento inválido")
+	}
+
+	return claims.ID, nil
+}
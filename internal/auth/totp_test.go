@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Semilla SHA1 de los vectores de prueba de la RFC 6238 ("12345678901234567890") en base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTPRFCVectors(t *testing.T) {
+	// Vectores del apéndice B de la RFC 6238 recortados a 6 dígitos
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		step, ok := ValidateTOTP(rfcSecret, tt.code, time.Unix(tt.unix, 0))
+		if !ok {
+			t.Errorf("T=%d: el código %s no validó", tt.unix, tt.code)
+			continue
+		}
+		if want := tt.unix / totpPeriod; step != want {
+			t.Errorf("T=%d: paso = %d, se esperaba %d", tt.unix, step, want)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	issued := time.Unix(1111111111, 0)
+	const code = "050471"
+	step := issued.Unix() / totpPeriod
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"un paso después", issued.Add(totpPeriod * time.Second), true},
+		{"un paso antes", issued.Add(-totpPeriod * time.Second), true},
+		{"dos pasos después", issued.Add(2 * totpPeriod * time.Second), false},
+		{"dos pasos antes", issued.Add(-2 * totpPeriod * time.Second), false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ValidateTOTP(rfcSecret, code, tt.now)
+		if ok != tt.want {
+			t.Errorf("%s: ok = %v, se esperaba %v", tt.name, ok, tt.want)
+		}
+		// Aunque valide en otro paso, se devuelve el paso en que se generó el código
+		if ok && got != step {
+			t.Errorf("%s: paso = %d, se esperaba %d", tt.name, got, step)
+		}
+	}
+}
+
+func TestValidateTOTPInvalid(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	tests := []struct {
+		name, secret, code string
+	}{
+		{"código incorrecto", rfcSecret, "050472"},
+		{"código corto", rfcSecret, "50471"},
+		{"código largo", rfcSecret, "0504710"},
+		{"código vacío", rfcSecret, ""},
+		{"secreto inválido", "no-es-base32!", "050471"},
+		{"secreto con padding", rfcSecret + "====", "050471"},
+	}
+
+	for _, tt := range tests {
+		if _, ok := ValidateTOTP(tt.secret, tt.code, now); ok {
+			t.Errorf("%s: se aceptó el código", tt.name)
+		}
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Fatalf("secreto %q inválido: %d bytes, %v", secret, len(key), err)
+	}
+	if strings.Contains(secret, "=") {
+		t.Errorf("el secreto no debería tener padding: %s", secret)
+	}
+
+	now := time.Now()
+	code := totpCode(key, now.Unix()/totpPeriod)
+	if _, ok := ValidateTOTP(secret, code, now); !ok {
+		t.Error("no valida un código generado con el propio secreto")
+	}
+}
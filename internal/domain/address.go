@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// Address es una dirección guardada por el cliente, geocodificada al guardarla.
+// PinAdjusted indica que las coordenadas las corrigió el cliente a mano en el mapa.
+type Address struct {
+	ID           string    `json:"id"`
+	UserID       string    `json:"user_id"`
+	Label        string    `json:"label"`
+	Address      string    `json:"address"`
+	Apartment    string    `json:"apartment,omitempty"`
+	Instructions string    `json:"instructions,omitempty"`
+	Lat          float64   `json:"lat"`
+	Lng          float64   `json:"lng"`
+	PinAdjusted  bool      `json:"pin_adjusted"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+// Scopes que puede tener una API key. Cada ruta abierta a integraciones pide uno.
+const (
+	ScopeOrdersRead   = "orders:read"
+	ScopeOrdersWrite  = "orders:write"
+	ScopeProductsRead = "products:read"
+)
+
+var APIKeyScopes = []string{ScopeOrdersRead, ScopeOrdersWrite, ScopeProductsRead}
+
+// APIKeyPrefix distingue una API key de un JWT cuando llega como Bearer
+const APIKeyPrefix = "trk_"
+
+// APIKey es una credencial de servidor a servidor. Actúa en nombre de UserID (la cuenta del
+// comercio) con sus mismos permisos, limitada a Scopes y, si AllowedIPs no está vacío, a esas IPs.
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	UserID     string     `json:"user_id"`
+	UserRole   string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	AllowedIPs []string   `json:"allowed_ips"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// HasScope dice si la key tiene el scope
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyUsage son los requests de un día; Errors cuenta las respuestas 4xx y 5xx
+type APIKeyUsage struct {
+	Day      time.Time `json:"day"`
+	Requests int64     `json:"requests"`
+	Errors   int64     `json:"errors"`
+}
@@ -0,0 +1,89 @@
+package domain
+
+import "time"
+
+const (
+	EarningKindDelivery = "DELIVERY"
+	EarningKindTip      = "TIP"
+)
+
+// CommissionModel define cuánto cobra el repartidor por entrega, además de las propinas
+type CommissionModel struct {
+	BasePerDelivery float64 `json:"base_per_delivery"`
+	PerKm           float64 `json:"per_km"`
+}
+
+// DriverEarning es un movimiento del libro de ganancias. La entrega genera uno (base + km + propina
+// al pedir) y la propina post-entrega otro. Quedan fijos aunque después cambie la comisión.
+type DriverEarning struct {
+	ID             string    `json:"id"`
+	DriverID       string    `json:"driver_id"`
+	OrderID        string    `json:"order_id"`
+	Kind           string    `json:"kind"`
+	DistanceKm     float64   `json:"distance_km"`
+	BaseAmount     float64   `json:"base_amount"`
+	DistanceAmount float64   `json:"distance_amount"`
+	TipAmount      float64   `json:"tip_amount"`
+	Amount         float64   `json:"amount"`
+	PayoutID       string    `json:"payout_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// UnrecordedDelivery es un pedido entregado que no tiene movimiento de entrega en el libro
+// (entregado antes de que existiera). Order.TipAmount ya descuenta las propinas registradas aparte.
+type UnrecordedDelivery struct {
+	Order       Order
+	DeliveredAt time.Time
+}
+
+// OrderEarning agrupa los movimientos de un pedido dentro de un período
+type OrderEarning struct {
+	OrderID            string    `json:"order_id"`
+	DestinationAddress string    `json:"destination_address"`
+	DeliveredAt        time.Time `json:"delivered_at"`
+	DistanceKm         float64   `json:"distance_km"`
+	BaseAmount         float64   `json:"base_amount"`
+	DistanceAmount     float64   `json:"distance_amount"`
+	TipAmount          float64   `json:"tip_amount"`
+	Total              float64   `json:"total"`
+	PaidAmount         float64   `json:"paid_amount"`
+}
+
+type EarningsSummary struct {
+	Deliveries     int     `json:"deliveries"`
+	DistanceKm     float64 `json:"distance_km"`
+	BaseAmount     float64 `json:"base_amount"`
+	DistanceAmount float64 `json:"distance_amount"`
+	TipAmount      float64 `json:"tip_amount"`
+	Total          float64 `json:"total"`
+	Paid           float64 `json:"paid"`
+	Pending        float64 `json:"pending"`
+}
+
+// EarningsReport es el resumen de un repartidor en un período [From, To)
+type EarningsReport struct {
+	DriverID   string          `json:"driver_id"`
+	DriverName string          `json:"driver_name"`
+	From       time.Time       `json:"from"`
+	To         time.Time       `json:"to"`
+	Summary    EarningsSummary `json:"summary"`
+	Orders     []OrderEarning  `json:"orders"`
+}
+
+// PayoutBatch es una liquidación: marca como pagados todos los movimientos anteriores a PeriodEnd
+type PayoutBatch struct {
+	ID          string         `json:"id"`
+	PeriodEnd   time.Time      `json:"period_end"`
+	TotalAmount float64        `json:"total_amount"`
+	CreatedBy   string         `json:"created_by,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	Payouts     []DriverPayout `json:"payouts,omitempty"`
+}
+
+type DriverPayout struct {
+	ID         string  `json:"id"`
+	DriverID   string  `json:"driver_id"`
+	DriverName string  `json:"driver_name"`
+	Amount     float64 `json:"amount"`
+	EntryCount int     `json:"entry_count"`
+}
@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+const (
+	AnomalyImpossibleSpeed  = "IMPOSSIBLE_SPEED"
+	AnomalyStaleTimestamp   = "STALE_TIMESTAMP"
+	AnomalyRepeatedPosition = "REPEATED_POSITION"
+)
+
+// LocationFix es una posición GPS reportada por un driver
+type LocationFix struct {
+	Lat        float64   `json:"lat"`
+	Lng        float64   `json:"lng"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// DriverLocation es la posición en vivo de un driver. RecordedAt queda en cero si no se conoce.
+type DriverLocation struct {
+	DriverID   string    `json:"driver_id"`
+	Lat        float64   `json:"lat"`
+	Lng        float64   `json:"lng"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// LocationAnomaly registra un fix sospechoso (saltos imposibles, timestamps viejos, posiciones repetidas)
+type LocationAnomaly struct {
+	ID         string    `json:"id"`
+	DriverID   string    `json:"driver_id"`
+	OrderID    string    `json:"order_id,omitempty"`
+	Type       string    `json:"type"`
+	Lat        float64   `json:"lat"`
+	Lng        float64   `json:"lng"`
+	PrevLat    *float64  `json:"prev_lat,omitempty"`
+	PrevLng    *float64  `json:"prev_lng,omitempty"`
+	SpeedKmh   *float64  `json:"speed_kmh,omitempty"`
+	Rejected   bool      `json:"rejected"`
+	RecordedAt time.Time `json:"recorded_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
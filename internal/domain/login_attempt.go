@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+const (
+	LoginOutcomeSuccess  = "SUCCESS"
+	LoginOutcomeFailed   = "FAILED"
+	LoginOutcomeBlocked  = "BLOCKED"
+	LoginOutcomeUnlocked = "UNLOCKED"
+)
+
+// LoginAttempt es una entrada de la auditoría de logins. UNLOCKED registra el desbloqueo manual de un admin.
+type LoginAttempt struct {
+	ID        int64     `json:"id"`
+	Email     string    `json:"email"`
+	UserID    string    `json:"user_id,omitempty"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Outcome   string    `json:"outcome"`
+	CreatedAt time.Time `json:"created_at"`
+}
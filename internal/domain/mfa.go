@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// TOTPEnrollment es el secreto TOTP del usuario. Hasta que confirma el primer código EnabledAt es nil
+// y el segundo factor no se exige.
+type TOTPEnrollment struct {
+	UserID       string
+	Secret       string
+	EnabledAt    *time.Time
+	LastUsedStep int64
+}
+
+// MFAStatus es lo que el usuario ve de su segundo factor
+type MFAStatus struct {
+	Enabled           bool       `json:"enabled"`
+	Required          bool       `json:"required"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+// TOTPSetup es lo que se devuelve al empezar el enrolamiento; el cliente muestra ProvisioningURI como QR
+type TOTPSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// LoginResult es la respuesta del login. Si el usuario tiene (o debe configurar) doble factor, en
+// lugar de tokens trae un MFAToken de corta duración para completar el segundo paso.
+type LoginResult struct {
+	User             *User
+	AccessToken      string
+	RefreshToken     string
+	MFARequired      bool
+	MFAToken         string
+	EnrollmentNeeded bool
+	RecoveryCodes    []string
+}
@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+const (
+	NotificationPending = "PENDING"
+	NotificationSent    = "SENT"
+	NotificationFailed  = "FAILED"
+	NotificationSkipped = "SKIPPED"
+)
+
+// NotificationPreferences indica por qué canales quiere recibir avisos el usuario y en qué idioma.
+// Phone y PushToken son los destinos de SMS y push.
+type NotificationPreferences struct {
+	UserID       string    `json:"user_id"`
+	Locale       string    `json:"locale"`
+	EmailEnabled bool      `json:"email_enabled"`
+	SMSEnabled   bool      `json:"sms_enabled"`
+	PushEnabled  bool      `json:"push_enabled"`
+	Phone        string    `json:"phone,omitempty"`
+	PushToken    string    `json:"push_token,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Notification es un envío concreto por un canal, con su estado de entrega
+type Notification struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	OrderID   string     `json:"order_id,omitempty"`
+	EventID   string     `json:"event_id,omitempty"`
+	Channel   string     `json:"channel"`
+	Template  string     `json:"template"`
+	Recipient string     `json:"recipient"`
+	Subject   string     `json:"subject"`
+	Body      string     `json:"body"`
+	Status    string     `json:"status"`
+	Error     *string    `json:"error,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+}
@@ -21,7 +21,15 @@ type Order struct {
     DestLat            float64   `json:"dest_lat"`
     DestLng            float64   `json:"dest_lng"`
     DestinationAddress string    `json:"destination_address"`
+    AddressID          string    `json:"address_id,omitempty"`
+    Apartment          string    `json:"apartment,omitempty"`
+    DeliveryInstructions string  `json:"delivery_instructions,omitempty"`
     TotalPrice         float64   `json:"total_price"`
+    TipAmount          float64   `json:"tip_amount"`
+    TipAddedAt         *time.Time `json:"tip_added_at,omitempty"`
+    ScheduledStart     *time.Time `json:"scheduled_start,omitempty"`
+    ScheduledEnd       *time.Time `json:"scheduled_end,omitempty"`
+    ReleasedAt         *time.Time `json:"released_at,omitempty"`
     CreatedAt          time.Time `json:"created_at"`
     Items              []OrderItem `json:"items"`
 }
\ No newline at end of file
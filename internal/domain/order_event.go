@@ -0,0 +1,67 @@
+package domain
+
+import "time"
+
+const (
+	OrderEventCreated   = "ORDER_CREATED"
+	OrderEventAccepted  = "ORDER_ACCEPTED"
+	OrderEventPickedUp  = "ORDER_PICKED_UP"
+	OrderEventDelivered = "ORDER_DELIVERED"
+	OrderEventCancelled = "ORDER_CANCELLED"
+	OrderEventReleased  = "ORDER_RELEASED"
+	OrderEventTipAdded  = "TIP_ADDED"
+
+	OrderEventArrivedAtPickup  = "ARRIVED_AT_PICKUP"
+	OrderEventArrivedAtDropoff = "ARRIVED_AT_DROPOFF"
+)
+
+// OrderEventTypes son los tipos de evento a los que se puede suscribir un webhook
+var OrderEventTypes = []string{
+	OrderEventCreated,
+	OrderEventAccepted,
+	OrderEventPickedUp,
+	OrderEventDelivered,
+	OrderEventCancelled,
+	OrderEventReleased,
+	OrderEventTipAdded,
+	OrderEventArrivedAtPickup,
+	OrderEventArrivedAtDropoff,
+}
+
+// oneShotOrderEvents ocurren como mucho una vez por pedido (cambios de estado y llegadas detectadas
+// por geofence). Los demás, como TIP_ADDED, se pueden repetir.
+var oneShotOrderEvents = map[string]bool{
+	OrderEventCreated:          true,
+	OrderEventAccepted:         true,
+	OrderEventPickedUp:         true,
+	OrderEventDelivered:        true,
+	OrderEventCancelled:        true,
+	OrderEventReleased:         true,
+	OrderEventArrivedAtPickup:  true,
+	OrderEventArrivedAtDropoff: true,
+}
+
+// OrderEvent es una entrada del timeline de un pedido
+type OrderEvent struct {
+	ID        string                 `json:"id"`
+	OrderID   string                 `json:"order_id"`
+	Type      string                 `json:"type"`
+	ActorID   string                 `json:"actor_id,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+
+	// IdempotencyKey deduplica el evento dentro del pedido. Vacío usa DedupeKey.
+	IdempotencyKey string `json:"-"`
+}
+
+// DedupeKey es la clave con la que se descarta un evento repetido: la que pasó quien lo genera o, si
+// no hay, el tipo en los eventos de una sola vez. "" significa que no se deduplica.
+func (e OrderEvent) DedupeKey() string {
+	if e.IdempotencyKey != "" {
+		return e.IdempotencyKey
+	}
+	if oneShotOrderEvents[e.Type] {
+		return e.Type
+	}
+	return ""
+}
@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+// Permisos que chequea el código. Los roles son datos (tabla roles) y se arman con estos permisos;
+// los que terminan en :own exigen además ser dueño del recurso, los :any no.
+const (
+	PermOrdersCreate    = "orders:create"
+	PermOrdersReadOwn   = "orders:read:own"
+	PermOrdersReadAny   = "orders:read:any"
+	PermOrdersCancelOwn = "orders:cancel:own"
+	PermOrdersCancelAny = "orders:cancel:any"
+	PermOrdersDeliver   = "orders:deliver"
+	PermOrdersTip       = "orders:tip"
+	PermOrdersShare     = "orders:share"
+	PermAddressesManage = "addresses:manage"
+	PermProductsRead    = "products:read"
+	PermProductsWrite   = "products:write"
+	PermReviewsCreate   = "reviews:create"
+	PermReviewsReadOwn  = "reviews:read:own"
+	PermReviewsReadAny  = "reviews:read:any"
+	PermReviewsModerate = "reviews:moderate"
+	PermEarningsReadOwn = "earnings:read:own"
+	PermEarningsReadAny = "earnings:read:any"
+	PermPayoutsManage   = "payouts:manage"
+	PermUsersManage     = "users:manage"
+	PermStoreManage     = "store:manage"
+	PermWebhooksManage  = "webhooks:manage"
+	PermNotifManage     = "notifications:manage"
+	PermLocationsAudit  = "locations:audit"
+	PermAPIKeysManage   = "api_keys:manage"
+	PermRolesManage     = "roles:manage"
+)
+
+// AdminRole tiene siempre todos los permisos y no se puede editar ni borrar
+const AdminRole = "admin"
+
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type Role struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	IsSystem    bool      `json:"is_system"`
+	Permissions []string  `json:"permissions"`
+	UserCount   int       `json:"user_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
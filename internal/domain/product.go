@@ -1,9 +1,10 @@
 package domain
 
 type Product struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Price       float64 `json:"price"`
-	Description string  `json:"description"`
-	IsActive    bool    `json:"is_active"`
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	Price       float64       `json:"price"`
+	Description string        `json:"description"`
+	IsActive    bool          `json:"is_active"`
+	Rating      RatingSummary `json:"rating"`
 }
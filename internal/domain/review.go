@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+// ReviewTags son las etiquetas que puede elegir el cliente al calificar
+var ReviewTags = []string{
+	"PUNTUAL",
+	"AMABLE",
+	"BUEN_ESTADO",
+	"DEMORADO",
+	"PRODUCTO_FRIO",
+	"MAL_ESTADO",
+	"MALA_ATENCION",
+}
+
+// Review es la calificación que deja el cliente sobre un pedido entregado
+type Review struct {
+	ID           string     `json:"id"`
+	OrderID      string     `json:"order_id"`
+	CustomerID   string     `json:"customer_id"`
+	DriverID     string     `json:"driver_id,omitempty"`
+	DriverScore  int        `json:"driver_score"`
+	ProductScore int        `json:"product_score"`
+	Tags         []string   `json:"tags"`
+	Comment      string     `json:"comment,omitempty"`
+	IsHidden     bool       `json:"is_hidden"`
+	HiddenReason string     `json:"hidden_reason,omitempty"`
+	HiddenBy     string     `json:"hidden_by,omitempty"`
+	HiddenAt     *time.Time `json:"hidden_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// RatingSummary es el promedio agregado de un repartidor o producto
+type RatingSummary struct {
+	Average float64 `json:"average"`
+	Count   int     `json:"count"`
+}
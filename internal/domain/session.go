@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// Session es un dispositivo logueado. Cada refresh rota el token dentro de la misma sesión
+// (familia); presentar un token ya rotado revoca la sesión entera.
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"-"`
+	CurrentJTI string    `json:"-"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
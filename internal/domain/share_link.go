@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// ShareLink permite seguir un pedido sin login hasta que vence o se revoca
+type ShareLink struct {
+	ID        string     `json:"id"`
+	OrderID   string     `json:"order_id"`
+	CreatedBy string     `json:"created_by"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (l ShareLink) IsUsable(now time.Time) bool {
+	return l.RevokedAt == nil && now.Before(l.ExpiresAt)
+}
@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// StoreHours es un rango de apertura de la semana tipo. Un día puede tener varios (ej. mediodía y noche).
+// Weekday sigue time.Weekday: 0 es domingo. Los horarios son HH:MM en hora de Buenos Aires.
+type StoreHours struct {
+	ID       string `json:"id"`
+	Weekday  int    `json:"weekday"`
+	OpensAt  string `json:"opens_at"`
+	ClosesAt string `json:"closes_at"`
+}
+
+// StoreException reemplaza la semana tipo en una fecha: cerrado (feriado) u horario especial
+type StoreException struct {
+	Date      string    `json:"date"`
+	Closed    bool      `json:"closed"`
+	OpensAt   string    `json:"opens_at,omitempty"`
+	ClosesAt  string    `json:"closes_at,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// StorePause es el interruptor del admin para dejar de tomar pedidos en el momento
+type StorePause struct {
+	Paused   bool       `json:"paused"`
+	Reason   string     `json:"reason,omitempty"`
+	PausedBy string     `json:"paused_by,omitempty"`
+	PausedAt *time.Time `json:"paused_at,omitempty"`
+}
@@ -3,11 +3,44 @@ package domain
 import "time"
 
 type User struct {
-	ID           string    `json:"id"`
-	Email        string    `json:"email"`
-	FullName     string    `json:"full_name"`
-	PasswordHash string    `json:"-"`
-	Role         string    `json:"role"`
-	IsActive     bool      `json:"is_active"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID              string     `json:"id"`
+	Email           string     `json:"email"`
+	FullName        string     `json:"full_name"`
+	PasswordHash    string     `json:"-"`
+	Role            string     `json:"role"`
+	IsActive        bool       `json:"is_active"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// UserFilter son los filtros del listado de usuarios del admin. Search busca en email y nombre.
+type UserFilter struct {
+	Role   string
+	Active *bool
+	Search string
+	Limit  int
+	Offset int
+}
+
+type UserPage struct {
+	Users  []User `json:"users"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+// UserOrderStats resume los pedidos del usuario como cliente y como repartidor
+type UserOrderStats struct {
+	OrdersPlaced    int        `json:"orders_placed"`
+	OrdersDelivered int        `json:"orders_delivered"`
+	OrdersCancelled int        `json:"orders_cancelled"`
+	TotalSpent      float64    `json:"total_spent"`
+	LastOrderAt     *time.Time `json:"last_order_at"`
+	Deliveries      int        `json:"deliveries"`
+	LastDeliveryAt  *time.Time `json:"last_delivery_at"`
+}
+
+type UserDetail struct {
+	User
+	OrderStats UserOrderStats `json:"order_stats"`
 }
@@ -0,0 +1,37 @@
+package dto
+
+import "time"
+
+type CreateAddressRequest struct {
+	Label        string `json:"label" binding:"required,max=50"`
+	Address      string `json:"address" binding:"required,max=200"`
+	Apartment    string `json:"apartment" binding:"max=100"`
+	Instructions string `json:"instructions" binding:"max=500"`
+}
+
+// UpdateAddressRequest actualiza solo los campos enviados. Cambiar la dirección vuelve a geocodificarla.
+type UpdateAddressRequest struct {
+	Label        *string `json:"label" binding:"omitempty,max=50"`
+	Address      *string `json:"address" binding:"omitempty,max=200"`
+	Apartment    *string `json:"apartment" binding:"omitempty,max=100"`
+	Instructions *string `json:"instructions" binding:"omitempty,max=500"`
+}
+
+// UpdateAddressPinRequest corrige las coordenadas con el pin que el cliente soltó en el mapa
+type UpdateAddressPinRequest struct {
+	Lat float64 `json:"lat" binding:"required"`
+	Lng float64 `json:"lng" binding:"required"`
+}
+
+type AddressResponse struct {
+	ID           string    `json:"id"`
+	Label        string    `json:"label"`
+	Address      string    `json:"address"`
+	Apartment    string    `json:"apartment,omitempty"`
+	Instructions string    `json:"instructions,omitempty"`
+	Lat          float64   `json:"lat"`
+	Lng          float64   `json:"lng"`
+	PinAdjusted  bool      `json:"pin_adjusted"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+import "time"
+
+type CreateAPIKeyRequest struct {
+	Name       string     `json:"name" binding:"required,max=100"`
+	UserID     string     `json:"user_id" binding:"required,uuid"`
+	Scopes     []string   `json:"scopes" binding:"required,min=1,dive,required"`
+	AllowedIPs []string   `json:"allowed_ips" binding:"max=20"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+import "time"
+
+// CreatePayoutBatchRequest liquida lo pendiente hasta Until; sin Until liquida todo lo pendiente hasta ahora
+type CreatePayoutBatchRequest struct {
+	Until *time.Time `json:"until"`
+}
@@ -0,0 +1,17 @@
+package dto
+
+type AutocompleteQuery struct {
+	Q string `form:"q" binding:"required,min=3,max=200"`
+}
+
+type ReverseGeocodeQuery struct {
+	Lat float64 `form:"lat" binding:"required"`
+	Lng float64 `form:"lng" binding:"required"`
+}
+
+type GeoSuggestionResponse struct {
+	Address     string  `json:"address"`
+	DisplayName string  `json:"display_name"`
+	Lat         float64 `json:"lat"`
+	Lng         float64 `json:"lng"`
+}
@@ -0,0 +1,21 @@
+package dto
+
+import "time"
+
+// UpdateNotificationPreferencesRequest actualiza solo los campos enviados
+type UpdateNotificationPreferencesRequest struct {
+	Locale       *string `json:"locale" binding:"omitempty,oneof=es en"`
+	EmailEnabled *bool   `json:"email_enabled"`
+	SMSEnabled   *bool   `json:"sms_enabled"`
+	PushEnabled  *bool   `json:"push_enabled"`
+	Phone        *string `json:"phone" binding:"omitempty,max=30"`
+	PushToken    *string `json:"push_token"`
+}
+
+type CapturedEmailResponse struct {
+	To       string    `json:"to"`
+	Subject  string    `json:"subject"`
+	Body     string    `json:"body"`
+	Redacted bool      `json:"redacted,omitempty"` // cuerpo oculto porque tenía un link de acceso
+	SentAt   time.Time `json:"sent_at"`
+}
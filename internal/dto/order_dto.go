@@ -1,8 +1,25 @@
 package dto
 import "time"
 type CreateOrderRequest struct {
-	DestinationAddress string             `json:"destination_address" binding:"required"`
+	DestinationAddress string             `json:"destination_address" binding:"required_without=AddressID"`
+	AddressID          string             `json:"address_id" binding:"omitempty,uuid"`
 	Items              []OrderItemRequest `json:"items" binding:"required,gt=0"`
+	ScheduledFor       *DeliveryWindowRequest `json:"scheduled_for"`
+	TipAmount          float64            `json:"tip_amount" binding:"omitempty,gte=0,lte=100000"`
+}
+// AddTipRequest es la propina que el cliente deja después de la entrega
+type AddTipRequest struct {
+	Amount float64 `json:"amount" binding:"required,gt=0,lte=100000"`
+}
+// DeliveryWindowRequest es la franja pedida para un pedido programado (ej. 20:00 a 20:30)
+type DeliveryWindowRequest struct {
+	Start time.Time `json:"start" binding:"required"`
+	End   time.Time `json:"end" binding:"required"`
+}
+type DeliverySlotResponse struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Remaining int       `json:"remaining"`
 }
 type OrderItemResponse struct {
     ProductName string  `json:"product_name"`
@@ -18,12 +35,63 @@ type OrderResponse struct {
 	CustomerID string `json:"customer_id"`
 	CustomerName       string `json:"customer_name"`
 	DestinationAddress string `json:"destination_address"`
+	Apartment          string `json:"apartment,omitempty"`
+	DeliveryInstructions string `json:"delivery_instructions,omitempty"`
 	TotalPrice         float64 `json:"total_price"`
+	TipAmount          float64 `json:"tip_amount"`
+	ScheduledStart     *time.Time `json:"scheduled_start,omitempty"`
+	ScheduledEnd       *time.Time `json:"scheduled_end,omitempty"`
 	Status string `json:"status"`
 	Items  []OrderItemResponse `json:"items"`
 	CreatedAt time.Time `json:"created_at"`
 }
 type UpdateLocationRequest struct {
-	Lat float64 `json:"lat" binding:"required"`
-	Lng float64 `json:"lng" binding:"required"`
+	Lat        float64    `json:"lat" binding:"required"`
+	Lng        float64    `json:"lng" binding:"required"`
+	RecordedAt *time.Time `json:"recorded_at"`
+}
+type OrderLocationResponse struct {
+	Lat        float64    `json:"lat"`
+	Lng        float64    `json:"lng"`
+	RecordedAt *time.Time `json:"recorded_at,omitempty"`
+	AgeSeconds *int64     `json:"age_seconds,omitempty"`
+}
+type LocationFixRequest struct {
+	Lat        float64   `json:"lat" binding:"required"`
+	Lng        float64   `json:"lng" binding:"required"`
+	RecordedAt time.Time `json:"recorded_at" binding:"required"`
+}
+type BatchLocationRequest struct {
+	Fixes []LocationFixRequest `json:"fixes" binding:"required,gt=0,max=500,dive"`
+}
+type BatchLocationResponse struct {
+	Accepted int `json:"accepted"`
+	Dropped  int `json:"dropped"`
+	Rejected int `json:"rejected"`
+}
+type OrderEventResponse struct {
+	Type      string                 `json:"type"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+type CreateShareLinkRequest struct {
+	ExpiresInMinutes int `json:"expires_in_minutes" binding:"omitempty,gt=0,max=1440"`
+}
+type ShareLinkResponse struct {
+	ID        string     `json:"id"`
+	Token     string     `json:"token,omitempty"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+type CoarseLocation struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+type PublicTrackingResponse struct {
+	Status          string          `json:"status"`
+	DriverFirstName string          `json:"driver_first_name,omitempty"`
+	EtaMinutes      *int            `json:"eta_minutes,omitempty"`
+	DriverLocation  *CoarseLocation `json:"driver_location,omitempty"`
+	UpdatedAt       *time.Time      `json:"updated_at,omitempty"`
 }
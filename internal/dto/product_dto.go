@@ -1,11 +1,13 @@
 package dto
 
 type ProductResponse struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Price       float64 `json:"price"`
-	Description string  `json:"description"`
-	IsActive    bool    `json:"is_active"`
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	Price         float64 `json:"price"`
+	Description   string  `json:"description"`
+	IsActive      bool    `json:"is_active"`
+	RatingAverage float64 `json:"rating_average"`
+	RatingCount   int     `json:"rating_count"`
 }
 
 type UpsertProductRequest struct {
@@ -0,0 +1,12 @@
+package dto
+
+type CreateReviewRequest struct {
+	DriverScore  int      `json:"driver_score" binding:"required,min=1,max=5"`
+	ProductScore int      `json:"product_score" binding:"required,min=1,max=5"`
+	Tags         []string `json:"tags" binding:"max=7"`
+	Comment      string   `json:"comment" binding:"max=1000"`
+}
+
+type HideReviewRequest struct {
+	Reason string `json:"reason" binding:"required,max=200"`
+}
@@ -0,0 +1,36 @@
+package dto
+
+import "time"
+
+type StoreHoursItem struct {
+	Weekday  int    `json:"weekday" binding:"min=0,max=6"`
+	OpensAt  string `json:"opens_at" binding:"required"`
+	ClosesAt string `json:"closes_at" binding:"required"`
+}
+
+// UpdateStoreHoursRequest reemplaza la semana tipo completa. Un día sin rangos queda cerrado.
+type UpdateStoreHoursRequest struct {
+	Hours []StoreHoursItem `json:"hours" binding:"required,dive"`
+}
+
+// StoreExceptionRequest cierra el local en una fecha (feriado) o le pone un horario especial
+type StoreExceptionRequest struct {
+	Date     string `json:"date" binding:"required"`
+	Closed   bool   `json:"closed"`
+	OpensAt  string `json:"opens_at"`
+	ClosesAt string `json:"closes_at"`
+	Reason   string `json:"reason" binding:"max=200"`
+}
+
+type PauseStoreRequest struct {
+	Reason string `json:"reason" binding:"required,max=200"`
+}
+
+type StoreStatusResponse struct {
+	IsOpen      bool       `json:"is_open"`
+	Paused      bool       `json:"paused"`
+	Reason      string     `json:"reason,omitempty"`
+	ClosesAt    *time.Time `json:"closes_at,omitempty"`
+	NextOpensAt *time.Time `json:"next_opens_at,omitempty"`
+	Timezone    string     `json:"timezone"`
+}
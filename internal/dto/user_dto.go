@@ -22,3 +22,52 @@ type BootstrapAdminRequest struct {
 	FullName string `json:"full_name" binding:"required"`
 	Secret   string `json:"secret" binding:"required"`
 }
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type MFATokenRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+type VerifyMFARequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code" binding:"omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" binding:"omitempty,max=20"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type ListUsersQuery struct {
+	Role   string `form:"role"`
+	Active string `form:"active" binding:"omitempty,oneof=true false"`
+	Q      string `form:"q" binding:"max=100"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=200"`
+	Offset int    `form:"offset" binding:"omitempty,min=0"`
+}
+
+type ChangeRoleRequest struct {
+	Role string `json:"role" binding:"required,max=50"`
+}
+
+type UpdateUserRequest struct {
+	FullName string `json:"full_name" binding:"required,max=100"`
+	Email    string `json:"email" binding:"required,email,max=100"`
+}
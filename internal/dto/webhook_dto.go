@@ -0,0 +1,24 @@
+package dto
+
+import "time"
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required,url"`
+	Events []string `json:"events" binding:"required,gt=0"`
+	Secret string   `json:"secret" binding:"omitempty,min=16"`
+}
+
+type UpdateWebhookRequest struct {
+	URL      *string  `json:"url" binding:"omitempty,url"`
+	Events   []string `json:"events"`
+	IsActive *bool    `json:"is_active"`
+}
+
+type WebhookResponse struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	IsActive  bool      `json:"is_active"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
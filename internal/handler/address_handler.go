@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"tracking/internal/dto"
+	"tracking/internal/service"
+	"tracking/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AddressHandler struct {
+	svc service.AddressServiceInterface
+}
+
+func NewAddressHandler(svc service.AddressServiceInterface) *AddressHandler {
+	return &AddressHandler{svc: svc}
+}
+
+// CreateAddress godoc
+// @Summary Guardar una dirección
+// @Description Guarda una dirección con etiqueta (ej. "Casa") y la geocodifica una sola vez.
+// @Tags Direcciones
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param address body dto.CreateAddressRequest true "Dirección"
+// @Success 201 {object} dto.AddressResponse
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /me/addresses [post]
+func (h *AddressHandler) CreateAddress(c *gin.Context) {
+	var req dto.CreateAddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(string)
+
+	address, err := h.svc.CreateAddress(c.Request.Context(), userID, req)
+	if err != nil {
+		respondAddressError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, address)
+}
+
+// ListAddresses godoc
+// @Summary Listar mis direcciones
+// @Tags Direcciones
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} dto.AddressResponse
+// @Router /me/addresses [get]
+func (h *AddressHandler) ListAddresses(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	addresses, err := h.svc.ListAddresses(c.Request.Context(), userID)
+	if err != nil {
+		respondAddressError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, addresses)
+}
+
+// UpdateAddress godoc
+// @Summary Editar una dirección
+// @Description Si cambia el texto de la dirección se vuelve a geocodificar y se descarta el pin manual.
+// @Tags Direcciones
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "ID de la dirección"
+// @Param address body dto.UpdateAddressRequest true "Campos a actualizar"
+// @Success 200 {object} dto.AddressResponse
+// @Failure 404 {object} map[string]string
+// @Router /me/addresses/{id} [patch]
+func (h *AddressHandler) UpdateAddress(c *gin.Context) {
+	var req dto.UpdateAddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(string)
+
+	address, err := h.svc.UpdateAddress(c.Request.Context(), userID, c.Param("id"), req)
+	if err != nil {
+		respondAddressError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, address)
+}
+
+// UpdateAddressPin godoc
+// @Summary Corregir la ubicación con un pin
+// @Description Reemplaza las coordenadas geocodificadas por las del pin soltado en el mapa. Debe estar dentro de Rafaela.
+// @Tags Direcciones
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "ID de la dirección"
+// @Param pin body dto.UpdateAddressPinRequest true "Coordenadas del pin"
+// @Success 200 {object} dto.AddressResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /me/addresses/{id}/pin [put]
+func (h *AddressHandler) UpdateAddressPin(c *gin.Context) {
+	var req dto.UpdateAddressPinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(string)
+
+	address, err := h.svc.UpdatePin(c.Request.Context(), userID, c.Param("id"), req)
+	if err != nil {
+		respondAddressError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, address)
+}
+
+// DeleteAddress godoc
+// @Summary Eliminar una dirección
+// @Tags Direcciones
+// @Security BearerAuth
+// @Param id path string true "ID de la dirección"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /me/addresses/{id} [delete]
+func (h *AddressHandler) DeleteAddress(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	if err := h.svc.DeleteAddress(c.Request.Context(), userID, c.Param("id")); err != nil {
+		respondAddressError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondAddressError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, utils.ErrAddressNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrAddressLabelTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrInvalidAddress), errors.Is(err, utils.ErrAddressOutsideArea):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar la solicitud"})
+	}
+}
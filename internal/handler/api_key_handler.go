@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"tracking/internal/dto"
+	"tracking/internal/service"
+	"tracking/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	svc service.APIKeyServiceInterface
+}
+
+func NewAPIKeyHandler(svc service.APIKeyServiceInterface) *APIKeyHandler {
+	return &APIKeyHandler{svc: svc}
+}
+
+// CreateAPIKey godoc
+// @Summary Crear API key (Admin)
+// @Description Emite una key para que el sistema de un comercio actúe en nombre de su cuenta. La key completa se devuelve solo en esta respuesta. Scopes: orders:read, orders:write, products:read.
+// @Tags API Keys
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body dto.CreateAPIKeyRequest true "Datos de la key"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /admin/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req dto.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	adminID := c.MustGet("user_id").(string)
+
+	apiKey, key, err := h.svc.CreateAPIKey(c.Request.Context(), req, adminID)
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"key": key, "api_key": apiKey})
+}
+
+// ListAPIKeys godoc
+// @Summary Listar API keys (Admin)
+// @Tags API Keys
+// @Security BearerAuth
+// @Produce json
+// @Param user_id query string false "Cuenta del comercio"
+// @Success 200 {array} domain.APIKey
+// @Router /admin/api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.svc.ListAPIKeys(c.Request.Context(), c.Query("user_id"))
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// GetAPIKey godoc
+// @Summary Ver una API key (Admin)
+// @Tags API Keys
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "ID de la key"
+// @Success 200 {object} domain.APIKey
+// @Failure 404 {object} map[string]string
+// @Router /admin/api-keys/{id} [get]
+func (h *APIKeyHandler) GetAPIKey(c *gin.Context) {
+	key, err := h.svc.GetAPIKey(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, key)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revocar API key (Admin)
+// @Description La key deja de funcionar en el acto
+// @Tags API Keys
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "ID de la key"
+// @Success 200 {object} domain.APIKey
+// @Failure 404 {object} map[string]string
+// @Router /admin/api-keys/{id}/revoke [post]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	key, err := h.svc.RevokeAPIKey(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, key)
+}
+
+// GetAPIKeyUsage godoc
+// @Summary Uso de una API key (Admin)
+// @Description Requests y errores por día. Sin fechas devuelve el mes en curso.
+// @Tags API Keys
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "ID de la key"
+// @Param from query string false "Desde (AAAA-MM-DD)"
+// @Param to query string false "Hasta inclusive (AAAA-MM-DD)"
+// @Success 200 {array} domain.APIKeyUsage
+// @Failure 404 {object} map[string]string
+// @Router /admin/api-keys/{id}/usage [get]
+func (h *APIKeyHandler) GetAPIKeyUsage(c *gin.Context) {
+	usage, err := h.svc.GetAPIKeyUsage(c.Request.Context(), c.Param("id"), c.Query("from"), c.Query("to"))
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
+
+func respondAPIKeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, utils.ErrAPIKeyNotFound), errors.Is(err, utils.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrInvalidAPIKeyScope), errors.Is(err, utils.ErrInvalidAllowedIP),
+		errors.Is(err, utils.ErrAPIKeyAdminAccount), errors.Is(err, utils.ErrInvalidDate), errors.Is(err, utils.ErrInvalidPeriod):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar la solicitud"})
+	}
+}
@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"tracking/internal/dto"
+	"tracking/internal/service"
+	"tracking/internal/statement"
+	"tracking/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EarningHandler struct {
+	svc service.EarningServiceInterface
+}
+
+func NewEarningHandler(svc service.EarningServiceInterface) *EarningHandler {
+	return &EarningHandler{svc: svc}
+}
+
+// GetMyEarnings godoc
+// @Summary Ver mis ganancias
+// @Description Resumen por pedido (monto fijo, km y propinas) con lo pagado y lo pendiente. Sin fechas devuelve el mes en curso.
+// @Tags Earnings
+// @Security BearerAuth
+// @Produce json
+// @Param from query string false "Desde (AAAA-MM-DD)"
+// @Param to query string false "Hasta inclusive (AAAA-MM-DD)"
+// @Success 200 {object} domain.EarningsReport
+// @Failure 400 {object} map[string]string
+// @Router /drivers/me/earnings [get]
+func (h *EarningHandler) GetMyEarnings(c *gin.Context) {
+	h.respondEarnings(c, c.MustGet("user_id").(string))
+}
+
+// GetMyStatement godoc
+// @Summary Descargar mi resumen de ganancias
+// @Tags Earnings
+// @Security BearerAuth
+// @Produce text/csv,application/pdf
+// @Param from query string false "Desde (AAAA-MM-DD)"
+// @Param to query string false "Hasta inclusive (AAAA-MM-DD)"
+// @Param format query string false "csv (default) o pdf"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Router /drivers/me/earnings/statement [get]
+func (h *EarningHandler) GetMyStatement(c *gin.Context) {
+	h.respondStatement(c, c.MustGet("user_id").(string))
+}
+
+// GetDriverEarnings godoc
+// @Summary Ver ganancias de un repartidor (Admin)
+// @Tags Earnings
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "ID del repartidor"
+// @Param from query string false "Desde (AAAA-MM-DD)"
+// @Param to query string false "Hasta inclusive (AAAA-MM-DD)"
+// @Success 200 {object} domain.EarningsReport
+// @Failure 404 {object} map[string]string
+// @Router /admin/drivers/{id}/earnings [get]
+func (h *EarningHandler) GetDriverEarnings(c *gin.Context) {
+	h.respondEarnings(c, c.Param("id"))
+}
+
+// GetDriverStatement godoc
+// @Summary Descargar resumen de ganancias de un repartidor (Admin)
+// @Tags Earnings
+// @Security BearerAuth
+// @Produce text/csv,application/pdf
+// @Param id path string true "ID del repartidor"
+// @Param from query string false "Desde (AAAA-MM-DD)"
+// @Param to query string false "Hasta inclusive (AAAA-MM-DD)"
+// @Param format query string false "csv (default) o pdf"
+// @Success 200 {file} file
+// @Failure 404 {object} map[string]string
+// @Router /admin/drivers/{id}/earnings/statement [get]
+func (h *EarningHandler) GetDriverStatement(c *gin.Context) {
+	h.respondStatement(c, c.Param("id"))
+}
+
+// CreatePayoutBatch godoc
+// @Summary Liquidar ganancias pendientes
+// @Description Crea un pago por repartidor con todo lo pendiente hasta until y lo marca como pagado. Solo ADMIN.
+// @Tags Earnings
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body dto.CreatePayoutBatchRequest false "Fecha de corte"
+// @Success 201 {object} domain.PayoutBatch
+// @Failure 409 {object} map[string]string
+// @Router /admin/payouts [post]
+func (h *EarningHandler) CreatePayoutBatch(c *gin.Context) {
+	var req dto.CreatePayoutBatchRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+	}
+
+	adminID := c.MustGet("user_id").(string)
+
+	batch, err := h.svc.CreatePayoutBatch(c.Request.Context(), req.Until, adminID)
+	if err != nil {
+		respondEarningError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, batch)
+}
+
+// ListPayoutBatches godoc
+// @Summary Listar liquidaciones
+// @Tags Earnings
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} domain.PayoutBatch
+// @Router /admin/payouts [get]
+func (h *EarningHandler) ListPayoutBatches(c *gin.Context) {
+	batches, err := h.svc.ListPayoutBatches(c.Request.Context())
+	if err != nil {
+		respondEarningError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, batches)
+}
+
+// GetPayoutBatch godoc
+// @Summary Ver una liquidación
+// @Description Incluye el monto pagado a cada repartidor
+// @Tags Earnings
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "ID de la liquidación"
+// @Success 200 {object} domain.PayoutBatch
+// @Failure 404 {object} map[string]string
+// @Router /admin/payouts/{id} [get]
+func (h *EarningHandler) GetPayoutBatch(c *gin.Context) {
+	batch, err := h.svc.GetPayoutBatch(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondEarningError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+func (h *EarningHandler) respondEarnings(c *gin.Context, driverID string) {
+	report, err := h.svc.GetEarnings(c.Request.Context(), driverID, c.Query("from"), c.Query("to"))
+	if err != nil {
+		respondEarningError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *EarningHandler) respondStatement(c *gin.Context, driverID string) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "pdf" {
+		respondEarningError(c, utils.ErrInvalidStatementFormat)
+		return
+	}
+
+	report, err := h.svc.GetEarnings(c.Request.Context(), driverID, c.Query("from"), c.Query("to"))
+	if err != nil {
+		respondEarningError(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	contentType := "text/csv; charset=utf-8"
+	write := statement.WriteCSV
+	if format == "pdf" {
+		contentType = "application/pdf"
+		write = statement.WritePDF
+	}
+	if err := write(&buf, report); err != nil {
+		respondEarningError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+statement.Filename(report, format)+`"`)
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+func respondEarningError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, utils.ErrDriverNotFound), errors.Is(err, utils.ErrPayoutNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrInvalidDate), errors.Is(err, utils.ErrInvalidPeriod), errors.Is(err, utils.ErrInvalidStatementFormat):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrNoEarningsToPay):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar la solicitud"})
+	}
+}
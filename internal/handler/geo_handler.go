@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"tracking/internal/dto"
+	"tracking/internal/service"
+	"tracking/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GeoHandler struct {
+	svc service.GeoServiceInterface
+}
+
+func NewGeoHandler(svc service.GeoServiceInterface) *GeoHandler {
+	return &GeoHandler{svc: svc}
+}
+
+// Autocomplete godoc
+// @Summary Sugerencias de direcciones
+// @Description Devuelve hasta 5 direcciones de Rafaela que coinciden con el texto. Las respuestas se cachean y hay un límite de búsquedas por minuto por usuario.
+// @Tags Geo
+// @Security BearerAuth
+// @Produce json
+// @Param q query string true "Texto a buscar (mínimo 3 caracteres)"
+// @Success 200 {array} dto.GeoSuggestionResponse
+// @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /geo/autocomplete [get]
+func (h *GeoHandler) Autocomplete(c *gin.Context) {
+	var query dto.AutocompleteQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(string)
+
+	suggestions, err := h.svc.Autocomplete(c.Request.Context(), userID, query.Q)
+	if err != nil {
+		respondGeoError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// Reverse godoc
+// @Summary Dirección de un punto del mapa
+// @Description Devuelve la dirección legible más cercana al pin. El punto debe estar dentro de Rafaela.
+// @Tags Geo
+// @Security BearerAuth
+// @Produce json
+// @Param lat query number true "Latitud"
+// @Param lng query number true "Longitud"
+// @Success 200 {object} dto.GeoSuggestionResponse
+// @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /geo/reverse [get]
+func (h *GeoHandler) Reverse(c *gin.Context) {
+	var query dto.ReverseGeocodeQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(string)
+
+	suggestion, err := h.svc.Reverse(c.Request.Context(), userID, query.Lat, query.Lng)
+	if err != nil {
+		respondGeoError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestion)
+}
+
+func respondGeoError(c *gin.Context, err error) {
+	var rateErr *utils.RateLimitError
+	switch {
+	case errors.As(err, &rateErr):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(rateErr.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrAddressOutsideArea):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrGeocodingUnavailable):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar la solicitud"})
+	}
+}
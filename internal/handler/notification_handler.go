@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"tracking/internal/dto"
+	"tracking/internal/service"
+	"tracking/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	svc service.NotificationServiceInterface
+}
+
+func NewNotificationHandler(svc service.NotificationServiceInterface) *NotificationHandler {
+	return &NotificationHandler{svc: svc}
+}
+
+// GetPreferences godoc
+// @Summary Ver mis preferencias de notificación
+// @Description Canales activos (email, SMS, push), idioma y destinos. Si nunca se configuraron devuelve los valores por defecto.
+// @Tags Notificaciones
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} domain.NotificationPreferences
+// @Router /me/notification-preferences [get]
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	prefs, err := h.svc.GetPreferences(c.Request.Context(), userID)
+	if err != nil {
+		respondNotificationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdatePreferences godoc
+// @Summary Actualizar mis preferencias de notificación
+// @Description Solo cambia los campos enviados. Para activar SMS o push hace falta el teléfono o el token del dispositivo.
+// @Tags Notificaciones
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param preferences body dto.UpdateNotificationPreferencesRequest true "Preferencias"
+// @Success 200 {object} domain.NotificationPreferences
+// @Failure 400 {object} map[string]string
+// @Router /me/notification-preferences [put]
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	var req dto.UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(string)
+
+	prefs, err := h.svc.UpdatePreferences(c.Request.Context(), userID, req)
+	if err != nil {
+		respondNotificationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// ListMyNotifications godoc
+// @Summary Ver mis notificaciones
+// @Tags Notificaciones
+// @Security BearerAuth
+// @Produce json
+// @Param order_id query string false "Filtrar por pedido"
+// @Success 200 {array} domain.Notification
+// @Router /me/notifications [get]
+func (h *NotificationHandler) ListMyNotifications(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	notifications, err := h.svc.ListNotifications(c.Request.Context(), userID, c.Query("order_id"), "")
+	if err != nil {
+		respondNotificationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
+
+// ListNotifications godoc
+// @Summary Listar envíos de notificaciones
+// @Description Registro de cada envío con su canal y estado (PENDING, SENT, FAILED). Solo ADMIN.
+// @Tags Notificaciones
+// @Security BearerAuth
+// @Produce json
+// @Param user_id query string false "Filtrar por usuario"
+// @Param order_id query string false "Filtrar por pedido"
+// @Param status query string false "Filtrar por estado"
+// @Success 200 {array} domain.Notification
+// @Router /admin/notifications [get]
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	notifications, err := h.svc.ListNotifications(c.Request.Context(), c.Query("user_id"), c.Query("order_id"), c.Query("status"))
+	if err != nil {
+		respondNotificationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
+
+// ListCapturedEmails godoc
+// @Summary Ver emails capturados
+// @Description Emails retenidos en memoria cuando NOTIFY_EMAIL_DRIVER=capture, del más nuevo al más viejo. Los emails de reseteo de contraseña y verificación se devuelven sin cuerpo.
+// @Tags Notificaciones
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} dto.CapturedEmailResponse
+// @Router /admin/notifications/captured-emails [get]
+func (h *NotificationHandler) ListCapturedEmails(c *gin.Context) {
+	c.JSON(http.StatusOK, h.svc.CapturedEmails())
+}
+
+func respondNotificationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, utils.ErrInvalidLocale), errors.Is(err, utils.ErrPhoneRequired), errors.Is(err, utils.ErrPushTokenRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar la solicitud"})
+	}
+}
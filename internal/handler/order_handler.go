@@ -1,10 +1,16 @@
 package handler
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"io"
 	"log/slog"
 	"net/http"
+	"time"
+	"tracking/internal/domain"
 	"tracking/internal/dto"
+	"tracking/internal/policy"
 	"tracking/internal/service"
 	"tracking/internal/utils"
 
@@ -23,13 +29,14 @@ func NewOrderHandler(oSvc service.OrderServiceInterface, locationSvc *service.Lo
 
 // Create godoc
 // @Summary Crear un nuevo pedido
-// @Description Toma la dirección del cliente, busca las coordenadas y guarda el pedido
+// @Description Toma la dirección del cliente (texto libre o address_id de una dirección guardada), busca las coordenadas y guarda el pedido. Con scheduled_for se programa para una franja de entrega.
 // @Tags Orders
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param order body dto.CreateOrderRequest true "Datos del pedido"
 // @Success 201 {object} map[string]string
+// @Failure 422 {object} utils.ErrorResponse "STORE_CLOSED o STORE_PAUSED"
 // @Router /orders [post]
 func (h *OrderHandler) Create(c *gin.Context) {
 	var req dto.CreateOrderRequest
@@ -42,10 +49,28 @@ func (h *OrderHandler) Create(c *gin.Context) {
 
 	id, err := h.svc.CreateOrder(c.Request.Context(), req, userID)
 	if err != nil {
-		if errors.Is(err, utils.ErrInvalidAddress) {
+		var appErr *utils.AppError
+		if errors.As(err, &appErr) {
+			c.JSON(appErr.StatusCode, appErr.ToErrorResponse())
+			return
+		}
+		if errors.Is(err, utils.ErrInvalidAddress) || errors.Is(err, utils.ErrDestinationRequired) ||
+			errors.Is(err, utils.ErrInvalidDeliveryWindow) || errors.Is(err, utils.ErrDeliveryWindowClosed) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, utils.ErrDeliverySlotFull) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, utils.ErrAddressNotFound) || errors.Is(err, utils.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, utils.ErrEmailNotVerified) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al crear pedido"})
 		return
 	}
@@ -56,9 +81,33 @@ func (h *OrderHandler) Create(c *gin.Context) {
 	})
 }
 
+// ListDeliverySlots godoc
+// @Summary Listar franjas de entrega disponibles
+// @Description Franjas reservables de un día para pedidos programados, con el lugar que les queda
+// @Tags Orders
+// @Security BearerAuth
+// @Produce json
+// @Param date query string true "Día en formato AAAA-MM-DD"
+// @Success 200 {array} dto.DeliverySlotResponse
+// @Failure 400 {object} map[string]string
+// @Router /orders/slots [get]
+func (h *OrderHandler) ListDeliverySlots(c *gin.Context) {
+	slots, err := h.svc.ListDeliverySlots(c.Request.Context(), c.Query("date"))
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidDate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		respondOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, slots)
+}
+
 // GetPending godoc
 // @Summary Listar pedidos pendientes
-// @Description Obtiene todos los pedidos con estado 'PENDING' disponibles para ser aceptados
+// @Description Obtiene todos los pedidos con estado 'PENDING' disponibles para ser aceptados. Los programados aparecen recién cuando se acerca su franja.
 // @Tags Orders
 // @Security BearerAuth
 // @Produce json
@@ -102,14 +151,52 @@ func (h *OrderHandler) Accept(c *gin.Context) {
 
 // UpdateLocation godoc
 // @Summary Actualizar GPS (Driver)
-// @Description Guarda la ubicación actual en Redis
+// @Description Recibe los fixes con timestamp que registró el driver, también los acumulados mientras estuvo sin señal, como array o como {"fixes": [...]}. Se guardan en el historial en orden; duplicados y fixes desordenados se descartan (dropped) y los saltos a velocidades imposibles se rechazan (rejected). Solo el más nuevo actualiza la posición en vivo. Por compatibilidad también acepta un único fix {lat, lng, recorded_at}: ese caso responde 409 si no es posterior al último y 422 si es un salto imposible.
 // @Tags Orders
 // @Security BearerAuth
 // @Accept json
-// @Param location body dto.UpdateLocationRequest true "Coordenadas"
-// @Success 200 {object} map[string]string
+// @Produce json
+// @Param locations body dto.BatchLocationRequest true "Fixes con timestamp"
+// @Success 200 {object} dto.BatchLocationResponse
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} map[string]string
 // @Router /orders/location [post]
 func (h *OrderHandler) UpdateLocation(c *gin.Context) {
+	raw, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cuerpo inválido"})
+		return
+	}
+
+	// El body se vuelve a leer con el DTO que corresponda a su forma
+	body, batch := locationBody(raw)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if batch {
+		h.updateLocationBatch(c)
+		return
+	}
+	h.updateSingleLocation(c)
+}
+
+// locationBody distingue un lote (array o {"fixes": [...]}) de un único fix. Un array se envuelve
+// como {"fixes": ...} para validarlo con BatchLocationRequest.
+func locationBody(raw []byte) ([]byte, bool) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		return append(append([]byte(`{"fixes":`), trimmed...), '}'), true
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &fields); err == nil {
+		if _, ok := fields["fixes"]; ok {
+			return raw, true
+		}
+	}
+	return raw, false
+}
+
+func (h *OrderHandler) updateSingleLocation(c *gin.Context) {
 	var body dto.UpdateLocationRequest
 
 	// 1. Validar que el JSON tenga lat y lng
@@ -121,9 +208,22 @@ func (h *OrderHandler) UpdateLocation(c *gin.Context) {
 	// 2. Obtener el ID del Driver desde el Contexto (inyectado por el AuthMiddleware)
 	driverID := c.MustGet("user_id").(string)
 
+	fix := domain.LocationFix{Lat: body.Lat, Lng: body.Lng}
+	if body.RecordedAt != nil {
+		fix.RecordedAt = *body.RecordedAt
+	}
+
 	// 3. Llamar al servicio de ubicación para guardar en Redis
-	err := h.locationSvc.UpdateLocation(c.Request.Context(), driverID, body.Lat, body.Lng)
+	err := h.locationSvc.UpdateLocation(c.Request.Context(), driverID, fix)
 	if err != nil {
+		if errors.Is(err, utils.ErrImpossibleLocationJump) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, utils.ErrStaleLocationFix) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar ubicación en tiempo real"})
 		return
 	}
@@ -131,19 +231,44 @@ func (h *OrderHandler) UpdateLocation(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "Ubicación actualizada correctamente"})
 }
 
+func (h *OrderHandler) updateLocationBatch(c *gin.Context) {
+	var body dto.BatchLocationRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	driverID := c.MustGet("user_id").(string)
+
+	fixes := make([]domain.LocationFix, len(body.Fixes))
+	for i, f := range body.Fixes {
+		fixes[i] = domain.LocationFix{Lat: f.Lat, Lng: f.Lng, RecordedAt: f.RecordedAt}
+	}
+
+	accepted, dropped, rejected, err := h.locationSvc.UpdateLocationBatch(c.Request.Context(), driverID, fixes)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidLocationBatch) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar el lote de ubicaciones"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.BatchLocationResponse{Accepted: accepted, Dropped: dropped, Rejected: rejected})
+}
+
 // GetOrderLocation godoc
 // @Summary Consultar ubicación de un pedido (Cliente)
-// @Description Obtiene la última posición registrada en Redis del driver asignado a la orden
+// @Description Obtiene la última posición registrada en Redis del driver asignado a la orden, con recorded_at y age_seconds para detectar posiciones viejas
 // @Tags Orders
 // @Security BearerAuth
 // @Param id path string true "ID del pedido"
 // @Produce json
-// @Success 200 {object} map[string]float64
+// @Success 200 {object} dto.OrderLocationResponse
 // @Router /orders/{id}/location [get]
 func (h *OrderHandler) GetOrderLocation(c *gin.Context) {
 	orderID := c.Param("id")
-	userID := c.MustGet("user_id").(string)
-	role := c.MustGet("role").(string)
 
 	order, err := h.svc.GetOrderById(c.Request.Context(), orderID)
 	if err != nil {
@@ -151,7 +276,7 @@ func (h *OrderHandler) GetOrderLocation(c *gin.Context) {
 		return
 	}
 
-	if role != "admin" && order.CustomerID != userID {
+	if !policy.CanAccess(c, "orders:read", order.CustomerID) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "No tienes permiso para trackear este pedido"})
 		return
 	}
@@ -168,10 +293,18 @@ func (h *OrderHandler) GetOrderLocation(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"lat": location.Latitude,
-		"lng": location.Longitude,
-	})
+	response := dto.OrderLocationResponse{
+		Lat: location.Lat,
+		Lng: location.Lng,
+	}
+	if !location.RecordedAt.IsZero() {
+		recordedAt := location.RecordedAt
+		ageSeconds := int64(time.Since(recordedAt).Seconds())
+		response.RecordedAt = &recordedAt
+		response.AgeSeconds = &ageSeconds
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // Complete godoc
@@ -195,6 +328,85 @@ func (h *OrderHandler) Complete(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "¡Pedido entregado con éxito!"})
 }
 
+// PickUp godoc
+// @Summary Retirar pedido del local (Driver)
+// @Description Cambia el estado del pedido de ASSIGNED a PICKED_UP
+// @Tags Orders
+// @Security BearerAuth
+// @Param id path string true "ID del pedido"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /orders/{id}/pickup [patch]
+func (h *OrderHandler) PickUp(c *gin.Context) {
+	orderID := c.Param("id")
+	driverID := c.MustGet("user_id").(string)
+
+	err := h.svc.PickUpOrder(c.Request.Context(), orderID, driverID)
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Pedido retirado"})
+}
+
+// Cancel godoc
+// @Summary Cancelar pedido
+// @Description Con orders:cancel:own el cliente cancela su pedido mientras está PENDING. Con orders:cancel:any se cancela cualquier pedido no entregado.
+// @Tags Orders
+// @Security BearerAuth
+// @Param id path string true "ID del pedido"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /orders/{id}/cancel [patch]
+func (h *OrderHandler) Cancel(c *gin.Context) {
+	orderID := c.Param("id")
+	userID := c.MustGet("user_id").(string)
+	role := c.MustGet("role").(string)
+
+	err := h.svc.CancelOrder(c.Request.Context(), orderID, userID, role, policy.Can(c, domain.PermOrdersCancelAny))
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Pedido cancelado"})
+}
+
+// AddTip godoc
+// @Summary Dejar propina después de la entrega
+// @Description El cliente puede sumar una propina para el repartidor una sola vez por pedido entregado. Se guarda aparte del total de productos.
+// @Tags Orders
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "ID del pedido"
+// @Param tip body dto.AddTipRequest true "Monto de la propina"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /orders/{id}/tip [post]
+func (h *OrderHandler) AddTip(c *gin.Context) {
+	var req dto.AddTipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	orderID := c.Param("id")
+	customerID := c.MustGet("user_id").(string)
+
+	err := h.svc.AddTip(c.Request.Context(), orderID, customerID, req.Amount)
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Propina registrada"})
+}
+
 // GetHistory godoc
 // @Summary Ver historial de pedidos
 // @Description Trae todos los pedidos DELIVERED del usuario
@@ -214,3 +426,70 @@ func (h *OrderHandler) GetHistory(c *gin.Context) {
 
 	c.JSON(http.StatusOK, orders)
 }
+
+// GetTimeline godoc
+// @Summary Ver timeline de un pedido
+// @Description Devuelve los eventos registrados del pedido (ej. llegada del driver al local o al cliente)
+// @Tags Orders
+// @Security BearerAuth
+// @Param id path string true "ID del pedido"
+// @Produce json
+// @Success 200 {array} dto.OrderEventResponse
+// @Router /orders/{id}/timeline [get]
+func (h *OrderHandler) GetTimeline(c *gin.Context) {
+	orderID := c.Param("id")
+
+	order, err := h.svc.GetOrderById(c.Request.Context(), orderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pedido no encontrado"})
+		return
+	}
+
+	if !policy.CanAccess(c, "orders:read", order.CustomerID, order.DriverID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "No tienes permiso para ver este pedido"})
+		return
+	}
+
+	events, err := h.svc.GetOrderTimeline(c.Request.Context(), orderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener timeline"})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// ListLocationAnomalies godoc
+// @Summary Ver anomalías de GPS (Admin)
+// @Description Lista los fixes sospechosos registrados. Se puede filtrar por driver y por pedido.
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param driver_id query string false "ID del driver"
+// @Param order_id query string false "ID del pedido"
+// @Success 200 {array} domain.LocationAnomaly
+// @Router /admin/location-anomalies [get]
+func (h *OrderHandler) ListLocationAnomalies(c *gin.Context) {
+	anomalies, err := h.locationSvc.ListAnomalies(c.Request.Context(), c.Query("driver_id"), c.Query("order_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener anomalías"})
+		return
+	}
+
+	c.JSON(http.StatusOK, anomalies)
+}
+
+func respondOrderError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, utils.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrUnauthorizedAction):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrInvalidState), errors.Is(err, utils.ErrOrderNotAvailable),
+		errors.Is(err, utils.ErrTipNotAllowed), errors.Is(err, utils.ErrTipAlreadyAdded):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		slog.Error("error al procesar pedido", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar la solicitud"})
+	}
+}
@@ -0,0 +1,24 @@
+package handler
+
+import "testing"
+
+func TestLocationBody(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		wantBody  string
+		wantBatch bool
+	}{
+		{"un fix", `{"lat":-31.25,"lng":-61.49}`, `{"lat":-31.25,"lng":-61.49}`, false},
+		{"lote con fixes", `{"fixes":[{"lat":1,"lng":2}]}`, `{"fixes":[{"lat":1,"lng":2}]}`, true},
+		{"array", ` [{"lat":1,"lng":2}] `, `{"fixes":[{"lat":1,"lng":2}]}`, true},
+		{"JSON inválido", `{"lat":`, `{"lat":`, false},
+	}
+
+	for _, tt := range tests {
+		body, batch := locationBody([]byte(tt.raw))
+		if string(body) != tt.wantBody || batch != tt.wantBatch {
+			t.Errorf("%s: locationBody = %s, %v; se esperaba %s, %v", tt.name, body, batch, tt.wantBody, tt.wantBatch)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"tracking/internal/dto"
+	"tracking/internal/service"
+	"tracking/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PermissionHandler struct {
+	svc service.PermissionServiceInterface
+}
+
+func NewPermissionHandler(svc service.PermissionServiceInterface) *PermissionHandler {
+	return &PermissionHandler{svc: svc}
+}
+
+// ListPermissions godoc
+// @Summary Catálogo de permisos (Admin)
+// @Description Permisos que se pueden asignar a un rol. Los que terminan en :own exigen ser dueño del recurso.
+// @Tags Roles
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} domain.Permission
+// @Router /admin/permissions [get]
+func (h *PermissionHandler) ListPermissions(c *gin.Context) {
+	permissions, err := h.svc.ListPermissions(c.Request.Context())
+	if err != nil {
+		respondPermissionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, permissions)
+}
+
+// ListRoles godoc
+// @Summary Listar roles (Admin)
+// @Tags Roles
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} domain.Role
+// @Router /admin/roles [get]
+func (h *PermissionHandler) ListRoles(c *gin.Context) {
+	roles, err := h.svc.ListRoles(c.Request.Context())
+	if err != nil {
+		respondPermissionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+// CreateRole godoc
+// @Summary Crear rol (Admin)
+// @Description Crea un rol nuevo (ej. dispatcher o support) con los permisos indicados
+// @Tags Roles
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body dto.CreateRoleRequest true "Rol"
+// @Success 201 {object} domain.Role
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/roles [post]
+func (h *PermissionHandler) CreateRole(c *gin.Context) {
+	var req dto.CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	role, err := h.svc.CreateRole(c.Request.Context(), req)
+	if err != nil {
+		respondPermissionError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, role)
+}
+
+// SetRolePermissions godoc
+// @Summary Reemplazar permisos de un rol (Admin)
+// @Description El cambio aplica a todos los usuarios del rol en menos de 30 segundos. No se editan el rol admin ni el rol propio.
+// @Tags Roles
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param name path string true "Nombre del rol"
+// @Param body body dto.UpdateRolePermissionsRequest true "Permisos"
+// @Success 200 {object} domain.Role
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/roles/{name}/permissions [put]
+func (h *PermissionHandler) SetRolePermissions(c *gin.Context) {
+	var req dto.UpdateRolePermissionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	actorRole := c.MustGet("role").(string)
+
+	role, err := h.svc.SetRolePermissions(c.Request.Context(), actorRole, c.Param("name"), req.Permissions)
+	if err != nil {
+		respondPermissionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, role)
+}
+
+// DeleteRole godoc
+// @Summary Borrar rol (Admin)
+// @Description Solo roles creados desde la API y sin usuarios asignados
+// @Tags Roles
+// @Security BearerAuth
+// @Param name path string true "Nombre del rol"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/roles/{name} [delete]
+func (h *PermissionHandler) DeleteRole(c *gin.Context) {
+	if err := h.svc.DeleteRole(c.Request.Context(), c.Param("name")); err != nil {
+		respondPermissionError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetMyPermissions godoc
+// @Summary Ver mis permisos
+// @Description Permisos del rol del usuario autenticado, para mostrar solo las acciones disponibles
+// @Tags Roles
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /me/permissions [get]
+func (h *PermissionHandler) GetMyPermissions(c *gin.Context) {
+	role := c.MustGet("role").(string)
+
+	permissions, err := h.svc.MyPermissions(c.Request.Context(), role)
+	if err != nil {
+		respondPermissionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"role": role, "permissions": permissions})
+}
+
+func respondPermissionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, utils.ErrRoleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrInvalidRoleName), errors.Is(err, utils.ErrInvalidPermission),
+		errors.Is(err, utils.ErrAdminRoleLocked), errors.Is(err, utils.ErrSystemRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrOwnRoleLocked):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrRoleAlreadyExists), errors.Is(err, utils.ErrRoleInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar la solicitud"})
+	}
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"tracking/internal/domain"
+	"tracking/internal/dto"
+	"tracking/internal/policy"
+	"tracking/internal/service"
+	"tracking/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReviewHandler struct {
+	svc service.ReviewServiceInterface
+}
+
+func NewReviewHandler(svc service.ReviewServiceInterface) *ReviewHandler {
+	return &ReviewHandler{svc: svc}
+}
+
+// CreateReview godoc
+// @Summary Calificar un pedido entregado
+// @Description Puntaje de 1 a 5 para el repartidor y para los productos, etiquetas y comentario. Una sola vez por pedido y dentro del plazo desde la entrega.
+// @Tags Reviews
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "ID del pedido"
+// @Param review body dto.CreateReviewRequest true "Calificación"
+// @Success 201 {object} domain.Review
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /orders/{id}/review [post]
+func (h *ReviewHandler) CreateReview(c *gin.Context) {
+	var req dto.CreateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(string)
+
+	review, err := h.svc.CreateReview(c.Request.Context(), c.Param("id"), userID, req)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, review)
+}
+
+// GetOrderReview godoc
+// @Summary Ver la reseña de un pedido
+// @Tags Reviews
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "ID del pedido"
+// @Success 200 {object} domain.Review
+// @Failure 404 {object} map[string]string
+// @Router /orders/{id}/review [get]
+func (h *ReviewHandler) GetOrderReview(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	review, err := h.svc.GetOrderReview(c.Request.Context(), c.Param("id"), userID, policy.Can(c, domain.PermReviewsReadAny))
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// GetMyRating godoc
+// @Summary Ver mi calificación promedio
+// @Description Promedio de las reseñas visibles del repartidor autenticado
+// @Tags Reviews
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} domain.RatingSummary
+// @Router /drivers/me/rating [get]
+func (h *ReviewHandler) GetMyRating(c *gin.Context) {
+	driverID := c.MustGet("user_id").(string)
+
+	summary, err := h.svc.GetDriverRating(c.Request.Context(), driverID)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// ListReviews godoc
+// @Summary Listar reseñas
+// @Description Para moderación. Solo ADMIN.
+// @Tags Reviews
+// @Security BearerAuth
+// @Produce json
+// @Param driver_id query string false "Filtrar por repartidor"
+// @Param hidden query bool false "Filtrar por ocultas o visibles"
+// @Success 200 {array} domain.Review
+// @Router /admin/reviews [get]
+func (h *ReviewHandler) ListReviews(c *gin.Context) {
+	var hidden *bool
+	if raw := c.Query("hidden"); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "hidden debe ser true o false"})
+			return
+		}
+		hidden = &value
+	}
+
+	reviews, err := h.svc.ListReviews(c.Request.Context(), c.Query("driver_id"), hidden)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+// HideReview godoc
+// @Summary Ocultar una reseña abusiva
+// @Description La reseña deja de mostrarse y de contar en los promedios. Solo ADMIN.
+// @Tags Reviews
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "ID de la reseña"
+// @Param body body dto.HideReviewRequest true "Motivo"
+// @Success 200 {object} domain.Review
+// @Failure 404 {object} map[string]string
+// @Router /admin/reviews/{id}/hide [patch]
+func (h *ReviewHandler) HideReview(c *gin.Context) {
+	var req dto.HideReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	adminID := c.MustGet("user_id").(string)
+
+	review, err := h.svc.HideReview(c.Request.Context(), c.Param("id"), req.Reason, adminID)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// UnhideReview godoc
+// @Summary Volver a mostrar una reseña
+// @Tags Reviews
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "ID de la reseña"
+// @Success 200 {object} domain.Review
+// @Failure 404 {object} map[string]string
+// @Router /admin/reviews/{id}/unhide [patch]
+func (h *ReviewHandler) UnhideReview(c *gin.Context) {
+	review, err := h.svc.UnhideReview(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+func respondReviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, utils.ErrOrderNotFound), errors.Is(err, utils.ErrReviewNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrUnauthorizedAction):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrReviewAlreadyExists), errors.Is(err, utils.ErrReviewNotAllowed), errors.Is(err, utils.ErrReviewWindowExpired):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrInvalidReviewTag):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar la solicitud"})
+	}
+}
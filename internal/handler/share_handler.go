@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"tracking/internal/dto"
+	"tracking/internal/service"
+	"tracking/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ShareHandler struct {
+	svc service.ShareServiceInterface
+}
+
+func NewShareHandler(svc service.ShareServiceInterface) *ShareHandler {
+	return &ShareHandler{svc: svc}
+}
+
+// CreateShareLink godoc
+// @Summary Compartir seguimiento de un pedido (Cliente)
+// @Description Genera un token firmado, con vencimiento y revocable, para seguir el pedido sin login
+// @Tags Tracking
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "ID del pedido"
+// @Param data body dto.CreateShareLinkRequest false "Vencimiento del link"
+// @Success 201 {object} dto.ShareLinkResponse
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /orders/{id}/share [post]
+func (h *ShareHandler) CreateShareLink(c *gin.Context) {
+	var req dto.CreateShareLinkRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+			return
+		}
+	}
+
+	customerID := c.MustGet("user_id").(string)
+	ttl := time.Duration(req.ExpiresInMinutes) * time.Minute
+
+	link, err := h.svc.CreateShareLink(c.Request.Context(), c.Param("id"), customerID, ttl)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, link)
+}
+
+// ListShareLinks godoc
+// @Summary Listar links de seguimiento de un pedido (Cliente)
+// @Tags Tracking
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "ID del pedido"
+// @Success 200 {array} dto.ShareLinkResponse
+// @Router /orders/{id}/share [get]
+func (h *ShareHandler) ListShareLinks(c *gin.Context) {
+	customerID := c.MustGet("user_id").(string)
+
+	links, err := h.svc.ListShareLinks(c.Request.Context(), c.Param("id"), customerID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+// RevokeShareLink godoc
+// @Summary Revocar un link de seguimiento (Cliente)
+// @Tags Tracking
+// @Security BearerAuth
+// @Param id path string true "ID del pedido"
+// @Param linkId path string true "ID del link"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /orders/{id}/share/{linkId} [delete]
+func (h *ShareHandler) RevokeShareLink(c *gin.Context) {
+	customerID := c.MustGet("user_id").(string)
+
+	err := h.svc.RevokeShareLink(c.Request.Context(), c.Param("id"), c.Param("linkId"), customerID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// PublicTrack godoc
+// @Summary Seguimiento público de un pedido
+// @Description No requiere login. Devuelve estado, nombre del driver, ETA y una posición aproximada, sin datos del cliente.
+// @Tags Tracking
+// @Produce json
+// @Param token path string true "Token del link compartido"
+// @Success 200 {object} dto.PublicTrackingResponse
+// @Failure 404 {object} map[string]string
+// @Router /public/track/{token} [get]
+func (h *ShareHandler) PublicTrack(c *gin.Context) {
+	tracking, err := h.svc.GetPublicTracking(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tracking)
+}
+
+func (h *ShareHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, utils.ErrOrderNotFound), errors.Is(err, utils.ErrShareLinkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrUnauthorizedAction):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrInvalidState):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar la solicitud"})
+	}
+}
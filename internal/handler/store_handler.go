@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"tracking/internal/dto"
+	"tracking/internal/service"
+	"tracking/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StoreHandler struct {
+	svc service.StoreServiceInterface
+}
+
+func NewStoreHandler(svc service.StoreServiceInterface) *StoreHandler {
+	return &StoreHandler{svc: svc}
+}
+
+// GetStatus godoc
+// @Summary Estado del local
+// @Description Indica si el local está abierto ahora (hora de Buenos Aires), hasta cuándo, o cuándo vuelve a abrir
+// @Tags Store
+// @Produce json
+// @Success 200 {object} dto.StoreStatusResponse
+// @Router /store/status [get]
+func (h *StoreHandler) GetStatus(c *gin.Context) {
+	status, err := h.svc.GetStatus(c.Request.Context())
+	if err != nil {
+		respondStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// GetHours godoc
+// @Summary Ver la semana tipo
+// @Tags Store
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} domain.StoreHours
+// @Router /admin/store/hours [get]
+func (h *StoreHandler) GetHours(c *gin.Context) {
+	hours, err := h.svc.GetWeeklyHours(c.Request.Context())
+	if err != nil {
+		respondStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, hours)
+}
+
+// UpdateHours godoc
+// @Summary Reemplazar la semana tipo
+// @Description Reemplaza todos los rangos de apertura. weekday 0 es domingo; un día sin rangos queda cerrado. Solo ADMIN.
+// @Tags Store
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param hours body dto.UpdateStoreHoursRequest true "Rangos por día"
+// @Success 200 {array} domain.StoreHours
+// @Failure 400 {object} map[string]string
+// @Router /admin/store/hours [put]
+func (h *StoreHandler) UpdateHours(c *gin.Context) {
+	var req dto.UpdateStoreHoursRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	hours, err := h.svc.UpdateWeeklyHours(c.Request.Context(), req)
+	if err != nil {
+		respondStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, hours)
+}
+
+// ListExceptions godoc
+// @Summary Listar feriados y horarios especiales
+// @Tags Store
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} domain.StoreException
+// @Router /admin/store/exceptions [get]
+func (h *StoreHandler) ListExceptions(c *gin.Context) {
+	exceptions, err := h.svc.ListExceptions(c.Request.Context())
+	if err != nil {
+		respondStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, exceptions)
+}
+
+// SaveException godoc
+// @Summary Cargar un feriado u horario especial
+// @Description Con closed=true el local no abre esa fecha; si no, usa opens_at y closes_at en lugar de la semana tipo. Solo ADMIN.
+// @Tags Store
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param exception body dto.StoreExceptionRequest true "Excepción"
+// @Success 200 {object} domain.StoreException
+// @Failure 400 {object} map[string]string
+// @Router /admin/store/exceptions [put]
+func (h *StoreHandler) SaveException(c *gin.Context) {
+	var req dto.StoreExceptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	exception, err := h.svc.SaveException(c.Request.Context(), req)
+	if err != nil {
+		respondStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, exception)
+}
+
+// DeleteException godoc
+// @Summary Borrar una excepción de horario
+// @Tags Store
+// @Security BearerAuth
+// @Param date path string true "Fecha AAAA-MM-DD"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /admin/store/exceptions/{date} [delete]
+func (h *StoreHandler) DeleteException(c *gin.Context) {
+	if err := h.svc.DeleteException(c.Request.Context(), c.Param("date")); err != nil {
+		respondStoreError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Pause godoc
+// @Summary Pausar la toma de pedidos
+// @Description Deja de aceptar pedidos nuevos hasta que se reanude, sin importar el horario. Solo ADMIN.
+// @Tags Store
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param pause body dto.PauseStoreRequest true "Motivo"
+// @Success 200 {object} map[string]string
+// @Router /admin/store/pause [post]
+func (h *StoreHandler) Pause(c *gin.Context) {
+	var req dto.PauseStoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	adminID := c.MustGet("user_id").(string)
+
+	if err := h.svc.Pause(c.Request.Context(), req.Reason, adminID); err != nil {
+		respondStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Pedidos pausados"})
+}
+
+// Resume godoc
+// @Summary Reanudar la toma de pedidos
+// @Tags Store
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Router /admin/store/pause [delete]
+func (h *StoreHandler) Resume(c *gin.Context) {
+	if err := h.svc.Resume(c.Request.Context()); err != nil {
+		respondStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Pedidos reanudados"})
+}
+
+func respondStoreError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, utils.ErrStoreExceptionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrInvalidStoreHours), errors.Is(err, utils.ErrInvalidDate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar la solicitud"})
+	}
+}
@@ -1,9 +1,14 @@
 package handler
 
 import (
+	"errors"
+	"math"
 	"net/http"
-	_ "tracking/internal/domain"
-	_ "tracking/internal/dto"
+	"strconv"
+	"time"
+	"tracking/internal/auth"
+	"tracking/internal/domain"
+	"tracking/internal/dto"
 	"tracking/internal/service"
 	"tracking/internal/utils"
 
@@ -54,12 +59,15 @@ func (h *UserHandler) Register(c *gin.Context) {
 
 // Login godoc
 // @Summary Iniciar sesión
-// @Description Devuelve un token JWT si las credenciales son válidas
+// @Description Devuelve un token JWT si las credenciales son válidas. Cada login abre una sesión nueva sin cerrar las de otros dispositivos. Si la cuenta tiene doble factor (obligatorio para admins) devuelve mfa_required y un mfa_token para /auth/mfa/verify.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param credentials body dto.LoginRequest true "Credenciales de usuario"
 // @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 503 {object} map[string]string
 // @Router /auth/login [post]
 func (h *UserHandler) Login(c *gin.Context) {
 	var body struct {
@@ -72,28 +80,59 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	user, accessToken, refreshToken, err := h.svc.Login(c.Request.Context(), body.Email, body.Password)
-	if err != nil {
+	result, err := h.svc.Login(c.Request.Context(), body.Email, body.Password, c.Request.UserAgent(), c.ClientIP())
+	var rateErr *utils.RateLimitError
+	if errors.As(err, &rateErr) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(rateErr.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, utils.ErrLoginUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, utils.ErrInvalidCredentials) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Credenciales inválidas"})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al iniciar sesión"})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"access_token":  accessToken,
-		"refresh_token": refreshToken,
+	if result.MFARequired {
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required":        true,
+			"mfa_token":           result.MFAToken,
+			"enrollment_required": result.EnrollmentNeeded,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, loginResponse(result))
+}
+
+func loginResponse(result *domain.LoginResult) gin.H {
+	resp := gin.H{
+		"access_token":  result.AccessToken,
+		"refresh_token": result.RefreshToken,
 		"token_type":    "Bearer",
 		"user": gin.H{
-			"id":        user.ID,
-			"email":     user.Email,
-			"full_name": user.FullName,
-			"role":      user.Role,
+			"id":        result.User.ID,
+			"email":     result.User.Email,
+			"full_name": result.User.FullName,
+			"role":      result.User.Role,
 		},
-	})
+	}
+	if len(result.RecoveryCodes) > 0 {
+		resp["recovery_codes"] = result.RecoveryCodes
+	}
+	return resp
 }
 
 // RefreshToken godoc
 // @Summary Renovar tokens
-// @Description Devuelve un nuevo access token y refresh token válidos
+// @Description Devuelve un nuevo access token y refresh token válidos. El refresh token usado deja de servir; si se vuelve a presentar se cierra la sesión completa.
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -160,32 +199,148 @@ func (h *UserHandler) BootstrapAdmin(c *gin.Context) {
 
 // ListUsers godoc
 // @Summary Listar usuarios
-// @Description Devuelve todos los usuarios del sistema. Solo ADMIN. Soporta filtros por role y active.
+// @Description Devuelve una página de usuarios con el total. Soporta filtros por role y active y búsqueda por email o nombre.
 // @Tags Admin
 // @Security BearerAuth
 // @Produce json
-// @Param role query string false "Filtrar por role (driver, customer, admin)"
+// @Param role query string false "Filtrar por role (driver, customer, admin u otro rol)"
 // @Param active query boolean false "Filtrar por estado activo (true/false)"
-// @Success 200 {array} domain.User
+// @Param q query string false "Buscar en email y nombre"
+// @Param limit query int false "Tamaño de página (default 50, máx 200)"
+// @Param offset query int false "Desde qué resultado"
+// @Success 200 {object} domain.UserPage
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /admin/users [get]
 func (h *UserHandler) ListUsers(c *gin.Context) {
-	role := c.Query("role")
-	activeStr := c.Query("active")
+	var query dto.ListUsersQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
 
 	var active *bool
-	if activeStr != "" {
-		activeVal := activeStr == "true"
+	if query.Active != "" {
+		activeVal := query.Active == "true"
 		active = &activeVal
 	}
 
-	users, err := h.svc.ListUsers(c.Request.Context(), role, active)
+	page, err := h.svc.ListUsers(c.Request.Context(), domain.UserFilter{
+		Role:   query.Role,
+		Active: active,
+		Search: query.Q,
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error al listar usuarios"})
 		return
 	}
 
-	c.JSON(http.StatusOK, users)
+	c.JSON(http.StatusOK, page)
+}
+
+// GetUser godoc
+// @Summary Ver un usuario
+// @Description Datos del usuario y resumen de sus pedidos como cliente y como repartidor
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path string true "ID del usuario"
+// @Produce json
+// @Success 200 {object} domain.UserDetail
+// @Failure 404 {object} map[string]string
+// @Router /admin/users/{id} [get]
+func (h *UserHandler) GetUser(c *gin.Context) {
+	detail, err := h.svc.GetUserDetail(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondAdminUserError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, detail)
+}
+
+// ReactivateUser godoc
+// @Summary Reactivar usuario
+// @Description Vuelve a habilitar un usuario desactivado. Tiene que iniciar sesión de nuevo. Solo un admin reactiva una cuenta admin.
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path string true "ID del usuario"
+// @Produce json
+// @Success 200 {object} domain.User
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/users/{id}/reactivate [patch]
+func (h *UserHandler) ReactivateUser(c *gin.Context) {
+	user, err := h.svc.ReactivateUser(c.Request.Context(), c.GetString("role"), c.Param("id"))
+	if err != nil {
+		respondAdminUserError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// ChangeUserRole godoc
+// @Summary Cambiar el rol de un usuario
+// @Description Asigna un rol existente (ej. customer o driver). Solo un admin da o quita el rol admin y nadie cambia su propio rol. Cierra las sesiones del usuario.
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "ID del usuario"
+// @Param body body dto.ChangeRoleRequest true "Rol nuevo"
+// @Success 200 {object} domain.User
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/users/{id}/role [patch]
+func (h *UserHandler) ChangeUserRole(c *gin.Context) {
+	var req dto.ChangeRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	actorUserID := c.MustGet("user_id").(string)
+	actorRole := c.MustGet("role").(string)
+
+	user, err := h.svc.ChangeRole(c.Request.Context(), actorUserID, actorRole, c.Param("id"), req.Role)
+	if err != nil {
+		respondAdminUserError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateUser godoc
+// @Summary Editar nombre y email de un usuario
+// @Description Si el email cambia, la cuenta queda sin verificar y se manda el link al email nuevo
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "ID del usuario"
+// @Param body body dto.UpdateUserRequest true "Datos del usuario"
+// @Success 200 {object} domain.User
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/users/{id} [patch]
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	var req dto.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	actorRole := c.MustGet("role").(string)
+
+	user, err := h.svc.UpdateProfile(c.Request.Context(), actorRole, c.Param("id"), req.FullName, req.Email)
+	if err != nil {
+		respondAdminUserError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
 }
 
 // DeactivateUser godoc
@@ -197,6 +352,7 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 // @Produce json
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Router /admin/users/{id}/deactivate [patch]
 func (h *UserHandler) DeactivateUser(c *gin.Context) {
 	actorUserID := c.GetString("user_id")
@@ -211,7 +367,15 @@ func (h *UserHandler) DeactivateUser(c *gin.Context) {
 		return
 	}
 
-	err := h.svc.DeactivateUser(c.Request.Context(), actorUserID, userID)
+	err := h.svc.DeactivateUser(c.Request.Context(), actorUserID, c.GetString("role"), userID)
+	if errors.Is(err, utils.ErrAdminTarget) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, utils.ErrInternal) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar la solicitud"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -219,3 +383,543 @@ func (h *UserHandler) DeactivateUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "usuario desactivado correctamente"})
 }
+
+// ListSessions godoc
+// @Summary Ver mis sesiones
+// @Description Lista los dispositivos con sesión abierta. current indica la sesión de esta request.
+// @Tags Auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} domain.Session
+// @Router /me/sessions [get]
+func (h *UserHandler) ListSessions(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	sessions, err := h.svc.ListSessions(c.Request.Context(), userID, c.GetString("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error al listar sesiones"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession godoc
+// @Summary Cerrar una sesión
+// @Description Revoca el refresh token de esa sesión. El access token ya emitido sigue valiendo hasta vencer.
+// @Tags Auth
+// @Security BearerAuth
+// @Param id path string true "ID de la sesión"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /me/sessions/{id} [delete]
+func (h *UserHandler) RevokeSession(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	err := h.svc.RevokeSession(c.Request.Context(), userID, c.Param("id"))
+	if errors.Is(err, utils.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error al cerrar la sesión"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Logout godoc
+// @Summary Cerrar sesión
+// @Description Revoca el access token de la request y el refresh token de su sesión
+// @Tags Auth
+// @Security BearerAuth
+// @Success 204
+// @Router /auth/logout [post]
+func (h *UserHandler) Logout(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	expiresAt, _ := c.Get("token_expires_at")
+	accessExpiresAt, _ := expiresAt.(time.Time)
+
+	err := h.svc.Logout(c.Request.Context(), userID, c.GetString("session_id"), c.GetString("token_id"), accessExpiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error al cerrar la sesión"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// LogoutAll godoc
+// @Summary Cerrar todas las sesiones
+// @Description Revoca todos los access y refresh tokens del usuario en todos sus dispositivos
+// @Tags Auth
+// @Security BearerAuth
+// @Success 204
+// @Router /auth/logout-all [post]
+func (h *UserHandler) LogoutAll(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	if err := h.svc.LogoutAll(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error al cerrar las sesiones"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// UnlockUser godoc
+// @Summary Desbloquear cuenta
+// @Description Levanta el bloqueo por intentos de login fallidos. Solo ADMIN.
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path string true "ID del usuario"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/users/{id}/unlock [post]
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	err := h.svc.UnlockUser(c.Request.Context(), c.GetString("role"), c.Param("id"))
+	if errors.Is(err, utils.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, utils.ErrAdminTarget) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error al desbloquear la cuenta"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "cuenta desbloqueada"})
+}
+
+// ListLoginAttempts godoc
+// @Summary Ver intentos de login
+// @Description Auditoría de logins con fecha, IP, user agent y resultado. Solo ADMIN.
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param email query string false "Filtrar por email"
+// @Param ip query string false "Filtrar por IP"
+// @Param limit query int false "Máximo de resultados (default 100, máx 500)"
+// @Success 200 {array} domain.LoginAttempt
+// @Router /admin/login-attempts [get]
+func (h *UserHandler) ListLoginAttempts(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit debe estar entre 1 y 500"})
+		return
+	}
+
+	attempts, err := h.svc.ListLoginAttempts(c.Request.Context(), c.Query("email"), c.Query("ip"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error al listar intentos de login"})
+		return
+	}
+
+	c.JSON(http.StatusOK, attempts)
+}
+
+// ForgotPassword godoc
+// @Summary Olvidé mi contraseña
+// @Description Manda por email un link de un solo uso para elegir otra contraseña. Responde lo mismo aunque el email no esté registrado.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param data body dto.ForgotPasswordRequest true "Email de la cuenta"
+// @Success 200 {object} map[string]string
+// @Router /auth/forgot-password [post]
+func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	var req dto.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	if err := h.svc.ForgotPassword(c.Request.Context(), req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error al procesar la solicitud"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "si el email está registrado, te enviamos un link para restablecer la contraseña"})
+}
+
+// ResetPassword godoc
+// @Summary Restablecer contraseña
+// @Description Usa el token recibido por email. Cierra todas las sesiones abiertas.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param data body dto.ResetPasswordRequest true "Token y contraseña nueva"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /auth/reset-password [post]
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var req dto.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	err := h.svc.ResetPassword(c.Request.Context(), req.Token, req.NewPassword)
+	if errors.Is(err, utils.ErrInvalidResetToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error al restablecer la contraseña"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "contraseña actualizada, iniciá sesión de nuevo"})
+}
+
+// ChangePassword godoc
+// @Summary Cambiar mi contraseña
+// @Description Requiere la contraseña actual. Cierra todas las sesiones, incluida la actual.
+// @Tags Auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param data body dto.ChangePasswordRequest true "Contraseña actual y nueva"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /me/password [post]
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	var req dto.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(string)
+
+	err := h.svc.ChangePassword(c.Request.Context(), userID, req.CurrentPassword, req.NewPassword)
+	switch {
+	case errors.Is(err, utils.ErrWrongPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, utils.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error al cambiar la contraseña"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "contraseña actualizada, iniciá sesión de nuevo"})
+}
+
+// VerifyEmail godoc
+// @Summary Verificar email
+// @Description Confirma el email con el token del link enviado al registrarse. Sin email verificado no se pueden crear pedidos.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param data body dto.VerifyEmailRequest true "Token del link"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /auth/verify-email [post]
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	var req dto.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	err := h.svc.VerifyEmail(c.Request.Context(), req.Token)
+	if errors.Is(err, utils.ErrInvalidVerificationToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error al verificar el email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email verificado"})
+}
+
+// ResendVerification godoc
+// @Summary Reenviar email de verificación
+// @Description Manda un link nuevo e invalida el anterior. Tiene un límite de reenvíos por hora.
+// @Tags Auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /me/email-verification [post]
+func (h *UserHandler) ResendVerification(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	err := h.svc.ResendVerification(c.Request.Context(), userID)
+	var rateErr *utils.RateLimitError
+	switch {
+	case errors.As(err, &rateErr):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(rateErr.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, utils.ErrEmailAlreadyVerified):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, utils.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error al reenviar el email de verificación"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "te enviamos un nuevo link de verificación"})
+}
+
+// StartMFAEnrollment godoc
+// @Summary Configurar doble factor durante el login
+// @Description Para cuentas que deben tener doble factor y todavía no lo configuraron (enrollment_required en el login). Devuelve el secreto y la URI otpauth:// para mostrar como QR.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param data body dto.MFATokenRequest true "Token del desafío"
+// @Success 200 {object} domain.TOTPSetup
+// @Failure 401 {object} map[string]string
+// @Router /auth/mfa/enroll [post]
+func (h *UserHandler) StartMFAEnrollment(c *gin.Context) {
+	var req dto.MFATokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	setup, err := h.svc.StartMFAEnrollment(c.Request.Context(), req.MFAToken)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, setup)
+}
+
+// VerifyMFA godoc
+// @Summary Completar login con doble factor
+// @Description Recibe el mfa_token del login y un código TOTP o un código de recuperación. Si la cuenta estaba configurando el doble factor, lo activa y devuelve además los códigos de recuperación (se muestran una sola vez).
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param data body dto.VerifyMFARequest true "Token del desafío y código"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/mfa/verify [post]
+func (h *UserHandler) VerifyMFA(c *gin.Context) {
+	var req dto.VerifyMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code o recovery_code es requerido"})
+		return
+	}
+
+	result, err := h.svc.VerifyMFA(c.Request.Context(), req.MFAToken, req.Code, req.RecoveryCode, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, loginResponse(result))
+}
+
+// GetMFAStatus godoc
+// @Summary Estado de mi doble factor
+// @Tags Auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} domain.MFAStatus
+// @Router /me/mfa [get]
+func (h *UserHandler) GetMFAStatus(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	role := c.MustGet("role").(string)
+
+	status, err := h.svc.GetMFAStatus(c.Request.Context(), userID, role)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// StartTOTPEnrollment godoc
+// @Summary Configurar doble factor
+// @Description Genera un secreto TOTP nuevo. El doble factor queda activo recién al confirmar un código en /me/mfa/totp/confirm.
+// @Tags Auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} domain.TOTPSetup
+// @Failure 409 {object} map[string]string
+// @Router /me/mfa/totp [post]
+func (h *UserHandler) StartTOTPEnrollment(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	setup, err := h.svc.StartTOTPEnrollment(c.Request.Context(), userID)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, setup)
+}
+
+// ConfirmTOTP godoc
+// @Summary Activar doble factor
+// @Description Activa el doble factor con el primer código de la app. Devuelve los códigos de recuperación, que se muestran una sola vez.
+// @Tags Auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param data body dto.MFACodeRequest true "Código TOTP"
+// @Success 200 {object} map[string][]string
+// @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /me/mfa/totp/confirm [post]
+func (h *UserHandler) ConfirmTOTP(c *gin.Context) {
+	var req dto.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(string)
+
+	codes, err := h.svc.ConfirmTOTP(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// DisableTOTP godoc
+// @Summary Desactivar doble factor
+// @Description Requiere un código TOTP válido. No está permitido en roles donde el doble factor es obligatorio.
+// @Tags Auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param data body dto.MFACodeRequest true "Código TOTP"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /me/mfa/disable [post]
+func (h *UserHandler) DisableTOTP(c *gin.Context) {
+	var req dto.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(string)
+	role := c.MustGet("role").(string)
+
+	if err := h.svc.DisableTOTP(c.Request.Context(), userID, role, req.Code); err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "doble factor desactivado"})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerar códigos de recuperación
+// @Description Invalida los códigos anteriores. Requiere un código TOTP válido.
+// @Tags Auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param data body dto.MFACodeRequest true "Código TOTP"
+// @Success 200 {object} map[string][]string
+// @Failure 429 {object} map[string]string
+// @Router /me/mfa/recovery-codes [post]
+func (h *UserHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req dto.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(string)
+
+	codes, err := h.svc.RegenerateRecoveryCodes(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// ResetMFA godoc
+// @Summary Reiniciar el doble factor de un usuario
+// @Description Para usuarios que perdieron el teléfono y los códigos de recuperación. Borra su TOTP y cierra sus sesiones.
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "ID del usuario"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/users/{id}/mfa/reset [post]
+func (h *UserHandler) ResetMFA(c *gin.Context) {
+	if err := h.svc.ResetMFA(c.Request.Context(), c.GetString("role"), c.Param("id")); err != nil {
+		respondAdminUserError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "doble factor reiniciado"})
+}
+
+func respondMFAError(c *gin.Context, err error) {
+	var rateErr *utils.RateLimitError
+	switch {
+	case errors.As(err, &rateErr):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(rateErr.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrTooManyMFAAttempts):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrInvalidMFAToken), errors.Is(err, utils.ErrInvalidMFACode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrMFARequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrMFANotEnrolled), errors.Is(err, utils.ErrMFAAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error al procesar el doble factor"})
+	}
+}
+
+// JWKS godoc
+// @Summary Claves públicas de firma
+// @Description Claves públicas (JWK Set) para que otros servicios verifiquen los tokens por su kid. Vacío si los tokens se firman con HS256.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} auth.JWKSet
+// @Router /.well-known/jwks.json [get]
+func (h *UserHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, auth.PublicJWKS())
+}
+
+func respondAdminUserError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, utils.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrRoleNotFound), errors.Is(err, utils.ErrCannotChangeOwnRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrAdminRoleChange), errors.Is(err, utils.ErrAdminTarget):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrUserAlreadyActive), errors.Is(err, utils.ErrEmailTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar la solicitud"})
+	}
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"tracking/internal/dto"
+	"tracking/internal/service"
+	"tracking/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	svc service.WebhookServiceInterface
+}
+
+func NewWebhookHandler(svc service.WebhookServiceInterface) *WebhookHandler {
+	return &WebhookHandler{svc: svc}
+}
+
+// CreateWebhook godoc
+// @Summary Crear suscripción de webhook
+// @Description Registra una URL que recibirá los eventos elegidos firmados con HMAC-SHA256. El secret solo se devuelve en esta respuesta. Solo ADMIN.
+// @Tags Webhooks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param webhook body dto.CreateWebhookRequest true "URL, eventos y secret opcional"
+// @Success 201 {object} dto.WebhookResponse
+// @Failure 400 {object} map[string]string
+// @Router /admin/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req dto.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	adminID := c.MustGet("user_id").(string)
+
+	webhook, err := h.svc.CreateSubscription(c.Request.Context(), req, adminID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// ListWebhooks godoc
+// @Summary Listar suscripciones de webhook
+// @Tags Webhooks
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} dto.WebhookResponse
+// @Router /admin/webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.svc.ListSubscriptions(c.Request.Context())
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// UpdateWebhook godoc
+// @Summary Actualizar suscripción de webhook
+// @Description Cambia URL, eventos o la activa/desactiva. Solo ADMIN.
+// @Tags Webhooks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "ID del webhook"
+// @Param webhook body dto.UpdateWebhookRequest true "Campos a actualizar"
+// @Success 200 {object} dto.WebhookResponse
+// @Failure 404 {object} map[string]string
+// @Router /admin/webhooks/{id} [patch]
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	var req dto.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+
+	webhook, err := h.svc.UpdateSubscription(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook godoc
+// @Summary Eliminar suscripción de webhook
+// @Tags Webhooks
+// @Security BearerAuth
+// @Param id path string true "ID del webhook"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /admin/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	if err := h.svc.DeleteSubscription(c.Request.Context(), c.Param("id")); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeliveries godoc
+// @Summary Ver log de entregas de un webhook
+// @Description Lista los intentos de entrega con su estado (PENDING, DELIVERED, DEAD). Solo ADMIN.
+// @Tags Webhooks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "ID del webhook"
+// @Param status query string false "Filtrar por estado (PENDING, DELIVERED, DEAD)"
+// @Success 200 {array} domain.WebhookDelivery
+// @Failure 404 {object} map[string]string
+// @Router /admin/webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	deliveries, err := h.svc.ListDeliveries(c.Request.Context(), c.Param("id"), c.Query("status"))
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// RetryDelivery godoc
+// @Summary Reintentar una entrega en dead-letter
+// @Tags Webhooks
+// @Security BearerAuth
+// @Param deliveryId path string true "ID de la entrega"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/webhooks/deliveries/{deliveryId}/retry [post]
+func (h *WebhookHandler) RetryDelivery(c *gin.Context) {
+	if err := h.svc.RetryDelivery(c.Request.Context(), c.Param("deliveryId")); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Entrega reencolada"})
+}
+
+func respondWebhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, utils.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrInvalidWebhookEvent), errors.Is(err, utils.ErrInvalidWebhookURL),
+		errors.Is(err, utils.ErrWebhookURLNotPublic):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar la solicitud"})
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"tracking/internal/domain"
+	"tracking/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyAuthenticator valida las API keys de integraciones y lleva sus estadísticas de uso
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key, ip string) (domain.APIKey, error)
+	RecordAPIKeyUsage(ctx context.Context, keyID, ip string, status int)
+}
+
+var apiKeyAuthenticator APIKeyAuthenticator
+
+// UseAPIKeys habilita las API keys en AuthOrAPIKey. Se configura al registrar las rutas de API keys.
+func UseAPIKeys(authenticator APIKeyAuthenticator) {
+	apiKeyAuthenticator = authenticator
+}
+
+// AuthOrAPIKey acepta un access token como AuthMiddleware o una API key (X-API-Key o Bearer) que
+// tenga el scope. Con API key el request actúa como la cuenta del comercio dueña de la key.
+// Solo las rutas que lo usan explícitamente quedan abiertas a integraciones.
+func AuthOrAPIKey(scope string) gin.HandlerFunc {
+	jwtAuth := AuthMiddleware()
+
+	return func(c *gin.Context) {
+		key := apiKeyFromRequest(c)
+		if key == "" {
+			jwtAuth(c)
+			return
+		}
+
+		if apiKeyAuthenticator == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "API keys no habilitadas"})
+			c.Abort()
+			return
+		}
+
+		apiKey, err := apiKeyAuthenticator.AuthenticateAPIKey(c.Request.Context(), key, c.ClientIP())
+		switch {
+		case errors.Is(err, utils.ErrAPIKeyIPNotAllowed):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		case errors.Is(err, utils.ErrInvalidAPIKey):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		case err != nil:
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "no se pudo validar la API key"})
+			c.Abort()
+			return
+		}
+
+		defer func() {
+			apiKeyAuthenticator.RecordAPIKeyUsage(c.Request.Context(), apiKey.ID, c.ClientIP(), c.Writer.Status())
+		}()
+
+		if !apiKey.HasScope(scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": utils.ErrAPIKeyScopeMissing.Error(), "required_scope": scope})
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", apiKey.UserID)
+		c.Set("role", apiKey.UserRole)
+		c.Set("api_key_id", apiKey.ID)
+
+		c.Next()
+	}
+}
+
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := strings.TrimSpace(c.GetHeader("X-API-Key")); key != "" {
+		return key
+	}
+	parts := strings.Fields(c.GetHeader("Authorization"))
+	if len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") && strings.HasPrefix(parts[1], domain.APIKeyPrefix) {
+		return parts[1]
+	}
+	return ""
+}
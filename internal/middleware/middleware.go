@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"context"
+	"log/slog"
 	"net/http"
 	"strings"
 	"tracking/internal/auth"
@@ -8,6 +10,21 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// TokenRevocationChecker dice si un access token fue revocado (logout, logout-all, sesión cerrada
+// o usuario desactivado)
+type TokenRevocationChecker interface {
+	IsAccessTokenRevoked(ctx context.Context, userID, sessionID, jti string, version int64) (bool, error)
+}
+
+var revocationChecker TokenRevocationChecker
+
+// UseTokenRevocation activa el chequeo de revocación en AuthMiddleware. Se configura una vez al
+// registrar las rutas de usuarios, que son las que tienen acceso a Redis. Sin checker
+// AuthMiddleware rechaza todos los tokens.
+func UseTokenRevocation(checker TokenRevocationChecker) {
+	revocationChecker = checker
+}
+
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := strings.TrimSpace(c.GetHeader("Authorization"))
@@ -39,14 +56,39 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		if claims.Role == "" {
+		if claims.Role == "" || claims.SessionID == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "token inválido"})
 			c.Abort()
 			return
 		}
 
+		// Sin checker no se puede saber si el token fue revocado: se rechaza en lugar de aceptarlo
+		if revocationChecker == nil {
+			slog.Error("chequeo de revocación de tokens no configurado")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "no se pudo validar el token"})
+			c.Abort()
+			return
+		}
+		revoked, err := revocationChecker.IsAccessTokenRevoked(c.Request.Context(), claims.UserID, claims.SessionID, claims.ID, claims.Version)
+		if err != nil {
+			slog.Error("error al verificar revocación del token", "user_id", claims.UserID, "error", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "no se pudo validar el token"})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token revocado"})
+			c.Abort()
+			return
+		}
+
 		c.Set("user_id", claims.UserID)
 		c.Set("role", claims.Role)
+		c.Set("session_id", claims.SessionID)
+		c.Set("token_id", claims.ID)
+		if claims.ExpiresAt != nil {
+			c.Set("token_expires_at", claims.ExpiresAt.Time)
+		}
 
 		c.Next()
 	}
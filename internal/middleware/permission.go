@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"tracking/internal/policy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission deja pasar si el rol tiene alguno de los permisos. Va después de AuthMiddleware;
+// la propiedad del recurso (permisos :own) la chequea el handler con policy.CanAccess.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted, err := policy.Permissions(c)
+		if err != nil {
+			slog.Error("error al obtener permisos del rol", "role", c.GetString("role"), "error", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "no se pudieron validar los permisos"})
+			c.Abort()
+			return
+		}
+
+		for _, p := range permissions {
+			if granted[p] {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "no tienes permisos para realizar esta acción"})
+		c.Abort()
+	}
+}
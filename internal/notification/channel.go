@@ -0,0 +1,219 @@
+package notification
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+	ChannelPush  = "push"
+)
+
+// Message es lo que se envía por un canal. To depende del canal: email, teléfono o token de push.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	// Sensitive marca los mensajes con links de acceso (reseteo, verificación): su cuerpo no se
+	// muestra en los emails capturados
+	Sensitive bool
+}
+
+// Sender es un canal concreto de envío (SMTP, proveedor de SMS, push, etc.)
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogSender no envía nada: deja destinatario y asunto en el log. Es el default en desarrollo.
+// El cuerpo no se loguea porque puede llevar links con tokens (reseteo, verificación).
+type LogSender struct {
+	Channel string
+}
+
+func (s LogSender) Send(ctx context.Context, msg Message) error {
+	slog.Info("notificación (log)", "channel", s.Channel, "to", msg.To, "subject", msg.Subject, "body_length", len(msg.Body))
+	return nil
+}
+
+// CapturedMessage es un mensaje retenido por CaptureSender
+type CapturedMessage struct {
+	Message
+	SentAt time.Time
+}
+
+// CaptureSender guarda en memoria los mensajes en lugar de enviarlos, para inspeccionarlos
+// en desarrollo o en tests sin un servidor SMTP real.
+type CaptureSender struct {
+	mu       sync.Mutex
+	messages []CapturedMessage
+	limit    int
+}
+
+func NewCaptureSender(limit int) *CaptureSender {
+	return &CaptureSender{limit: limit}
+}
+
+func (s *CaptureSender) Send(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = append(s.messages, CapturedMessage{Message: msg, SentAt: time.Now()})
+	if s.limit > 0 && len(s.messages) > s.limit {
+		s.messages = s.messages[len(s.messages)-s.limit:]
+	}
+	return nil
+}
+
+// Messages devuelve una copia de los mensajes capturados, del más viejo al más nuevo
+func (s *CaptureSender) Messages() []CapturedMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]CapturedMessage, len(s.messages))
+	copy(out, s.messages)
+	return out
+}
+
+// SMTPSender envía emails por SMTP con autenticación PLAIN si hay usuario configurado
+type SMTPSender struct {
+	host string
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTPSender(host, port, username, password, from string) *SMTPSender {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPSender{host: host, addr: net.JoinHostPort(host, port), from: from, auth: auth}
+}
+
+// errInvalidHeader: un salto de línea en un encabezado permitiría agregar otros (Bcc, etc.)
+var errInvalidHeader = errors.New("encabezado de email con salto de línea")
+
+// Send respeta el deadline y la cancelación del contexto durante toda la conversación SMTP
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	for _, v := range []string{s.from, msg.To, msg.Subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return errInvalidHeader
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	// Los asuntos en español llevan acentos: van codificados como RFC 2047
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", msg.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	// Cerrar la conexión corta cualquier lectura o escritura pendiente si se cancela el contexto
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := s.send(conn, msg.To, []byte(b.String())); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	}
+	return nil
+}
+
+// send hace lo mismo que smtp.SendMail sobre una conexión ya abierta
+func (s *SMTPSender) send(conn net.Conn, to string, data []byte) error {
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return err
+		}
+	}
+	if s.auth != nil {
+		if err := c.Auth(s.auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(s.from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// Capture es la instancia compartida que usan los canales configurados con driver "capture"
+var Capture = NewCaptureSender(500)
+
+// SendersFromEnv arma un Sender por canal según NOTIFY_<CANAL>_DRIVER (log, capture o smtp para email).
+// Los canales sin proveedor real (SMS y push) usan log por ahora.
+func SendersFromEnv() map[string]Sender {
+	return map[string]Sender{
+		ChannelEmail: emailSenderFromEnv(),
+		ChannelSMS:   senderFromDriver(ChannelSMS, os.Getenv("NOTIFY_SMS_DRIVER")),
+		ChannelPush:  senderFromDriver(ChannelPush, os.Getenv("NOTIFY_PUSH_DRIVER")),
+	}
+}
+
+func emailSenderFromEnv() Sender {
+	if os.Getenv("NOTIFY_EMAIL_DRIVER") != "smtp" {
+		return senderFromDriver(ChannelEmail, os.Getenv("NOTIFY_EMAIL_DRIVER"))
+	}
+
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = "no-reply@tracking.local"
+	}
+	return NewSMTPSender(os.Getenv("SMTP_HOST"), port, os.Getenv("SMTP_USER"), os.Getenv("SMTP_PASSWORD"), from)
+}
+
+func senderFromDriver(channel, driver string) Sender {
+	if driver == "capture" {
+		return Capture
+	}
+	return LogSender{Channel: channel}
+}
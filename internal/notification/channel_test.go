@@ -0,0 +1,129 @@
+package notification
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTPServer atiende una conversación SMTP mínima y devuelve lo recibido en DATA
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("no se pudo escuchar: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 fake")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 fake")
+			case cmd == "DATA":
+				reply("354 dale")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				received <- data.String()
+				reply("250 ok")
+			case cmd == "QUIT":
+				reply("221 chau")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return ln.Addr().String(), received
+}
+
+func newTestSMTPSender(addr string) *SMTPSender {
+	host, port, _ := net.SplitHostPort(addr)
+	return NewSMTPSender(host, port, "", "", "no-reply@tracking.local")
+}
+
+func TestSMTPSenderEncodesSubject(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+	sender := newTestSMTPSender(addr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := sender.Send(ctx, Message{To: "cliente@example.com", Subject: "Tu pedido está en camino", Body: "Hola"})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+
+	data := <-received
+	if !strings.Contains(data, "Subject: =?UTF-8?q?Tu_pedido_est=C3=A1_en_camino?=\r\n") {
+		t.Errorf("asunto sin codificar:\n%s", data)
+	}
+	if !strings.Contains(data, "To: cliente@example.com\r\n") {
+		t.Errorf("falta el destinatario:\n%s", data)
+	}
+}
+
+func TestSMTPSenderRejectsHeaderInjection(t *testing.T) {
+	sender := NewSMTPSender("127.0.0.1", "1", "", "", "no-reply@tracking.local")
+
+	tests := []Message{
+		{To: "cliente@example.com\r\nBcc: otro@example.com", Subject: "Hola"},
+		{To: "cliente@example.com", Subject: "Hola\nBcc: otro@example.com"},
+	}
+	for _, msg := range tests {
+		if err := sender.Send(context.Background(), msg); !errors.Is(err, errInvalidHeader) {
+			t.Errorf("Send(%q, %q) = %v, se esperaba errInvalidHeader", msg.To, msg.Subject, err)
+		}
+	}
+}
+
+func TestSMTPSenderHonoursContext(t *testing.T) {
+	// Un servidor que acepta la conexión y nunca responde
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("no se pudo escuchar: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(5 * time.Second)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = newTestSMTPSender(ln.Addr().String()).Send(ctx, Message{To: "cliente@example.com", Subject: "Hola"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, se esperaba context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Send tardó %v pese al deadline", elapsed)
+	}
+}
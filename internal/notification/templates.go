@@ -0,0 +1,141 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+const (
+	LocaleES      = "es"
+	LocaleEN      = "en"
+	DefaultLocale = LocaleES
+)
+
+const (
+	TemplateOrderAccepted  = "order_accepted"
+	TemplateOrderPickedUp  = "order_picked_up"
+	TemplateDriverArriving = "driver_arriving"
+	TemplateOrderDelivered = "order_delivered"
+	TemplateOrderCancelled = "order_cancelled"
+
+	TemplatePasswordReset     = "password_reset"
+	TemplateEmailVerification = "email_verification"
+)
+
+// TemplateData son los datos disponibles dentro de las plantillas
+type TemplateData struct {
+	Name       string
+	OrderID    string
+	DriverName string
+	Address    string
+	Link       string
+}
+
+type messageTemplate struct {
+	subject string
+	body    string
+}
+
+var templates = map[string]map[string]messageTemplate{
+	LocaleES: {
+		TemplateOrderAccepted: {
+			subject: "Tu pedido fue aceptado",
+			body:    "Hola {{.Name}}, {{.DriverName}} aceptó tu pedido y va camino al local.",
+		},
+		TemplateOrderPickedUp: {
+			subject: "Tu pedido está en camino",
+			body:    "Hola {{.Name}}, {{.DriverName}} ya retiró tu pedido y va hacia {{.Address}}.",
+		},
+		TemplateDriverArriving: {
+			subject: "Tu repartidor está llegando",
+			body:    "Hola {{.Name}}, {{.DriverName}} está llegando a {{.Address}}.",
+		},
+		TemplateOrderDelivered: {
+			subject: "Pedido entregado",
+			body:    "Hola {{.Name}}, tu pedido fue entregado. ¡Gracias por elegirnos!",
+		},
+		TemplateOrderCancelled: {
+			subject: "Pedido cancelado",
+			body:    "Hola {{.Name}}, el pedido con destino a {{.Address}} fue cancelado.",
+		},
+		TemplatePasswordReset: {
+			subject: "Restablecer tu contraseña",
+			body:    "Hola {{.Name}}, para elegir una contraseña nueva entrá a {{.Link}}. El link vence pronto y sirve una sola vez. Si no lo pediste, ignorá este mensaje.",
+		},
+		TemplateEmailVerification: {
+			subject: "Confirmá tu email",
+			body:    "Hola {{.Name}}, confirmá tu email entrando a {{.Link}} para poder hacer pedidos. Si no creaste una cuenta, ignorá este mensaje.",
+		},
+	},
+	LocaleEN: {
+		TemplateOrderAccepted: {
+			subject: "Your order was accepted",
+			body:    "Hi {{.Name}}, {{.DriverName}} accepted your order and is heading to the store.",
+		},
+		TemplateOrderPickedUp: {
+			subject: "Your order is on its way",
+			body:    "Hi {{.Name}}, {{.DriverName}} picked up your order and is heading to {{.Address}}.",
+		},
+		TemplateDriverArriving: {
+			subject: "Your driver is arriving",
+			body:    "Hi {{.Name}}, {{.DriverName}} is arriving at {{.Address}}.",
+		},
+		TemplateOrderDelivered: {
+			subject: "Order delivered",
+			body:    "Hi {{.Name}}, your order has been delivered. Thanks for choosing us!",
+		},
+		TemplateOrderCancelled: {
+			subject: "Order cancelled",
+			body:    "Hi {{.Name}}, the order to {{.Address}} was cancelled.",
+		},
+		TemplatePasswordReset: {
+			subject: "Reset your password",
+			body:    "Hi {{.Name}}, to choose a new password go to {{.Link}}. The link expires soon and works only once. If you did not ask for it, ignore this message.",
+		},
+		TemplateEmailVerification: {
+			subject: "Confirm your email",
+			body:    "Hi {{.Name}}, confirm your email at {{.Link}} to start placing orders. If you did not create an account, ignore this message.",
+		},
+	},
+}
+
+// Render arma asunto y cuerpo de la plantilla en el idioma pedido (español si no existe)
+func Render(key, locale string, data TemplateData) (string, string, error) {
+	byKey, ok := templates[locale]
+	if !ok {
+		byKey = templates[DefaultLocale]
+	}
+
+	tpl, ok := byKey[key]
+	if !ok {
+		return "", "", fmt.Errorf("plantilla de notificación desconocida: %s", key)
+	}
+
+	body, err := execute(tpl.body, data)
+	if err != nil {
+		return "", "", err
+	}
+	subject, err := execute(tpl.subject, data)
+	if err != nil {
+		return "", "", err
+	}
+	return subject, body, nil
+}
+
+func IsSupportedLocale(locale string) bool {
+	_, ok := templates[locale]
+	return ok
+}
+
+func execute(text string, data TemplateData) (string, error) {
+	t, err := template.New("msg").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,115 @@
+// Package policy resuelve los permisos del rol del request y las reglas de propiedad de recursos.
+package policy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cuánto tarda en verse un cambio de permisos hecho desde otra instancia
+const cacheTTL = 30 * time.Second
+
+const permissionsContextKey = "permissions"
+
+// Store devuelve los permisos de un rol (Postgres)
+type Store interface {
+	RolePermissions(ctx context.Context, role string) ([]string, error)
+}
+
+type cacheEntry struct {
+	permissions map[string]bool
+	loadedAt    time.Time
+}
+
+var (
+	store Store
+	mu    sync.RWMutex
+	cache = map[string]cacheEntry{}
+)
+
+// Use configura de dónde salen los permisos. Se llama al registrar las rutas de roles.
+func Use(s Store) {
+	store = s
+	Invalidate()
+}
+
+// Invalidate descarta la caché; se llama después de editar un rol
+func Invalidate() {
+	mu.Lock()
+	cache = map[string]cacheEntry{}
+	mu.Unlock()
+}
+
+// RolePermissions devuelve el conjunto de permisos del rol
+func RolePermissions(ctx context.Context, role string) (map[string]bool, error) {
+	mu.RLock()
+	entry, ok := cache[role]
+	mu.RUnlock()
+	if ok && time.Since(entry.loadedAt) < cacheTTL {
+		return entry.permissions, nil
+	}
+
+	if store == nil {
+		return nil, errors.New("permisos no configurados")
+	}
+	list, err := store.RolePermissions(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[string]bool, len(list))
+	for _, p := range list {
+		permissions[p] = true
+	}
+	mu.Lock()
+	cache[role] = cacheEntry{permissions: permissions, loadedAt: time.Now()}
+	mu.Unlock()
+	return permissions, nil
+}
+
+// Permissions devuelve los permisos del usuario del request; se cargan una vez por request
+func Permissions(c *gin.Context) (map[string]bool, error) {
+	if cached, ok := c.Get(permissionsContextKey); ok {
+		return cached.(map[string]bool), nil
+	}
+
+	role := c.GetString("role")
+	if role == "" {
+		return map[string]bool{}, nil
+	}
+	permissions, err := RolePermissions(c.Request.Context(), role)
+	if err != nil {
+		return nil, err
+	}
+	c.Set(permissionsContextKey, permissions)
+	return permissions, nil
+}
+
+// Can dice si el usuario del request tiene el permiso
+func Can(c *gin.Context, permission string) bool {
+	permissions, err := Permissions(c)
+	return err == nil && permissions[permission]
+}
+
+// CanAccess aplica la regla de propiedad para una acción como "orders:read": alcanza con
+// action:any, o con action:own si el usuario es uno de los dueños del recurso.
+func CanAccess(c *gin.Context, action string, ownerIDs ...string) bool {
+	if Can(c, action+":any") {
+		return true
+	}
+	if !Can(c, action+":own") {
+		return false
+	}
+
+	userID := c.GetString("user_id")
+	for _, owner := range ownerIDs {
+		if owner != "" && owner == userID {
+			return true
+		}
+	}
+	return false
+}
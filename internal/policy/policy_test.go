@@ -0,0 +1,110 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeStore cuenta las consultas para verificar la caché
+type fakeStore struct {
+	roles map[string][]string
+	err   error
+	calls int
+}
+
+func (s *fakeStore) RolePermissions(ctx context.Context, role string) ([]string, error) {
+	s.calls++
+	return s.roles[role], s.err
+}
+
+func useStore(t *testing.T, s Store) {
+	t.Helper()
+	Use(s)
+	t.Cleanup(func() { Use(nil) })
+}
+
+func newContext(role, userID string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	if role != "" {
+		c.Set("role", role)
+	}
+	if userID != "" {
+		c.Set("user_id", userID)
+	}
+	return c
+}
+
+func TestCanAccess(t *testing.T) {
+	useStore(t, &fakeStore{roles: map[string][]string{
+		"admin":    {"orders:read:any"},
+		"driver":   {"orders:read:own"},
+		"customer": {"orders:read:own", "orders:cancel:own"},
+	}})
+
+	tests := []struct {
+		name   string
+		role   string
+		userID string
+		action string
+		owners []string
+		want   bool
+	}{
+		{"any sin ser dueño", "admin", "admin-1", "orders:read", []string{"customer-1", "driver-1"}, true},
+		{"any sin dueños", "admin", "admin-1", "orders:read", nil, true},
+		{"own siendo uno de los dueños", "driver", "driver-1", "orders:read", []string{"customer-1", "driver-1"}, true},
+		{"own sin ser dueño", "driver", "driver-2", "orders:read", []string{"customer-1", "driver-1"}, false},
+		{"own sin dueños", "driver", "driver-1", "orders:read", nil, false},
+		{"dueño vacío no coincide con usuario vacío", "driver", "", "orders:read", []string{""}, false},
+		{"sin el permiso de la acción", "driver", "driver-1", "orders:cancel", []string{"driver-1"}, false},
+		{"otra acción con own", "customer", "customer-1", "orders:cancel", []string{"customer-1"}, true},
+		{"rol sin permisos", "invitado", "x", "orders:read", []string{"x"}, false},
+		{"sin rol", "", "x", "orders:read", []string{"x"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newContext(tt.role, tt.userID)
+			if got := CanAccess(c, tt.action, tt.owners...); got != tt.want {
+				t.Errorf("CanAccess = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanAccessStoreError(t *testing.T) {
+	useStore(t, &fakeStore{err: errors.New("sin conexión")})
+
+	c := newContext("admin", "admin-1")
+	if CanAccess(c, "orders:read", "admin-1") {
+		t.Error("con error al leer los permisos no se debería dar acceso")
+	}
+}
+
+func TestPermissionsCache(t *testing.T) {
+	store := &fakeStore{roles: map[string][]string{"driver": {"orders:read:own"}}}
+	useStore(t, store)
+
+	// Dos requests del mismo rol consultan una sola vez; dentro del request ni se mira la caché
+	c := newContext("driver", "driver-1")
+	Can(c, "orders:read:own")
+	Can(c, "orders:read:any")
+	Can(newContext("driver", "driver-2"), "orders:read:own")
+	if store.calls != 1 {
+		t.Errorf("consultas = %d, se esperaba 1", store.calls)
+	}
+
+	store.roles["driver"] = []string{"orders:read:any"}
+	Invalidate()
+	if !Can(newContext("driver", "driver-1"), "orders:read:any") {
+		t.Error("después de Invalidate se deberían ver los permisos nuevos")
+	}
+	if store.calls != 2 {
+		t.Errorf("consultas = %d, se esperaban 2", store.calls)
+	}
+}
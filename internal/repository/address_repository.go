@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"tracking/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrDuplicateAddressLabel indica que el usuario ya tiene una dirección con esa etiqueta
+var ErrDuplicateAddressLabel = errors.New("etiqueta de dirección duplicada")
+
+type AddressRepositoryInterface interface {
+	Create(ctx context.Context, a *domain.Address) error
+	GetByID(ctx context.Context, id, userID string) (domain.Address, error)
+	ListByUser(ctx context.Context, userID string) ([]domain.Address, error)
+	Update(ctx context.Context, a *domain.Address) error
+	Delete(ctx context.Context, id, userID string) error
+}
+type AddressRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAddressRepository(db *pgxpool.Pool) *AddressRepository {
+	return &AddressRepository{db: db}
+}
+
+const addressColumns = `id, user_id, label, address, COALESCE(apartment, ''), COALESCE(instructions, ''),
+	lat, lng, pin_adjusted, created_at, updated_at`
+
+func (r *AddressRepository) Create(ctx context.Context, a *domain.Address) error {
+	query := `INSERT INTO customer_addresses (user_id, label, address, apartment, instructions, lat, lng, pin_adjusted)
+	          VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8)
+	          RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(ctx, query,
+		a.UserID, a.Label, a.Address, a.Apartment, a.Instructions, a.Lat, a.Lng, a.PinAdjusted,
+	).Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+	return mapAddressError(err)
+}
+
+// GetByID solo devuelve la dirección si pertenece al usuario
+func (r *AddressRepository) GetByID(ctx context.Context, id, userID string) (domain.Address, error) {
+	query := `SELECT ` + addressColumns + ` FROM customer_addresses WHERE id = $1 AND user_id = $2`
+
+	var a domain.Address
+	err := r.db.QueryRow(ctx, query, id, userID).Scan(
+		&a.ID, &a.UserID, &a.Label, &a.Address, &a.Apartment, &a.Instructions,
+		&a.Lat, &a.Lng, &a.PinAdjusted, &a.CreatedAt, &a.UpdatedAt,
+	)
+	return a, err
+}
+
+func (r *AddressRepository) ListByUser(ctx context.Context, userID string) ([]domain.Address, error) {
+	query := `SELECT ` + addressColumns + ` FROM customer_addresses WHERE user_id = $1 ORDER BY label ASC`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addresses []domain.Address
+	for rows.Next() {
+		var a domain.Address
+		if err := rows.Scan(
+			&a.ID, &a.UserID, &a.Label, &a.Address, &a.Apartment, &a.Instructions,
+			&a.Lat, &a.Lng, &a.PinAdjusted, &a.CreatedAt, &a.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, rows.Err()
+}
+
+func (r *AddressRepository) Update(ctx context.Context, a *domain.Address) error {
+	query := `UPDATE customer_addresses
+	          SET label = $3, address = $4, apartment = NULLIF($5, ''), instructions = NULLIF($6, ''),
+	              lat = $7, lng = $8, pin_adjusted = $9, updated_at = NOW()
+	          WHERE id = $1 AND user_id = $2
+	          RETURNING updated_at`
+
+	err := r.db.QueryRow(ctx, query,
+		a.ID, a.UserID, a.Label, a.Address, a.Apartment, a.Instructions, a.Lat, a.Lng, a.PinAdjusted,
+	).Scan(&a.UpdatedAt)
+	return mapAddressError(err)
+}
+
+func (r *AddressRepository) Delete(ctx context.Context, id, userID string) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM customer_addresses WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func mapAddressError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrDuplicateAddressLabel
+	}
+	return err
+}
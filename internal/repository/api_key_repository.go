@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"tracking/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type APIKeyRepositoryInterface interface {
+	Create(ctx context.Context, k *domain.APIKey, keyHash string) error
+	GetByHash(ctx context.Context, keyHash string) (domain.APIKey, error)
+	GetByID(ctx context.Context, id string) (domain.APIKey, error)
+	List(ctx context.Context, userID string) ([]domain.APIKey, error)
+	Revoke(ctx context.Context, id string) (domain.APIKey, error)
+	RevokeByUser(ctx context.Context, userID string) (int64, error)
+	RecordUsage(ctx context.Context, id, ip string, failed bool) error
+	ListUsage(ctx context.Context, id string, from, to time.Time) ([]domain.APIKeyUsage, error)
+}
+type APIKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(db *pgxpool.Pool) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+const apiKeyColumns = `
+	k.id, k.name, k.prefix, k.user_id, u.role, k.scopes, k.allowed_ips, k.expires_at, k.revoked_at,
+	k.last_used_at, COALESCE(k.last_used_ip, ''), COALESCE(k.created_by::TEXT, ''), k.created_at`
+
+func (r *APIKeyRepository) Create(ctx context.Context, k *domain.APIKey, keyHash string) error {
+	query := `
+		INSERT INTO api_keys (name, prefix, key_hash, user_id, scopes, allowed_ips, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`
+
+	return r.db.QueryRow(ctx, query,
+		k.Name, k.Prefix, keyHash, k.UserID, k.Scopes, k.AllowedIPs, k.ExpiresAt, k.CreatedBy,
+	).Scan(&k.ID, &k.CreatedAt)
+}
+
+func (r *APIKeyRepository) GetByHash(ctx context.Context, keyHash string) (domain.APIKey, error) {
+	query := `SELECT` + apiKeyColumns + `
+		FROM api_keys k JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = $1 AND u.is_active = true`
+
+	return scanAPIKey(r.db.QueryRow(ctx, query, keyHash))
+}
+
+func (r *APIKeyRepository) GetByID(ctx context.Context, id string) (domain.APIKey, error) {
+	query := `SELECT` + apiKeyColumns + `
+		FROM api_keys k JOIN users u ON u.id = k.user_id
+		WHERE k.id = $1`
+
+	return scanAPIKey(r.db.QueryRow(ctx, query, id))
+}
+
+func (r *APIKeyRepository) List(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	query := `SELECT` + apiKeyColumns + `
+		FROM api_keys k JOIN users u ON u.id = k.user_id
+		WHERE ($1 = '' OR k.user_id::TEXT = $1)
+		ORDER BY k.created_at DESC`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []domain.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke es idempotente: revocar dos veces conserva la fecha de la primera
+func (r *APIKeyRepository) Revoke(ctx context.Context, id string) (domain.APIKey, error) {
+	if _, err := r.db.Exec(ctx,
+		`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1`, id,
+	); err != nil {
+		return domain.APIKey{}, err
+	}
+	return r.GetByID(ctx, id)
+}
+
+// RevokeByUser revoca todas las keys vigentes de la cuenta y devuelve cuántas eran
+func (r *APIKeyRepository) RevokeByUser(ctx context.Context, userID string) (int64, error) {
+	result, err := r.db.Exec(ctx,
+		`UPDATE api_keys SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, userID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// RecordUsage suma el request al contador del día y actualiza el último uso de la key
+func (r *APIKeyRepository) RecordUsage(ctx context.Context, id, ip string, failed bool) error {
+	errorCount := 0
+	if failed {
+		errorCount = 1
+	}
+
+	batch := &pgx.Batch{}
+	batch.Queue(`
+		INSERT INTO api_key_usage (api_key_id, day, requests, errors) VALUES ($1, CURRENT_DATE, 1, $2)
+		ON CONFLICT (api_key_id, day) DO UPDATE SET
+			requests = api_key_usage.requests + 1,
+			errors = api_key_usage.errors + EXCLUDED.errors`, id, errorCount)
+	batch.Queue(`UPDATE api_keys SET last_used_at = NOW(), last_used_ip = $2 WHERE id = $1`, id, ip)
+	return r.db.SendBatch(ctx, batch).Close()
+}
+
+func (r *APIKeyRepository) ListUsage(ctx context.Context, id string, from, to time.Time) ([]domain.APIKeyUsage, error) {
+	query := `
+		SELECT day, requests, errors FROM api_key_usage
+		WHERE api_key_id = $1 AND day >= $2::date AND day < $3::date
+		ORDER BY day`
+
+	rows, err := r.db.Query(ctx, query, id, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usage []domain.APIKeyUsage
+	for rows.Next() {
+		var u domain.APIKeyUsage
+		if err := rows.Scan(&u.Day, &u.Requests, &u.Errors); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+func scanAPIKey(row pgx.Row) (domain.APIKey, error) {
+	var k domain.APIKey
+	err := row.Scan(
+		&k.ID, &k.Name, &k.Prefix, &k.UserID, &k.UserRole, &k.Scopes, &k.AllowedIPs, &k.ExpiresAt, &k.RevokedAt,
+		&k.LastUsedAt, &k.LastUsedIP, &k.CreatedBy, &k.CreatedAt,
+	)
+	return k, err
+}
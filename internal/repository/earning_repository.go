@@ -0,0 +1,270 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"tracking/internal/domain"
+	"tracking/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type EarningRepositoryInterface interface {
+	ListOrderEarnings(ctx context.Context, driverID string, from, to time.Time) ([]domain.OrderEarning, int, error)
+	CreatePayoutBatch(ctx context.Context, until time.Time, adminID string) (domain.PayoutBatch, error)
+	ListPayoutBatches(ctx context.Context) ([]domain.PayoutBatch, error)
+	GetPayoutBatch(ctx context.Context, id string) (domain.PayoutBatch, error)
+	ListUnrecordedDeliveries(ctx context.Context) ([]domain.UnrecordedDelivery, error)
+	InsertBackfilledEarning(ctx context.Context, e *domain.DriverEarning) (bool, error)
+}
+type EarningRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewEarningRepository(db *pgxpool.Pool) *EarningRepository {
+	return &EarningRepository{db: db}
+}
+
+// ListOrderEarnings agrupa por pedido los movimientos del repartidor en [from, to) y devuelve
+// además cuántas entregas caen en el período (una propina tardía puede caer en otro).
+func (r *EarningRepository) ListOrderEarnings(ctx context.Context, driverID string, from, to time.Time) ([]domain.OrderEarning, int, error) {
+	query := `
+		SELECT e.order_id, o.destination_address, MIN(e.created_at),
+		       SUM(e.distance_km), SUM(e.base_amount), SUM(e.distance_amount), SUM(e.tip_amount), SUM(e.amount),
+		       COALESCE(SUM(e.amount) FILTER (WHERE e.payout_id IS NOT NULL), 0),
+		       COUNT(*) FILTER (WHERE e.kind = 'DELIVERY')
+		FROM driver_earnings e
+		JOIN orders o ON o.id = e.order_id
+		WHERE e.driver_id = $1 AND e.created_at >= $2 AND e.created_at < $3
+		GROUP BY e.order_id, o.destination_address
+		ORDER BY MIN(e.created_at)`
+
+	rows, err := r.db.Query(ctx, query, driverID, from, to)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var earnings []domain.OrderEarning
+	var deliveries int
+	for rows.Next() {
+		var e domain.OrderEarning
+		var delivered int
+		if err := rows.Scan(
+			&e.OrderID, &e.DestinationAddress, &e.DeliveredAt,
+			&e.DistanceKm, &e.BaseAmount, &e.DistanceAmount, &e.TipAmount, &e.Total,
+			&e.PaidAmount, &delivered,
+		); err != nil {
+			return nil, 0, err
+		}
+		deliveries += delivered
+		earnings = append(earnings, e)
+	}
+	return earnings, deliveries, rows.Err()
+}
+
+// CreatePayoutBatch liquida todos los movimientos impagos anteriores a until: arma un pago por
+// repartidor y les asigna payout_id. Los movimientos se bloquean para que una propina que entra
+// en paralelo no quede a medio liquidar.
+func (r *EarningRepository) CreatePayoutBatch(ctx context.Context, until time.Time, adminID string) (domain.PayoutBatch, error) {
+	batch := domain.PayoutBatch{PeriodEnd: until, CreatedBy: adminID}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return batch, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, driver_id FROM driver_earnings
+		WHERE payout_id IS NULL AND created_at < $1
+		ORDER BY driver_id
+		FOR UPDATE`, until)
+	if err != nil {
+		return batch, err
+	}
+
+	entriesByDriver := map[string][]string{}
+	var drivers []string
+	for rows.Next() {
+		var id, driverID string
+		if err := rows.Scan(&id, &driverID); err != nil {
+			rows.Close()
+			return batch, err
+		}
+		if _, ok := entriesByDriver[driverID]; !ok {
+			drivers = append(drivers, driverID)
+		}
+		entriesByDriver[driverID] = append(entriesByDriver[driverID], id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return batch, err
+	}
+	if len(drivers) == 0 {
+		return batch, utils.ErrNoEarningsToPay
+	}
+
+	err = tx.QueryRow(ctx,
+		`INSERT INTO payout_batches (period_end, created_by) VALUES ($1, $2) RETURNING id, created_at`,
+		until, adminID,
+	).Scan(&batch.ID, &batch.CreatedAt)
+	if err != nil {
+		return batch, err
+	}
+
+	for _, driverID := range drivers {
+		ids := entriesByDriver[driverID]
+		payout := domain.DriverPayout{DriverID: driverID}
+
+		err := tx.QueryRow(ctx, `
+			INSERT INTO driver_payouts (batch_id, driver_id, amount, entry_count)
+			SELECT $1, $2, SUM(amount), COUNT(*) FROM driver_earnings WHERE id = ANY($3::uuid[])
+			RETURNING id, amount, entry_count`,
+			batch.ID, driverID, ids,
+		).Scan(&payout.ID, &payout.Amount, &payout.EntryCount)
+		if err != nil {
+			return batch, err
+		}
+
+		if _, err := tx.Exec(ctx, `UPDATE driver_earnings SET payout_id = $1 WHERE id = ANY($2::uuid[])`, payout.ID, ids); err != nil {
+			return batch, err
+		}
+	}
+
+	err = tx.QueryRow(ctx, `
+		UPDATE payout_batches SET total_amount = (SELECT SUM(amount) FROM driver_payouts WHERE batch_id = $1)
+		WHERE id = $1
+		RETURNING total_amount`, batch.ID,
+	).Scan(&batch.TotalAmount)
+	if err != nil {
+		return batch, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return batch, err
+	}
+	return r.GetPayoutBatch(ctx, batch.ID)
+}
+
+func (r *EarningRepository) ListPayoutBatches(ctx context.Context) ([]domain.PayoutBatch, error) {
+	query := `
+		SELECT id, period_end, total_amount, COALESCE(created_by::TEXT, ''), created_at
+		FROM payout_batches
+		ORDER BY created_at DESC
+		LIMIT 100`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batches []domain.PayoutBatch
+	for rows.Next() {
+		var b domain.PayoutBatch
+		if err := rows.Scan(&b.ID, &b.PeriodEnd, &b.TotalAmount, &b.CreatedBy, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		batches = append(batches, b)
+	}
+	return batches, rows.Err()
+}
+
+// GetPayoutBatch devuelve la liquidación con el detalle por repartidor
+func (r *EarningRepository) GetPayoutBatch(ctx context.Context, id string) (domain.PayoutBatch, error) {
+	var b domain.PayoutBatch
+	err := r.db.QueryRow(ctx, `
+		SELECT id, period_end, total_amount, COALESCE(created_by::TEXT, ''), created_at
+		FROM payout_batches WHERE id = $1`, id,
+	).Scan(&b.ID, &b.PeriodEnd, &b.TotalAmount, &b.CreatedBy, &b.CreatedAt)
+	if err != nil {
+		return b, err
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT p.id, p.driver_id, u.full_name, p.amount, p.entry_count
+		FROM driver_payouts p
+		JOIN users u ON u.id = p.driver_id
+		WHERE p.batch_id = $1
+		ORDER BY u.full_name`, id)
+	if err != nil {
+		return b, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p domain.DriverPayout
+		if err := rows.Scan(&p.ID, &p.DriverID, &p.DriverName, &p.Amount, &p.EntryCount); err != nil {
+			return b, err
+		}
+		b.Payouts = append(b.Payouts, p)
+	}
+	return b, rows.Err()
+}
+
+// ListUnrecordedDeliveries devuelve los pedidos entregados sin movimiento DELIVERY. La propina
+// que ya se registró como TIP (post-entrega) se descuenta para no pagarla dos veces.
+func (r *EarningRepository) ListUnrecordedDeliveries(ctx context.Context) ([]domain.UnrecordedDelivery, error) {
+	query := `
+		SELECT o.id, o.driver_id, o.origin_lat, o.origin_lng, o.dest_lat, o.dest_lng,
+		       o.tip_amount - COALESCE((
+		           SELECT SUM(t.tip_amount) FROM driver_earnings t WHERE t.order_id = o.id AND t.kind = 'TIP'
+		       ), 0),
+		       COALESCE(o.delivered_at, o.created_at)
+		FROM orders o
+		WHERE o.status = 'DELIVERED' AND o.driver_id IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM driver_earnings e WHERE e.order_id = o.id AND e.kind = 'DELIVERY')
+		ORDER BY o.delivered_at`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []domain.UnrecordedDelivery
+	for rows.Next() {
+		var d domain.UnrecordedDelivery
+		if err := rows.Scan(
+			&d.Order.ID, &d.Order.DriverID, &d.Order.OriginLat, &d.Order.OriginLng, &d.Order.DestLat, &d.Order.DestLng,
+			&d.Order.TipAmount, &d.DeliveredAt,
+		); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// InsertBackfilledEarning registra la entrega con su fecha original (CreatedAt). Devuelve false si
+// otra instancia ya la había registrado.
+func (r *EarningRepository) InsertBackfilledEarning(ctx context.Context, e *domain.DriverEarning) (bool, error) {
+	query := `
+		INSERT INTO driver_earnings (driver_id, order_id, kind, distance_km, base_amount, distance_amount, tip_amount, amount, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (order_id) WHERE kind = 'DELIVERY' DO NOTHING`
+
+	tag, err := r.db.Exec(ctx, query,
+		e.DriverID, e.OrderID, e.Kind, e.DistanceKm, e.BaseAmount, e.DistanceAmount, e.TipAmount, e.Amount, e.CreatedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// insertDriverEarning registra un movimiento dentro de la transacción del pedido (entrega o propina).
+// Una entrega repetida no duplica el movimiento.
+func insertDriverEarning(ctx context.Context, tx pgx.Tx, e *domain.DriverEarning) error {
+	query := `
+		INSERT INTO driver_earnings (driver_id, order_id, kind, distance_km, base_amount, distance_amount, tip_amount, amount)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (order_id) WHERE kind = 'DELIVERY' DO NOTHING`
+
+	_, err := tx.Exec(ctx, query,
+		e.DriverID, e.OrderID, e.Kind, e.DistanceKm, e.BaseAmount, e.DistanceAmount, e.TipAmount, e.Amount,
+	)
+	return err
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"tracking/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisEventPublisher publica los eventos de cada pedido en un canal Pub/Sub de Redis
+type RedisEventPublisher struct {
+	rdb *redis.Client
+}
+
+func NewRedisEventPublisher(rdb *redis.Client) *RedisEventPublisher {
+	return &RedisEventPublisher{rdb: rdb}
+}
+
+func (p *RedisEventPublisher) Publish(ctx context.Context, event domain.OrderEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.rdb.Publish(ctx, orderEventsChannel(event.OrderID), payload).Err()
+}
+
+func orderEventsChannel(orderID string) string {
+	return fmt.Sprintf("orders:events:%s", orderID)
+}
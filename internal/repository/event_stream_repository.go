@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"tracking/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// OrderEventsStream es el Redis Stream donde el relay del outbox publica los eventos de pedidos
+const OrderEventsStream = "orders:events"
+
+// Tope aproximado de mensajes que se conservan en el stream
+const orderEventsStreamMaxLen = 100000
+
+// Tiempo durante el cual se recuerda que un consumer group ya procesó un evento
+const processedEventTTL = 7 * 24 * time.Hour
+
+// StreamEvent es un evento leído del stream junto con el ID del mensaje para hacer XACK
+type StreamEvent struct {
+	MessageID string
+	Event     domain.OrderEvent
+}
+
+type EventStreamRepositoryInterface interface {
+	Append(ctx context.Context, event domain.OrderEvent) error
+	EnsureGroup(ctx context.Context, group string) error
+	ReadGroup(ctx context.Context, group, consumer string, count int64, block time.Duration) ([]StreamEvent, error)
+	ClaimStale(ctx context.Context, group, consumer string, minIdle time.Duration, count int64) ([]StreamEvent, error)
+	Ack(ctx context.Context, group, messageID string) error
+	WasProcessed(ctx context.Context, group, eventID string) (bool, error)
+	MarkProcessed(ctx context.Context, group, eventID string) error
+}
+type EventStreamRepository struct {
+	rdb *redis.Client
+}
+
+func NewEventStreamRepository(rdb *redis.Client) *EventStreamRepository {
+	return &EventStreamRepository{rdb: rdb}
+}
+
+func (r *EventStreamRepository) Append(ctx context.Context, event domain.OrderEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return r.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: OrderEventsStream,
+		MaxLen: orderEventsStreamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"event_id": event.ID,
+			"type":     event.Type,
+			"order_id": event.OrderID,
+			"payload":  payload,
+		},
+	}).Err()
+}
+
+// EnsureGroup crea el consumer group desde el principio del stream si todavía no existe
+func (r *EventStreamRepository) EnsureGroup(ctx context.Context, group string) error {
+	err := r.rdb.XGroupCreateMkStream(ctx, OrderEventsStream, group, "0").Err()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+	return err
+}
+
+func (r *EventStreamRepository) ReadGroup(ctx context.Context, group, consumer string, count int64, block time.Duration) ([]StreamEvent, error) {
+	streams, err := r.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    group,
+		Consumer: consumer,
+		Streams:  []string{OrderEventsStream, ">"},
+		Count:    count,
+		Block:    block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var events []StreamEvent
+	for _, stream := range streams {
+		events = append(events, decodeStreamMessages(stream.Messages)...)
+	}
+	return events, nil
+}
+
+// ClaimStale toma mensajes entregados a un consumidor que no hizo XACK en minIdle,
+// por ejemplo porque el handler falló o el proceso se cayó.
+func (r *EventStreamRepository) ClaimStale(ctx context.Context, group, consumer string, minIdle time.Duration, count int64) ([]StreamEvent, error) {
+	messages, _, err := r.rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   OrderEventsStream,
+		Group:    group,
+		Consumer: consumer,
+		MinIdle:  minIdle,
+		Start:    "0",
+		Count:    count,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeStreamMessages(messages), nil
+}
+
+func (r *EventStreamRepository) Ack(ctx context.Context, group, messageID string) error {
+	return r.rdb.XAck(ctx, OrderEventsStream, group, messageID).Err()
+}
+
+// WasProcessed indica si el group ya procesó el evento (el relay puede publicarlo más de una vez)
+func (r *EventStreamRepository) WasProcessed(ctx context.Context, group, eventID string) (bool, error) {
+	n, err := r.rdb.Exists(ctx, processedEventKey(group, eventID)).Result()
+	return n > 0, err
+}
+
+func (r *EventStreamRepository) MarkProcessed(ctx context.Context, group, eventID string) error {
+	return r.rdb.Set(ctx, processedEventKey(group, eventID), 1, processedEventTTL).Err()
+}
+
+func processedEventKey(group, eventID string) string {
+	return fmt.Sprintf("orders:events:processed:%s:%s", group, eventID)
+}
+
+func decodeStreamMessages(messages []redis.XMessage) []StreamEvent {
+	events := make([]StreamEvent, 0, len(messages))
+	for _, msg := range messages {
+		event := StreamEvent{MessageID: msg.ID}
+		if payload, ok := msg.Values["payload"].(string); ok {
+			// Un payload ilegible se devuelve vacío para que el consumidor lo descarte con XACK
+			json.Unmarshal([]byte(payload), &event.Event)
+		}
+		events = append(events, event)
+	}
+	return events
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type GeoCacheRepositoryInterface interface {
+	Get(ctx context.Context, key string, out interface{}) (bool, error)
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+}
+
+// GeoCacheRepository guarda en Redis las respuestas de Nominatim para no repetir consultas
+type GeoCacheRepository struct {
+	rdb *redis.Client
+}
+
+func NewGeoCacheRepository(rdb *redis.Client) *GeoCacheRepository {
+	return &GeoCacheRepository{rdb: rdb}
+}
+
+func (r *GeoCacheRepository) Get(ctx context.Context, key string, out interface{}) (bool, error) {
+	data, err := r.rdb.Get(ctx, geoCacheKey(key)).Bytes()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(data, out)
+}
+
+func (r *GeoCacheRepository) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return r.rdb.Set(ctx, geoCacheKey(key), data, ttl).Err()
+}
+
+func geoCacheKey(key string) string {
+	return "geo:cache:" + key
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"tracking/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LocationAnomalyRepositoryInterface interface {
+	Create(ctx context.Context, a *domain.LocationAnomaly) error
+	List(ctx context.Context, driverID, orderID string) ([]domain.LocationAnomaly, error)
+}
+type LocationAnomalyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLocationAnomalyRepository(db *pgxpool.Pool) *LocationAnomalyRepository {
+	return &LocationAnomalyRepository{db: db}
+}
+
+func (r *LocationAnomalyRepository) Create(ctx context.Context, a *domain.LocationAnomaly) error {
+	query := `
+		INSERT INTO driver_location_anomalies (
+			driver_id, order_id, type, lat, lng, prev_lat, prev_lng, speed_kmh, rejected, recorded_at
+		)
+		VALUES ($1, NULLIF($2, '')::uuid, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at`
+
+	return r.db.QueryRow(ctx, query,
+		a.DriverID, a.OrderID, a.Type, a.Lat, a.Lng,
+		a.PrevLat, a.PrevLng, a.SpeedKmh, a.Rejected, a.RecordedAt,
+	).Scan(&a.ID, &a.CreatedAt)
+}
+
+func (r *LocationAnomalyRepository) List(ctx context.Context, driverID, orderID string) ([]domain.LocationAnomaly, error) {
+	query := `
+		SELECT id, driver_id, COALESCE(order_id::TEXT, ''), type, lat, lng,
+		       prev_lat, prev_lng, speed_kmh, rejected, recorded_at, created_at
+		FROM driver_location_anomalies`
+
+	var conditions []string
+	var args []interface{}
+	if driverID != "" {
+		args = append(args, driverID)
+		conditions = append(conditions, fmt.Sprintf("driver_id = $%d", len(args)))
+	}
+	if orderID != "" {
+		args = append(args, orderID)
+		conditions = append(conditions, fmt.Sprintf("order_id = $%d", len(args)))
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC LIMIT 500"
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var anomalies []domain.LocationAnomaly
+	for rows.Next() {
+		var a domain.LocationAnomaly
+		if err := rows.Scan(
+			&a.ID, &a.DriverID, &a.OrderID, &a.Type, &a.Lat, &a.Lng,
+			&a.PrevLat, &a.PrevLng, &a.SpeedKmh, &a.Rejected, &a.RecordedAt, &a.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		anomalies = append(anomalies, a)
+	}
+	return anomalies, rows.Err()
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"tracking/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LocationHistoryRepositoryInterface interface {
+	InsertFixes(ctx context.Context, driverID, orderID string, fixes []domain.LocationFix) (int, error)
+}
+type LocationHistoryRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLocationHistoryRepository(db *pgxpool.Pool) *LocationHistoryRepository {
+	return &LocationHistoryRepository{db: db}
+}
+
+// InsertFixes guarda los fixes en orden e ignora los que ya estaban (mismo driver y recorded_at).
+// Devuelve cuántos se insertaron realmente.
+func (r *LocationHistoryRepository) InsertFixes(ctx context.Context, driverID, orderID string, fixes []domain.LocationFix) (int, error) {
+	query := `
+		INSERT INTO driver_location_history (driver_id, order_id, lat, lng, recorded_at)
+		VALUES ($1, NULLIF($2, '')::uuid, $3, $4, $5)
+		ON CONFLICT (driver_id, recorded_at) DO NOTHING`
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, fix := range fixes {
+		batch.Queue(query, driverID, orderID, fix.Lat, fix.Lng, fix.RecordedAt)
+	}
+
+	results := tx.SendBatch(ctx, batch)
+	inserted := 0
+	for range fixes {
+		tag, err := results.Exec()
+		if err != nil {
+			results.Close()
+			return 0, err
+		}
+		inserted += int(tag.RowsAffected())
+	}
+	if err := results.Close(); err != nil {
+		return 0, err
+	}
+
+	return inserted, tx.Commit(ctx)
+}
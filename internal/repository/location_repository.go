@@ -2,14 +2,23 @@ package repository
 
 import (
 	"context"
-	"github.com/redis/go-redis/v9"
 	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	"tracking/internal/domain"
+
+	"github.com/redis/go-redis/v9"
 )
 type LocationRepositoryInterface interface {
-	SaveDriverLocation(ctx context.Context, driverID string, lat, lng float64) error
-	GetDriverLocation(ctx context.Context, driverID string) (*redis.GeoLocation, error)
+	SaveDriverLocation(ctx context.Context, driverID string, lat, lng float64, recordedAt time.Time) error
+	GetDriverLocation(ctx context.Context, driverID string) (*domain.DriverLocation, error)
 	DeleteDriverLocation(ctx context.Context, driverID string) error
 	UpdateDriverLocation(ctx context.Context, driverID string, lat, lng float64) error
+	EvictStaleLocations(ctx context.Context, olderThan time.Time) (int, error)
+	SeedMissingLastSeen(ctx context.Context) (int, error)
+	GetLastFix(ctx context.Context, driverID string) (*domain.LocationFix, error)
+	SaveLastFix(ctx context.Context, driverID string, fix domain.LocationFix) error
 }
 type LocationRepository struct {
 	redis *redis.Client
@@ -21,36 +30,131 @@ func NewLocationRepository(r *redis.Client) *LocationRepository {
 
 const DriversKey = "drivers_locations"
 
-func (r *LocationRepository) SaveDriverLocation(ctx context.Context, driverID string, lat, lng float64) error {
-	return r.redis.GeoAdd(ctx, DriversKey, &redis.GeoLocation{
+// DriversLastSeenKey guarda, con el mismo member que el GEO set, el timestamp (ms) de cada posición
+const DriversLastSeenKey = "drivers_locations:last_seen"
+
+// El último fix aceptado se guarda aparte del GEO set para conservar su timestamp
+const lastFixTTL = 24 * time.Hour
+
+// evictStaleScript borra de ambos sets, en forma atómica, los drivers sin posición reciente
+var evictStaleScript = redis.NewScript(`
+local stale = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])
+for _, member in ipairs(stale) do
+	redis.call('ZREM', KEYS[1], member)
+	redis.call('ZREM', KEYS[2], member)
+end
+return #stale
+`)
+
+// seedLastSeenScript da score 0 a los members del GEO set sin last_seen (escritos antes de que
+// existiera ese set), así el próximo barrido los trata como vencidos
+var seedLastSeenScript = redis.NewScript(`
+local members = redis.call('ZRANGE', KEYS[1], 0, -1)
+local seeded = 0
+for _, member in ipairs(members) do
+	seeded = seeded + redis.call('ZADD', KEYS[2], 'NX', 0, member)
+end
+return seeded
+`)
+
+func (r *LocationRepository) SaveDriverLocation(ctx context.Context, driverID string, lat, lng float64, recordedAt time.Time) error {
+	pipe := r.redis.TxPipeline()
+	pipe.GeoAdd(ctx, DriversKey, &redis.GeoLocation{
 		Name:      driverID,
 		Latitude:  lat,
 		Longitude: lng,
-	}).Err()
+	})
+	pipe.ZAdd(ctx, DriversLastSeenKey, redis.Z{
+		Score:  float64(recordedAt.UnixMilli()),
+		Member: driverID,
+	})
+	_, err := pipe.Exec(ctx)
+	return err
 }
 
 func (r *LocationRepository) UpdateDriverLocation(ctx context.Context, driverID string, lat, lng float64) error {
-	
-	return r.redis.GeoAdd(ctx, DriversKey, &redis.GeoLocation{
-		Name:      driverID,
-		Latitude:  lat,
-		Longitude: lng,
-	}).Err()
+
+	return r.SaveDriverLocation(ctx, driverID, lat, lng, time.Now())
 }
-func (r *LocationRepository) GetDriverLocation(ctx context.Context, driverID string) (*redis.GeoLocation, error) {
-	pos, err := r.redis.GeoPos(ctx, "drivers_locations", driverID).Result()
+func (r *LocationRepository) GetDriverLocation(ctx context.Context, driverID string) (*domain.DriverLocation, error) {
+	pos, err := r.redis.GeoPos(ctx, DriversKey, driverID).Result()
 	if err != nil || len(pos) == 0 || pos[0] == nil {
 		return nil, errors.New("ubicación no encontrada para este repartidor")
 	}
 
-	return &redis.GeoLocation{
-		Name:      driverID,
-		Longitude: pos[0].Longitude,
-		Latitude:  pos[0].Latitude,
-	}, nil
+	location := &domain.DriverLocation{
+		DriverID: driverID,
+		Lat:      pos[0].Latitude,
+		Lng:      pos[0].Longitude,
+	}
+
+	lastSeen, err := r.redis.ZScore(ctx, DriversLastSeenKey, driverID).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	if err == nil {
+		location.RecordedAt = time.UnixMilli(int64(lastSeen))
+	}
+
+	return location, nil
 }
 
 func (r *LocationRepository) DeleteDriverLocation(ctx context.Context, driverID string) error {
-	
-	return r.redis.ZRem(ctx, DriversKey, driverID).Err()
-}
\ No newline at end of file
+	pipe := r.redis.TxPipeline()
+	pipe.ZRem(ctx, DriversKey, driverID)
+	pipe.ZRem(ctx, DriversLastSeenKey, driverID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// EvictStaleLocations elimina las posiciones cuyo último fix es anterior a olderThan
+func (r *LocationRepository) EvictStaleLocations(ctx context.Context, olderThan time.Time) (int, error) {
+	cutoff := strconv.FormatInt(olderThan.UnixMilli(), 10)
+	return evictStaleScript.Run(ctx, r.redis, []string{DriversKey, DriversLastSeenKey}, cutoff).Int()
+}
+
+// SeedMissingLastSeen devuelve cuántos members no tenían last_seen
+func (r *LocationRepository) SeedMissingLastSeen(ctx context.Context) (int, error) {
+	return seedLastSeenScript.Run(ctx, r.redis, []string{DriversKey, DriversLastSeenKey}).Int()
+}
+
+// GetLastFix devuelve el último fix aceptado del driver, o nil si no hay ninguno
+func (r *LocationRepository) GetLastFix(ctx context.Context, driverID string) (*domain.LocationFix, error) {
+	values, err := r.redis.HGetAll(ctx, lastFixKey(driverID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	lat, errLat := strconv.ParseFloat(values["lat"], 64)
+	lng, errLng := strconv.ParseFloat(values["lng"], 64)
+	recordedAt, errTs := strconv.ParseInt(values["recorded_at"], 10, 64)
+	if errLat != nil || errLng != nil || errTs != nil {
+		return nil, errors.New("último fix corrupto en redis")
+	}
+
+	return &domain.LocationFix{
+		Lat:        lat,
+		Lng:        lng,
+		RecordedAt: time.UnixMilli(recordedAt),
+	}, nil
+}
+
+func (r *LocationRepository) SaveLastFix(ctx context.Context, driverID string, fix domain.LocationFix) error {
+	key := lastFixKey(driverID)
+	pipe := r.redis.TxPipeline()
+	pipe.HSet(ctx, key,
+		"lat", strconv.FormatFloat(fix.Lat, 'f', -1, 64),
+		"lng", strconv.FormatFloat(fix.Lng, 'f', -1, 64),
+		"recorded_at", fix.RecordedAt.UnixMilli(),
+	)
+	pipe.Expire(ctx, key, lastFixTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func lastFixKey(driverID string) string {
+	return fmt.Sprintf("drivers:last_fix:%s", driverID)
+}
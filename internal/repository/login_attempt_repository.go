@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"tracking/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LoginAttemptRepositoryInterface interface {
+	Create(ctx context.Context, a domain.LoginAttempt) error
+	List(ctx context.Context, email, ip string, limit int) ([]domain.LoginAttempt, error)
+}
+type LoginAttemptRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLoginAttemptRepository(db *pgxpool.Pool) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+func (r *LoginAttemptRepository) Create(ctx context.Context, a domain.LoginAttempt) error {
+	query := `
+		INSERT INTO login_attempts (email, user_id, ip, user_agent, outcome)
+		VALUES ($1, NULLIF($2, '')::uuid, $3, $4, $5)`
+
+	_, err := r.db.Exec(ctx, query, a.Email, a.UserID, a.IP, a.UserAgent, a.Outcome)
+	return err
+}
+
+func (r *LoginAttemptRepository) List(ctx context.Context, email, ip string, limit int) ([]domain.LoginAttempt, error) {
+	query := `
+		SELECT id, email, COALESCE(user_id::TEXT, ''), COALESCE(ip, ''), COALESCE(user_agent, ''), outcome, created_at
+		FROM login_attempts
+		WHERE ($1 = '' OR email = $1) AND ($2 = '' OR ip = $2)
+		ORDER BY created_at DESC
+		LIMIT $3`
+
+	rows, err := r.db.Query(ctx, query, email, ip, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []domain.LoginAttempt
+	for rows.Next() {
+		var a domain.LoginAttempt
+		if err := rows.Scan(&a.ID, &a.Email, &a.UserID, &a.IP, &a.UserAgent, &a.Outcome, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"tracking/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type NotificationRepositoryInterface interface {
+	GetPreferences(ctx context.Context, userID string) (domain.NotificationPreferences, error)
+	SavePreferences(ctx context.Context, p *domain.NotificationPreferences) error
+	CreateOnce(ctx context.Context, n *domain.Notification) (bool, error)
+	MarkSent(ctx context.Context, id string) error
+	MarkFailed(ctx context.Context, id, reason string) error
+	ClaimStalePending(ctx context.Context, olderThan time.Time, limit int) ([]domain.Notification, error)
+	ExpirePending(ctx context.Context, createdBefore time.Time) (int64, error)
+	List(ctx context.Context, userID, orderID, status string) ([]domain.Notification, error)
+}
+type NotificationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewNotificationRepository(db *pgxpool.Pool) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// GetPreferences devuelve las preferencias guardadas o los valores por defecto
+// (solo email, en español) si el usuario nunca las configuró.
+func (r *NotificationRepository) GetPreferences(ctx context.Context, userID string) (domain.NotificationPreferences, error) {
+	query := `SELECT user_id, locale, email_enabled, sms_enabled, push_enabled,
+	                 COALESCE(phone, ''), COALESCE(push_token, ''), updated_at
+	          FROM notification_preferences WHERE user_id = $1`
+
+	var p domain.NotificationPreferences
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&p.UserID, &p.Locale, &p.EmailEnabled, &p.SMSEnabled, &p.PushEnabled, &p.Phone, &p.PushToken, &p.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.NotificationPreferences{UserID: userID, Locale: "es", EmailEnabled: true}, nil
+	}
+	return p, err
+}
+
+func (r *NotificationRepository) SavePreferences(ctx context.Context, p *domain.NotificationPreferences) error {
+	query := `
+		INSERT INTO notification_preferences (user_id, locale, email_enabled, sms_enabled, push_enabled, phone, push_token, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), NOW())
+		ON CONFLICT (user_id) DO UPDATE SET
+			locale = EXCLUDED.locale,
+			email_enabled = EXCLUDED.email_enabled,
+			sms_enabled = EXCLUDED.sms_enabled,
+			push_enabled = EXCLUDED.push_enabled,
+			phone = EXCLUDED.phone,
+			push_token = EXCLUDED.push_token,
+			updated_at = NOW()
+		RETURNING updated_at`
+
+	return r.db.QueryRow(ctx, query,
+		p.UserID, p.Locale, p.EmailEnabled, p.SMSEnabled, p.PushEnabled, p.Phone, p.PushToken,
+	).Scan(&p.UpdatedAt)
+}
+
+// CreateOnce registra el envío en PENDING. Devuelve false si ese evento ya se notificó
+// al usuario por ese canal (el consumidor del stream puede recibirlo más de una vez).
+func (r *NotificationRepository) CreateOnce(ctx context.Context, n *domain.Notification) (bool, error) {
+	query := `
+		INSERT INTO notifications (user_id, order_id, event_id, channel, template, recipient, subject, body, status)
+		VALUES ($1, NULLIF($2, '')::uuid, NULLIF($3, '')::uuid, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (event_id, user_id, channel) DO NOTHING
+		RETURNING id, created_at`
+
+	if n.Status == "" {
+		n.Status = domain.NotificationPending
+	}
+
+	err := r.db.QueryRow(ctx, query,
+		n.UserID, n.OrderID, n.EventID, n.Channel, n.Template, n.Recipient, n.Subject, n.Body, n.Status,
+	).Scan(&n.ID, &n.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *NotificationRepository) MarkSent(ctx context.Context, id string) error {
+	query := `UPDATE notifications SET status = 'SENT', sent_at = NOW(), error = NULL WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, id)
+	return err
+}
+
+func (r *NotificationRepository) MarkFailed(ctx context.Context, id, reason string) error {
+	query := `UPDATE notifications SET status = 'FAILED', error = $2 WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, id, reason)
+	return err
+}
+
+// ClaimStalePending toma los envíos que siguen en PENDING desde antes de olderThan (contando el
+// último reintento) y marca el intento, así otra instancia no los reenvía al mismo tiempo
+func (r *NotificationRepository) ClaimStalePending(ctx context.Context, olderThan time.Time, limit int) ([]domain.Notification, error) {
+	query := `
+		UPDATE notifications SET last_attempt_at = NOW()
+		WHERE id IN (
+			SELECT id FROM notifications
+			WHERE status = 'PENDING' AND COALESCE(last_attempt_at, created_at) < $1
+			ORDER BY created_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, COALESCE(order_id::TEXT, ''), COALESCE(event_id::TEXT, ''), channel, template,
+		          recipient, subject, body, status, created_at`
+
+	rows, err := r.db.Query(ctx, query, olderThan, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []domain.Notification
+	for rows.Next() {
+		var n domain.Notification
+		if err := rows.Scan(
+			&n.ID, &n.UserID, &n.OrderID, &n.EventID, &n.Channel, &n.Template,
+			&n.Recipient, &n.Subject, &n.Body, &n.Status, &n.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// ExpirePending pasa a FAILED los envíos pendientes creados antes de createdBefore: el aviso ya no sirve
+func (r *NotificationRepository) ExpirePending(ctx context.Context, createdBefore time.Time) (int64, error) {
+	query := `UPDATE notifications SET status = 'FAILED', error = 'vencida sin enviar'
+	          WHERE status = 'PENDING' AND created_at < $1`
+	tag, err := r.db.Exec(ctx, query, createdBefore)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *NotificationRepository) List(ctx context.Context, userID, orderID, status string) ([]domain.Notification, error) {
+	query := `
+		SELECT id, user_id, COALESCE(order_id::TEXT, ''), COALESCE(event_id::TEXT, ''), channel, template,
+		       recipient, subject, body, status, error, created_at, sent_at
+		FROM notifications
+		WHERE ($1 = '' OR user_id::TEXT = $1)
+		  AND ($2 = '' OR order_id::TEXT = $2)
+		  AND ($3 = '' OR status = $3)
+		ORDER BY created_at DESC
+		LIMIT 200`
+
+	rows, err := r.db.Query(ctx, query, userID, orderID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []domain.Notification
+	for rows.Next() {
+		var n domain.Notification
+		if err := rows.Scan(
+			&n.ID, &n.UserID, &n.OrderID, &n.EventID, &n.Channel, &n.Template,
+			&n.Recipient, &n.Subject, &n.Body, &n.Status, &n.Error, &n.CreatedAt, &n.SentAt,
+		); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"tracking/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OrderEventRepositoryInterface interface {
+	CreateOnce(ctx context.Context, e *domain.OrderEvent) (bool, error)
+	ListByOrder(ctx context.Context, orderID string) ([]domain.OrderEvent, error)
+}
+type OrderEventRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOrderEventRepository(db *pgxpool.Pool) *OrderEventRepository {
+	return &OrderEventRepository{db: db}
+}
+
+// CreateOnce inserta el evento salvo que el pedido ya tenga uno con la misma clave de deduplicación
+// (domain.OrderEvent.DedupeKey). Devuelve false si el evento ya estaba registrado.
+func (r *OrderEventRepository) CreateOnce(ctx context.Context, e *domain.OrderEvent) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	created, err := insertOrderEvent(ctx, tx, e)
+	if err != nil || !created {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// insertOrderEvent agrega el evento al timeline y lo encola en el outbox dentro de tx.
+// Si el pedido ya tenía un evento con la misma clave no inserta nada y devuelve false; los eventos
+// sin clave (ej. TIP_ADDED) se insertan siempre.
+func insertOrderEvent(ctx context.Context, tx pgx.Tx, e *domain.OrderEvent) (bool, error) {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		INSERT INTO order_events (order_id, type, actor_id, data, idempotency_key)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, $4, NULLIF($5, ''))
+		ON CONFLICT (order_id, idempotency_key) DO NOTHING
+		RETURNING id, created_at`
+
+	err = tx.QueryRow(ctx, query, e.OrderID, e.Type, e.ActorID, data, e.DedupeKey()).Scan(&e.ID, &e.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return false, err
+	}
+
+	outboxQuery := `INSERT INTO outbox (id, aggregate_id, event_type, payload) VALUES ($1, $2, $3, $4)`
+	if _, err := tx.Exec(ctx, outboxQuery, e.ID, e.OrderID, e.Type, payload); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *OrderEventRepository) ListByOrder(ctx context.Context, orderID string) ([]domain.OrderEvent, error) {
+	query := `
+		SELECT id, order_id, type, COALESCE(actor_id::TEXT, ''), data, created_at
+		FROM order_events
+		WHERE order_id = $1
+		ORDER BY created_at ASC`
+
+	rows, err := r.db.Query(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []domain.OrderEvent
+	for rows.Next() {
+		var e domain.OrderEvent
+		var data []byte
+		if err := rows.Scan(&e.ID, &e.OrderID, &e.Type, &e.ActorID, &data, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &e.Data); err != nil {
+				return nil, err
+			}
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
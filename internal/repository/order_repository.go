@@ -3,39 +3,51 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 	"tracking/internal/domain"
 	"tracking/internal/utils"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/redis/go-redis/v9"
 )
 type OrderRepositoryInterface interface {
 	GetPending(ctx context.Context) ([]domain.Order, error)
 	GetOrderById(ctx context.Context, id string) (domain.Order, error)
 	GetHistory(ctx context.Context, userID string) ([]domain.Order, error)	
 	HasActiveOrder(ctx context.Context, driverID string) (bool, error)
+	GetActiveByDriver(ctx context.Context, driverID string) (domain.Order, error)
 	
-	CreateWithItems(ctx context.Context, o *domain.Order) (string, error)
-	CompleteOrder(ctx context.Context, orderID string, driverID string) error
-	AcceptOrder(ctx context.Context, orderID string, driverID string) error
+	// Las transiciones registran su evento (timeline + outbox) en la misma transacción
+	CreateWithItems(ctx context.Context, o *domain.Order, event *domain.OrderEvent) (string, error)
+	CompleteOrder(ctx context.Context, orderID string, driverID string, earning *domain.DriverEarning, event *domain.OrderEvent) error
+	AcceptOrder(ctx context.Context, orderID string, driverID string, event *domain.OrderEvent) error
+	PickUpOrder(ctx context.Context, orderID string, driverID string, event *domain.OrderEvent) error
+	CancelOrder(ctx context.Context, orderID, customerID string, anyOrder bool, event *domain.OrderEvent) error
+	AddTip(ctx context.Context, orderID string, customerID string, earning *domain.DriverEarning, event *domain.OrderEvent) error
+
+	// Pedidos programados
+	CreateScheduledWithItems(ctx context.Context, o *domain.Order, event *domain.OrderEvent, slotCapacity int) (string, error)
+	CountScheduledBetween(ctx context.Context, from, to time.Time) (map[int64]int, error)
+	ReleaseDueScheduled(ctx context.Context, releaseUntil time.Time) (int, error)
 }
 type OrderRepository struct {
-	db  *pgxpool.Pool
-	rdb *redis.Client
+	db *pgxpool.Pool
 }
 
-func NewOrderRepository(db *pgxpool.Pool, rdb *redis.Client) *OrderRepository {
-	return &OrderRepository{db: db, rdb: rdb}
+func NewOrderRepository(db *pgxpool.Pool) *OrderRepository {
+	return &OrderRepository{db: db}
 }
 
 func (r *OrderRepository) GetPending(ctx context.Context) ([]domain.Order, error) {
 	query := `
     SELECT o.id, o.customer_id, u.full_name, o.status, 
            o.origin_lat, o.origin_lng, o.dest_lat, o.dest_lng, 
-           o.destination_address, o.total_price, o.created_at
+           o.destination_address, o.total_price, o.created_at,
+           o.scheduled_start, o.scheduled_end
     FROM orders o
     JOIN users u ON o.customer_id = u.id -- El JOIN es clave
     WHERE o.status = 'PENDING'
+      AND (o.scheduled_start IS NULL OR o.released_at IS NOT NULL) -- los programados aparecen recién al liberarse
     ORDER BY o.created_at DESC`
 
 	rows, err := r.db.Query(ctx, query)
@@ -51,6 +63,7 @@ func (r *OrderRepository) GetPending(ctx context.Context) ([]domain.Order, error
 			&o.ID, &o.CustomerID, &o.CustomerName, &o.Status,
 			&o.OriginLat, &o.OriginLng, &o.DestLat, &o.DestLng,
 			&o.DestinationAddress, &o.TotalPrice, &o.CreatedAt,
+			&o.ScheduledStart, &o.ScheduledEnd,
 		)
 		if err != nil {
 			return nil, err
@@ -84,20 +97,23 @@ func (r *OrderRepository) GetPending(ctx context.Context) ([]domain.Order, error
 
 	return orders, nil
 }
-func (r *OrderRepository) AcceptOrder(ctx context.Context, orderID string, driverID string) error {
+func (r *OrderRepository) AcceptOrder(ctx context.Context, orderID string, driverID string, event *domain.OrderEvent) error {
 	query := `UPDATE orders 
 	          SET driver_id = $1, status = 'ASSIGNED' 
-	          WHERE id = $2 AND status = 'PENDING'`
+	          WHERE id = $2 AND status = 'PENDING'
+	            AND (scheduled_start IS NULL OR released_at IS NOT NULL) -- un programado no se acepta antes de liberarse`
 
-	result, err := r.db.Exec(ctx, query, driverID, orderID)
-	if err != nil {
-		return err
-	}
+	return r.execTransition(ctx, orderID, event, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query, driverID, orderID)
+		if err != nil {
+			return err
+		}
 
-	if result.RowsAffected() == 0 {
-		return utils.ErrOrderNotAvailable
-	}
-	return nil
+		if result.RowsAffected() == 0 {
+			return utils.ErrOrderNotAvailable
+		}
+		return nil
+	})
 }
 func (r *OrderRepository) GetOrderById(ctx context.Context, id string) (domain.Order, error) {
 	queryOrder := `
@@ -105,7 +121,9 @@ func (r *OrderRepository) GetOrderById(ctx context.Context, id string) (domain.O
 			o.id, o.customer_id, u_c.full_name,
 			COALESCE(o.driver_id::TEXT, ''), COALESCE(u_d.full_name, ''),
 			o.status, o.origin_lat, o.origin_lng, o.dest_lat, o.dest_lng, 
-			o.destination_address, o.total_price, o.created_at
+			o.destination_address, COALESCE(o.apartment, ''), COALESCE(o.delivery_instructions, ''),
+			o.total_price, o.tip_amount, o.tip_added_at, o.created_at,
+			o.scheduled_start, o.scheduled_end, o.released_at
 		FROM orders o
 		JOIN users u_c ON o.customer_id = u_c.id
 		LEFT JOIN users u_d ON o.driver_id = u_d.id
@@ -116,7 +134,9 @@ func (r *OrderRepository) GetOrderById(ctx context.Context, id string) (domain.O
 		&o.ID, &o.CustomerID, &o.CustomerName,
 		&o.DriverID, &o.DriverName,
 		&o.Status, &o.OriginLat, &o.OriginLng, &o.DestLat, &o.DestLng,
-		&o.DestinationAddress, &o.TotalPrice, &o.CreatedAt,
+		&o.DestinationAddress, &o.Apartment, &o.DeliveryInstructions,
+		&o.TotalPrice, &o.TipAmount, &o.TipAddedAt, &o.CreatedAt,
+		&o.ScheduledStart, &o.ScheduledEnd, &o.ReleasedAt,
 	)
 	if err != nil {
 		return o, err
@@ -145,30 +165,123 @@ func (r *OrderRepository) GetOrderById(ctx context.Context, id string) (domain.O
 	return o, nil
 }
 
-func (r *OrderRepository) CompleteOrder(ctx context.Context, orderID string, driverID string) error {
+// CompleteOrder marca el pedido entregado y registra la ganancia del repartidor en la misma transacción
+func (r *OrderRepository) CompleteOrder(ctx context.Context, orderID string, driverID string, earning *domain.DriverEarning, event *domain.OrderEvent) error {
 	query := `UPDATE orders SET status = 'DELIVERED' 
+	          WHERE id = $1 AND driver_id = $2 AND status IN ('ASSIGNED', 'PICKED_UP')`
+
+	// La posición del driver en Redis la limpia el consumidor del evento ORDER_DELIVERED
+	return r.execTransition(ctx, orderID, event, func(tx pgx.Tx) error {
+		res, err := tx.Exec(ctx, query, orderID, driverID)
+		if err != nil {
+			return err
+		}
+
+		if res.RowsAffected() == 0 {
+			return errors.New("no se pudo completar el pedido (revisar ID o estado)")
+		}
+		return insertDriverEarning(ctx, tx, earning)
+	})
+}
+func (r *OrderRepository) PickUpOrder(ctx context.Context, orderID string, driverID string, event *domain.OrderEvent) error {
+	query := `UPDATE orders SET status = 'PICKED_UP'
 	          WHERE id = $1 AND driver_id = $2 AND status = 'ASSIGNED'`
 
-	res, err := r.db.Exec(ctx, query, orderID, driverID)
+	return r.execTransition(ctx, orderID, event, func(tx pgx.Tx) error {
+		res, err := tx.Exec(ctx, query, orderID, driverID)
+		if err != nil {
+			return err
+		}
+
+		if res.RowsAffected() == 0 {
+			return utils.ErrInvalidState
+		}
+		return nil
+	})
+}
+
+// CancelOrder cancela en el mismo UPDATE que valida el estado: sin anyOrder solo el cliente dueño y
+// solo si sigue PENDING, así un driver que acepta entre la validación y la cancelación no la deja pasar.
+func (r *OrderRepository) CancelOrder(ctx context.Context, orderID, customerID string, anyOrder bool, event *domain.OrderEvent) error {
+	query := `UPDATE orders SET status = 'CANCELLED'
+	          WHERE id = $1 AND status = 'PENDING' AND customer_id = $2
+	          RETURNING COALESCE(driver_id::TEXT, '')`
+	args := []interface{}{orderID, customerID}
+	if anyOrder {
+		query = `UPDATE orders SET status = 'CANCELLED'
+		         WHERE id = $1 AND status IN ('PENDING', 'ASSIGNED', 'PICKED_UP')
+		         RETURNING COALESCE(driver_id::TEXT, '')`
+		args = []interface{}{orderID}
+	}
+
+	return r.execTransition(ctx, orderID, event, func(tx pgx.Tx) error {
+		var driverID string
+		err := tx.QueryRow(ctx, query, args...).Scan(&driverID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return utils.ErrInvalidState
+		}
+		if err != nil {
+			return err
+		}
+
+		// El consumidor del evento usa driver_id para limpiar la posición en Redis
+		if event != nil && driverID != "" {
+			if event.Data == nil {
+				event.Data = map[string]interface{}{}
+			}
+			event.Data["driver_id"] = driverID
+		}
+		return nil
+	})
+}
+
+// AddTip suma la propina post-entrega y la acredita al repartidor. El filtro por tip_added_at
+// garantiza que se use una sola vez aunque lleguen dos pedidos en paralelo.
+func (r *OrderRepository) AddTip(ctx context.Context, orderID string, customerID string, earning *domain.DriverEarning, event *domain.OrderEvent) error {
+	query := `UPDATE orders SET tip_amount = tip_amount + $3, tip_added_at = NOW()
+	          WHERE id = $1 AND customer_id = $2 AND status = 'DELIVERED' AND tip_added_at IS NULL`
+
+	return r.execTransition(ctx, orderID, event, func(tx pgx.Tx) error {
+		res, err := tx.Exec(ctx, query, orderID, customerID, earning.TipAmount)
+		if err != nil {
+			return err
+		}
+
+		if res.RowsAffected() == 0 {
+			return utils.ErrTipAlreadyAdded
+		}
+		return insertDriverEarning(ctx, tx, earning)
+	})
+}
+
+// execTransition ejecuta fn y registra el evento en la misma transacción, así el
+// cambio de estado y su publicación (vía outbox) se confirman o se descartan juntos.
+func (r *OrderRepository) execTransition(ctx context.Context, orderID string, event *domain.OrderEvent, fn func(tx pgx.Tx) error) error {
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback(ctx)
 
-	if res.RowsAffected() == 0 {
-		return errors.New("no se pudo completar el pedido (revisar ID o estado)")
+	if err := fn(tx); err != nil {
+		return err
 	}
 
-	// Se usa ZREM porque GEOADD crea un Sorted Set internamente
-	r.rdb.ZRem(ctx, "drivers_locations", driverID)
+	if event != nil {
+		event.OrderID = orderID
+		if _, err := insertOrderEvent(ctx, tx, event); err != nil {
+			return err
+		}
+	}
 
-	return nil
+	return tx.Commit(ctx)
 }
 func (r *OrderRepository) GetHistory(ctx context.Context, userID string) ([]domain.Order, error) {
     query := `
         SELECT 
             o.id, o.customer_id, u.full_name, o.status, 
 			o.origin_lat, o.origin_lng, o.dest_lat, o.dest_lng,
-            o.destination_address, o.total_price, o.created_at 
+            o.destination_address, o.total_price, o.tip_amount, o.created_at 
         FROM orders o
         JOIN users u ON o.customer_id = u.id
         WHERE (o.customer_id = $1 OR o.driver_id = $1) AND o.status = 'DELIVERED'
@@ -186,7 +299,7 @@ func (r *OrderRepository) GetHistory(ctx context.Context, userID string) ([]doma
         err := rows.Scan(
             &o.ID, &o.CustomerID, &o.CustomerName, &o.Status, 
 			&o.OriginLat, &o.OriginLng, &o.DestLat, &o.DestLng,
-            &o.DestinationAddress, &o.TotalPrice, &o.CreatedAt,
+            &o.DestinationAddress, &o.TotalPrice, &o.TipAmount, &o.CreatedAt,
         )
         if err != nil {
             return nil, err
@@ -219,42 +332,190 @@ func (r *OrderRepository) GetHistory(ctx context.Context, userID string) ([]doma
     return orders, nil
 }
 func (r *OrderRepository) HasActiveOrder(ctx context.Context, driverID string) (bool, error) {
-	query := `SELECT EXISTS(SELECT 1 FROM orders WHERE driver_id = $1 AND status = 'ASSIGNED')`
+	query := `SELECT EXISTS(SELECT 1 FROM orders WHERE driver_id = $1 AND status IN ('ASSIGNED', 'PICKED_UP'))`
 
 	var exists bool
 	err := r.db.QueryRow(ctx, query, driverID).Scan(&exists)
 	return exists, err
 }
-func (r *OrderRepository) CreateWithItems(ctx context.Context, o *domain.Order) (string, error) {
+func (r *OrderRepository) GetActiveByDriver(ctx context.Context, driverID string) (domain.Order, error) {
+	query := `
+		SELECT o.id, o.customer_id, COALESCE(o.driver_id::TEXT, ''), o.status,
+		       o.origin_lat, o.origin_lng, o.dest_lat, o.dest_lng,
+		       o.destination_address, o.total_price, o.created_at
+		FROM orders o
+		WHERE o.driver_id = $1 AND o.status IN ('ASSIGNED', 'PICKED_UP')
+		ORDER BY o.created_at DESC
+		LIMIT 1`
+
+	var o domain.Order
+	err := r.db.QueryRow(ctx, query, driverID).Scan(
+		&o.ID, &o.CustomerID, &o.DriverID, &o.Status,
+		&o.OriginLat, &o.OriginLng, &o.DestLat, &o.DestLng,
+		&o.DestinationAddress, &o.TotalPrice, &o.CreatedAt,
+	)
+	return o, err
+}
+func (r *OrderRepository) CreateWithItems(ctx context.Context, o *domain.Order, event *domain.OrderEvent) (string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	orderID, err := insertOrderWithItems(ctx, tx, o, event)
+	if err != nil {
+		return "", err
+	}
+	return orderID, tx.Commit(ctx)
+}
+
+// CreateScheduledWithItems crea un pedido programado solo si su franja todavía tiene lugar.
+// El advisory lock serializa las altas de la misma franja para que el conteo no quede viejo.
+func (r *OrderRepository) CreateScheduledWithItems(ctx context.Context, o *domain.Order, event *domain.OrderEvent, slotCapacity int) (string, error) {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return "", err
 	}
 	defer tx.Rollback(ctx)
 
+	slotKey := "delivery_slot:" + o.ScheduledStart.UTC().Format(time.RFC3339)
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, slotKey); err != nil {
+		return "", err
+	}
+
+	var taken int
+	countQuery := `SELECT COUNT(*) FROM orders WHERE scheduled_start = $1 AND status <> 'CANCELLED'`
+	if err := tx.QueryRow(ctx, countQuery, o.ScheduledStart).Scan(&taken); err != nil {
+		return "", err
+	}
+	if taken >= slotCapacity {
+		return "", utils.ErrDeliverySlotFull
+	}
+
+	orderID, err := insertOrderWithItems(ctx, tx, o, event)
+	if err != nil {
+		return "", err
+	}
+	return orderID, tx.Commit(ctx)
+}
+
+// CountScheduledBetween devuelve cuántos pedidos activos hay por franja, indexados por el Unix del inicio
+func (r *OrderRepository) CountScheduledBetween(ctx context.Context, from, to time.Time) (map[int64]int, error) {
+	query := `
+		SELECT scheduled_start, COUNT(*)
+		FROM orders
+		WHERE scheduled_start >= $1 AND scheduled_start < $2 AND status <> 'CANCELLED'
+		GROUP BY scheduled_start`
+
+	rows, err := r.db.Query(ctx, query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[int64]int{}
+	for rows.Next() {
+		var start time.Time
+		var count int
+		if err := rows.Scan(&start, &count); err != nil {
+			return nil, err
+		}
+		counts[start.Unix()] = count
+	}
+	return counts, rows.Err()
+}
+
+// ReleaseDueScheduled libera los pedidos programados cuya franja empieza antes de releaseUntil,
+// así aparecen en /orders/pending. Cada liberación deja su evento ORDER_RELEASED.
+func (r *OrderRepository) ReleaseDueScheduled(ctx context.Context, releaseUntil time.Time) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE orders SET released_at = NOW()
+		WHERE id IN (
+			SELECT id FROM orders
+			WHERE status = 'PENDING' AND scheduled_start IS NOT NULL AND released_at IS NULL
+			  AND scheduled_start <= $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, scheduled_start`
+
+	rows, err := tx.Query(ctx, query, releaseUntil)
+	if err != nil {
+		return 0, err
+	}
+
+	type released struct {
+		id    string
+		start time.Time
+	}
+	var orders []released
+	for rows.Next() {
+		var o released
+		if err := rows.Scan(&o.id, &o.start); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		orders = append(orders, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, o := range orders {
+		event := &domain.OrderEvent{
+			OrderID: o.id,
+			Type:    domain.OrderEventReleased,
+			Data:    map[string]interface{}{"scheduled_start": o.start},
+		}
+		if _, err := insertOrderEvent(ctx, tx, event); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(orders), tx.Commit(ctx)
+}
+
+func insertOrderWithItems(ctx context.Context, tx pgx.Tx, o *domain.Order, event *domain.OrderEvent) (string, error) {
 	queryOrder := `
         INSERT INTO orders (
             customer_id, status, destination_address, total_price, 
             origin_lat, origin_lng, dest_lat, dest_lng,
-            origin, destination
+            origin, destination,
+            address_id, apartment, delivery_instructions,
+            scheduled_start, scheduled_end, tip_amount
         )
         VALUES (
             $1, $2, $3, $4, $5, $6, $7, $8,
             ST_SetSRID(ST_MakePoint($6, $5), 4326)::geography, 
-            ST_SetSRID(ST_MakePoint($8, $7), 4326)::geography
+            ST_SetSRID(ST_MakePoint($8, $7), 4326)::geography,
+            NULLIF($9, '')::uuid, NULLIF($10, ''), NULLIF($11, ''),
+            $12, $13, $14
         )
         RETURNING id`
 
 	var orderID string
-	err = tx.QueryRow(ctx, queryOrder,
-		o.CustomerID,         // $1
-		o.Status,             // $2
-		o.DestinationAddress, // $3
-		o.TotalPrice,         // $4
-		o.OriginLat,          // $5
-		o.OriginLng,          // $6
-		o.DestLat,            // $7
-		o.DestLng,            // $8
+	err := tx.QueryRow(ctx, queryOrder,
+		o.CustomerID,           // $1
+		o.Status,               // $2
+		o.DestinationAddress,   // $3
+		o.TotalPrice,           // $4
+		o.OriginLat,            // $5
+		o.OriginLng,            // $6
+		o.DestLat,              // $7
+		o.DestLng,              // $8
+		o.AddressID,            // $9
+		o.Apartment,            // $10
+		o.DeliveryInstructions, // $11
+		o.ScheduledStart,       // $12
+		o.ScheduledEnd,         // $13
+		o.TipAmount,            // $14
 	).Scan(&orderID)
 
 	if err != nil {
@@ -268,5 +529,12 @@ func (r *OrderRepository) CreateWithItems(ctx context.Context, o *domain.Order)
 		}
 	}
 
-	return orderID, tx.Commit(ctx)
+	if event != nil {
+		event.OrderID = orderID
+		if _, err := insertOrderEvent(ctx, tx, event); err != nil {
+			return "", err
+		}
+	}
+
+	return orderID, nil
 }
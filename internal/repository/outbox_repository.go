@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// OutboxEntry es una fila reclamada del outbox con el evento serializado
+type OutboxEntry struct {
+	ID        string
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+type OutboxRepositoryInterface interface {
+	ClaimPending(ctx context.Context, limit int, staleBefore time.Time) ([]OutboxEntry, error)
+	MarkPublished(ctx context.Context, id string) error
+	Release(ctx context.Context, ids []string, lastError string) error
+	DeletePublishedBefore(ctx context.Context, before time.Time) (int64, error)
+	CountPending(ctx context.Context) (int, error)
+}
+type OutboxRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOutboxRepository(db *pgxpool.Pool) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// ClaimPending pasa hasta limit filas pendientes a PROCESSING en una sola sentencia (SKIP LOCKED, para
+// poder correr varios relays) y las devuelve en orden. Así la transacción no queda abierta mientras
+// se publica en Redis. Las filas PROCESSING reclamadas antes de staleBefore se vuelven a tomar: son
+// de un relay que se cayó antes de marcarlas.
+func (r *OutboxRepository) ClaimPending(ctx context.Context, limit int, staleBefore time.Time) ([]OutboxEntry, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE outbox SET status = 'PROCESSING', claimed_at = NOW(), attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE status = 'PENDING' OR (status = 'PROCESSING' AND claimed_at < $2)
+			ORDER BY created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, payload, created_at`, limit, staleBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []OutboxEntry
+	for rows.Next() {
+		var e OutboxEntry
+		if err := rows.Scan(&e.ID, &e.Payload, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// RETURNING no respeta el ORDER BY de la subconsulta
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].CreatedAt.Before(entries[j].CreatedAt) })
+	return entries, nil
+}
+
+func (r *OutboxRepository) MarkPublished(ctx context.Context, id string) error {
+	_, err := r.db.Exec(ctx, `UPDATE outbox SET status = 'PUBLISHED', last_error = NULL, published_at = NOW() WHERE id = $1`, id)
+	return err
+}
+
+// Release devuelve filas reclamadas a PENDING para el próximo ciclo
+func (r *OutboxRepository) Release(ctx context.Context, ids []string, lastError string) error {
+	_, err := r.db.Exec(ctx, `UPDATE outbox SET status = 'PENDING', claimed_at = NULL, last_error = NULLIF($2, '')
+		WHERE id = ANY($1::uuid[]) AND status = 'PROCESSING'`, ids, lastError)
+	return err
+}
+
+func (r *OutboxRepository) DeletePublishedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM outbox WHERE status = 'PUBLISHED' AND published_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+func (r *OutboxRepository) CountPending(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM outbox WHERE status <> 'PUBLISHED'`).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"tracking/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrRoleExists indica que ya hay un rol con ese nombre
+var ErrRoleExists = errors.New("role already exists")
+
+type PermissionRepositoryInterface interface {
+	RolePermissions(ctx context.Context, role string) ([]string, error)
+	ListPermissions(ctx context.Context) ([]domain.Permission, error)
+	ListRoles(ctx context.Context) ([]domain.Role, error)
+	GetRole(ctx context.Context, name string) (domain.Role, error)
+	CreateRole(ctx context.Context, role domain.Role) error
+	SetRolePermissions(ctx context.Context, role string, permissions []string) error
+	DeleteRole(ctx context.Context, name string) error
+}
+type PermissionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPermissionRepository(db *pgxpool.Pool) *PermissionRepository {
+	return &PermissionRepository{db: db}
+}
+
+func (r *PermissionRepository) RolePermissions(ctx context.Context, role string) ([]string, error) {
+	rows, err := r.db.Query(ctx, `SELECT permission FROM role_permissions WHERE role = $1`, role)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+func (r *PermissionRepository) ListPermissions(ctx context.Context) ([]domain.Permission, error) {
+	rows, err := r.db.Query(ctx, `SELECT name, description FROM permissions ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions []domain.Permission
+	for rows.Next() {
+		var p domain.Permission
+		if err := rows.Scan(&p.Name, &p.Description); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+	return permissions, rows.Err()
+}
+
+const roleQuery = `
+	SELECT r.name, r.description, r.is_system, r.created_at,
+	       COALESCE(ARRAY(SELECT permission FROM role_permissions rp WHERE rp.role = r.name ORDER BY permission), '{}'),
+	       (SELECT COUNT(*) FROM users u WHERE u.role = r.name)
+	FROM roles r`
+
+func (r *PermissionRepository) ListRoles(ctx context.Context) ([]domain.Role, error) {
+	rows, err := r.db.Query(ctx, roleQuery+` ORDER BY r.is_system DESC, r.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []domain.Role
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func (r *PermissionRepository) GetRole(ctx context.Context, name string) (domain.Role, error) {
+	return scanRole(r.db.QueryRow(ctx, roleQuery+` WHERE r.name = $1`, name))
+}
+
+func (r *PermissionRepository) CreateRole(ctx context.Context, role domain.Role) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `INSERT INTO roles (name, description) VALUES ($1, $2)`, role.Name, role.Description)
+	if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+		return ErrRoleExists
+	}
+	if err != nil {
+		return err
+	}
+	if err := insertRolePermissions(ctx, tx, role.Name, role.Permissions); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// SetRolePermissions reemplaza todos los permisos del rol
+func (r *PermissionRepository) SetRolePermissions(ctx context.Context, role string, permissions []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM role_permissions WHERE role = $1`, role); err != nil {
+		return err
+	}
+	if err := insertRolePermissions(ctx, tx, role, permissions); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// DeleteRole borra un rol que no es de sistema; la FK de users impide borrarlo si tiene usuarios
+func (r *PermissionRepository) DeleteRole(ctx context.Context, name string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM roles WHERE name = $1 AND is_system = false`, name)
+	return err
+}
+
+func insertRolePermissions(ctx context.Context, tx pgx.Tx, role string, permissions []string) error {
+	_, err := tx.Exec(ctx,
+		`INSERT INTO role_permissions (role, permission) SELECT $1, unnest($2::text[])`, role, permissions,
+	)
+	return err
+}
+
+func scanRole(row pgx.Row) (domain.Role, error) {
+	var role domain.Role
+	err := row.Scan(&role.Name, &role.Description, &role.IsSystem, &role.CreatedAt, &role.Permissions, &role.UserCount)
+	return role, err
+}
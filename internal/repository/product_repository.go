@@ -23,8 +23,15 @@ func NewProductRepository(db *pgxpool.Pool) *ProductRepository {
 	return &ProductRepository{db: db}
 }
 
+// Promedio y cantidad de calificaciones visibles del producto (0 si no tiene)
+const productRatingColumns = `COALESCE(ROUND(pr.rating_sum::numeric / NULLIF(pr.rating_count, 0), 2), 0)::float8,
+	COALESCE(pr.rating_count, 0)`
+
 func (r *ProductRepository) GetProductsRepo(ctx context.Context) ([]domain.Product, error) {
-	query := `SELECT id, name, price, description, is_active FROM products WHERE is_active = true`
+	query := `SELECT p.id, p.name, p.price, p.description, p.is_active, ` + productRatingColumns + `
+	          FROM products p
+	          LEFT JOIN product_ratings pr ON pr.product_id = p.id
+	          WHERE p.is_active = true`
 	rows, err := r.db.Query(ctx, query)
 	if err != nil {
 		return nil, err
@@ -34,7 +41,7 @@ func (r *ProductRepository) GetProductsRepo(ctx context.Context) ([]domain.Produ
 	var products []domain.Product
 	for rows.Next() {
 		var p domain.Product
-		if err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Description, &p.IsActive); err != nil {
+		if err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Description, &p.IsActive, &p.Rating.Average, &p.Rating.Count); err != nil {
 			return nil, err
 		}
 		products = append(products, p)
@@ -43,9 +50,12 @@ func (r *ProductRepository) GetProductsRepo(ctx context.Context) ([]domain.Produ
 }
 
 func (r *ProductRepository) GetByID(ctx context.Context, id string) (domain.Product, error) {
-	query := `SELECT id, name, price, description, is_active FROM products WHERE id = $1 AND is_active = true`
+	query := `SELECT p.id, p.name, p.price, p.description, p.is_active, ` + productRatingColumns + `
+	          FROM products p
+	          LEFT JOIN product_ratings pr ON pr.product_id = p.id
+	          WHERE p.id = $1 AND p.is_active = true`
 	var p domain.Product
-	err := r.db.QueryRow(ctx, query, id).Scan(&p.ID, &p.Name, &p.Price, &p.Description, &p.IsActive)
+	err := r.db.QueryRow(ctx, query, id).Scan(&p.ID, &p.Name, &p.Price, &p.Description, &p.IsActive, &p.Rating.Average, &p.Rating.Count)
 	return p, err
 }
 
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RateLimitRepositoryInterface interface {
+	Hit(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error)
+	Reset(ctx context.Context, key string) error
+
+	// Bloqueos temporales (ej. lockout de login)
+	Block(ctx context.Context, key string, ttl time.Duration) error
+	BlockedFor(ctx context.Context, key string) (time.Duration, error)
+	Unblock(ctx context.Context, key string) error
+}
+
+// RateLimitRepository cuenta hits en ventanas fijas usando INCR + EXPIRE en Redis
+type RateLimitRepository struct {
+	rdb *redis.Client
+}
+
+func NewRateLimitRepository(rdb *redis.Client) *RateLimitRepository {
+	return &RateLimitRepository{rdb: rdb}
+}
+
+// El EXPIRE solo se pone con el primer hit para que la ventana no se corra con cada request
+var rateLimitHitScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return {count, redis.call('PTTL', KEYS[1])}
+`)
+
+// Hit suma uno al contador de key y devuelve el total de la ventana y cuánto falta para que se reinicie
+func (r *RateLimitRepository) Hit(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	res, err := rateLimitHitScript.Run(ctx, r.rdb, []string{rateLimitKey(key)}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+	return res[0], time.Duration(res[1]) * time.Millisecond, nil
+}
+
+func (r *RateLimitRepository) Reset(ctx context.Context, key string) error {
+	return r.rdb.Del(ctx, rateLimitKey(key)).Err()
+}
+
+// Block impide usar key durante ttl. Un bloqueo más largo no se acorta con uno más corto.
+func (r *RateLimitRepository) Block(ctx context.Context, key string, ttl time.Duration) error {
+	remaining, err := r.BlockedFor(ctx, key)
+	if err != nil {
+		return err
+	}
+	if remaining >= ttl {
+		return nil
+	}
+	return r.rdb.Set(ctx, rateLimitBlockKey(key), 1, ttl).Err()
+}
+
+// BlockedFor devuelve cuánto falta para que se levante el bloqueo de key (0 si no está bloqueada)
+func (r *RateLimitRepository) BlockedFor(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.rdb.PTTL(ctx, rateLimitBlockKey(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (r *RateLimitRepository) Unblock(ctx context.Context, key string) error {
+	return r.rdb.Del(ctx, rateLimitBlockKey(key)).Err()
+}
+
+func rateLimitBlockKey(key string) string {
+	return "ratelimit:block:" + key
+}
+
+func rateLimitKey(key string) string {
+	return "ratelimit:" + key
+}
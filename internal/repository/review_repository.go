@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"tracking/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrReviewExists indica que el pedido ya fue calificado
+var ErrReviewExists = errors.New("el pedido ya tiene una reseña")
+
+type ReviewRepositoryInterface interface {
+	Create(ctx context.Context, r *domain.Review) error
+	GetByOrder(ctx context.Context, orderID string) (domain.Review, error)
+	List(ctx context.Context, driverID string, hidden *bool) ([]domain.Review, error)
+	SetHidden(ctx context.Context, id string, hidden bool, reason, adminID string) (domain.Review, error)
+	GetDriverRating(ctx context.Context, driverID string) (domain.RatingSummary, error)
+}
+type ReviewRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewReviewRepository(db *pgxpool.Pool) *ReviewRepository {
+	return &ReviewRepository{db: db}
+}
+
+const reviewColumns = `id, order_id, customer_id, COALESCE(driver_id::TEXT, ''), driver_score, product_score, tags,
+	COALESCE(comment, ''), is_hidden, COALESCE(hidden_reason, ''), COALESCE(hidden_by::TEXT, ''), hidden_at, created_at`
+
+// Create guarda la reseña y suma sus puntajes a los agregados del repartidor y de cada producto
+// del pedido, todo en una transacción.
+func (r *ReviewRepository) Create(ctx context.Context, rv *domain.Review) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO order_reviews (order_id, customer_id, driver_id, driver_score, product_score, tags, comment)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5, $6, NULLIF($7, ''))
+		ON CONFLICT (order_id) DO NOTHING
+		RETURNING id, created_at`
+
+	err = tx.QueryRow(ctx, query,
+		rv.OrderID, rv.CustomerID, rv.DriverID, rv.DriverScore, rv.ProductScore, rv.Tags, rv.Comment,
+	).Scan(&rv.ID, &rv.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrReviewExists
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := applyReviewToRatings(ctx, tx, *rv, 1); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *ReviewRepository) GetByOrder(ctx context.Context, orderID string) (domain.Review, error) {
+	query := `SELECT ` + reviewColumns + ` FROM order_reviews WHERE order_id = $1`
+	return scanReview(r.db.QueryRow(ctx, query, orderID))
+}
+
+func (r *ReviewRepository) List(ctx context.Context, driverID string, hidden *bool) ([]domain.Review, error) {
+	query := `SELECT ` + reviewColumns + ` FROM order_reviews
+	          WHERE ($1 = '' OR driver_id::TEXT = $1) AND ($2::boolean IS NULL OR is_hidden = $2)
+	          ORDER BY created_at DESC
+	          LIMIT 200`
+
+	rows, err := r.db.Query(ctx, query, driverID, hidden)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reviews []domain.Review
+	for rows.Next() {
+		rv, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, rv)
+	}
+	return reviews, rows.Err()
+}
+
+// SetHidden oculta o vuelve a mostrar una reseña y ajusta los agregados en consecuencia.
+// Si la reseña ya estaba en ese estado no cambia nada.
+func (r *ReviewRepository) SetHidden(ctx context.Context, id string, hidden bool, reason, adminID string) (domain.Review, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return domain.Review{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	current, err := scanReview(tx.QueryRow(ctx, `SELECT `+reviewColumns+` FROM order_reviews WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		return domain.Review{}, err
+	}
+	if current.IsHidden == hidden {
+		return current, nil
+	}
+
+	query := `
+		UPDATE order_reviews
+		SET is_hidden = $2,
+		    hidden_reason = CASE WHEN $2 THEN NULLIF($3, '') END,
+		    hidden_by = CASE WHEN $2 THEN NULLIF($4, '')::uuid END,
+		    hidden_at = CASE WHEN $2 THEN NOW() END
+		WHERE id = $1
+		RETURNING ` + reviewColumns
+
+	updated, err := scanReview(tx.QueryRow(ctx, query, id, hidden, reason, adminID))
+	if err != nil {
+		return domain.Review{}, err
+	}
+
+	delta := 1
+	if hidden {
+		delta = -1
+	}
+	if err := applyReviewToRatings(ctx, tx, updated, delta); err != nil {
+		return domain.Review{}, err
+	}
+	return updated, tx.Commit(ctx)
+}
+
+func (r *ReviewRepository) GetDriverRating(ctx context.Context, driverID string) (domain.RatingSummary, error) {
+	query := `SELECT COALESCE(ROUND(rating_sum::numeric / NULLIF(rating_count, 0), 2), 0)::float8, rating_count
+	          FROM driver_ratings WHERE driver_id = $1`
+
+	var summary domain.RatingSummary
+	err := r.db.QueryRow(ctx, query, driverID).Scan(&summary.Average, &summary.Count)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.RatingSummary{}, nil
+	}
+	return summary, err
+}
+
+// applyReviewToRatings suma (delta=1) o resta (delta=-1) los puntajes de la reseña en los agregados
+func applyReviewToRatings(ctx context.Context, tx pgx.Tx, rv domain.Review, delta int) error {
+	if rv.DriverID != "" {
+		query := `
+			INSERT INTO driver_ratings (driver_id, rating_count, rating_sum) VALUES ($1, $2, $3)
+			ON CONFLICT (driver_id) DO UPDATE SET
+				rating_count = driver_ratings.rating_count + EXCLUDED.rating_count,
+				rating_sum = driver_ratings.rating_sum + EXCLUDED.rating_sum`
+		if _, err := tx.Exec(ctx, query, rv.DriverID, delta, delta*rv.DriverScore); err != nil {
+			return err
+		}
+	}
+
+	// El puntaje de productos cuenta una vez para cada producto distinto del pedido
+	query := `
+		INSERT INTO product_ratings (product_id, rating_count, rating_sum)
+		SELECT DISTINCT product_id, $2::int, $3::int FROM order_items WHERE order_id = $1
+		ON CONFLICT (product_id) DO UPDATE SET
+			rating_count = product_ratings.rating_count + EXCLUDED.rating_count,
+			rating_sum = product_ratings.rating_sum + EXCLUDED.rating_sum`
+	_, err := tx.Exec(ctx, query, rv.OrderID, delta, delta*rv.ProductScore)
+	return err
+}
+
+func scanReview(row pgx.Row) (domain.Review, error) {
+	var rv domain.Review
+	err := row.Scan(
+		&rv.ID, &rv.OrderID, &rv.CustomerID, &rv.DriverID, &rv.DriverScore, &rv.ProductScore, &rv.Tags,
+		&rv.Comment, &rv.IsHidden, &rv.HiddenReason, &rv.HiddenBy, &rv.HiddenAt, &rv.CreatedAt,
+	)
+	return rv, err
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"tracking/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ShareLinkRepositoryInterface interface {
+	Create(ctx context.Context, l *domain.ShareLink) error
+	GetByID(ctx context.Context, id string) (domain.ShareLink, error)
+	ListByOrder(ctx context.Context, orderID string) ([]domain.ShareLink, error)
+	Revoke(ctx context.Context, id, orderID string) error
+}
+type ShareLinkRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewShareLinkRepository(db *pgxpool.Pool) *ShareLinkRepository {
+	return &ShareLinkRepository{db: db}
+}
+
+func (r *ShareLinkRepository) Create(ctx context.Context, l *domain.ShareLink) error {
+	query := `INSERT INTO order_share_links (order_id, created_by, expires_at)
+	          VALUES ($1, $2, $3) RETURNING id, created_at`
+
+	return r.db.QueryRow(ctx, query, l.OrderID, l.CreatedBy, l.ExpiresAt).Scan(&l.ID, &l.CreatedAt)
+}
+
+func (r *ShareLinkRepository) GetByID(ctx context.Context, id string) (domain.ShareLink, error) {
+	query := `SELECT id, order_id, created_by, expires_at, revoked_at, created_at
+	          FROM order_share_links WHERE id = $1`
+
+	var l domain.ShareLink
+	err := r.db.QueryRow(ctx, query, id).Scan(&l.ID, &l.OrderID, &l.CreatedBy, &l.ExpiresAt, &l.RevokedAt, &l.CreatedAt)
+	return l, err
+}
+
+func (r *ShareLinkRepository) ListByOrder(ctx context.Context, orderID string) ([]domain.ShareLink, error) {
+	query := `SELECT id, order_id, created_by, expires_at, revoked_at, created_at
+	          FROM order_share_links WHERE order_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []domain.ShareLink
+	for rows.Next() {
+		var l domain.ShareLink
+		if err := rows.Scan(&l.ID, &l.OrderID, &l.CreatedBy, &l.ExpiresAt, &l.RevokedAt, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+func (r *ShareLinkRepository) Revoke(ctx context.Context, id, orderID string) error {
+	query := `UPDATE order_share_links SET revoked_at = NOW()
+	          WHERE id = $1 AND order_id = $2 AND revoked_at IS NULL`
+
+	result, err := r.db.Exec(ctx, query, id, orderID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"tracking/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type StoreRepositoryInterface interface {
+	ListWeeklyHours(ctx context.Context) ([]domain.StoreHours, error)
+	ReplaceWeeklyHours(ctx context.Context, hours []domain.StoreHours) error
+	ListExceptions(ctx context.Context, from, to time.Time) ([]domain.StoreException, error)
+	UpsertException(ctx context.Context, e *domain.StoreException) error
+	DeleteException(ctx context.Context, date string) error
+	GetPause(ctx context.Context) (domain.StorePause, error)
+	SetPause(ctx context.Context, paused bool, reason, adminID string) error
+}
+type StoreRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewStoreRepository(db *pgxpool.Pool) *StoreRepository {
+	return &StoreRepository{db: db}
+}
+
+func (r *StoreRepository) ListWeeklyHours(ctx context.Context) ([]domain.StoreHours, error) {
+	query := `SELECT id, weekday, to_char(opens_at, 'HH24:MI'), to_char(closes_at, 'HH24:MI')
+	          FROM store_hours ORDER BY weekday, opens_at`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hours []domain.StoreHours
+	for rows.Next() {
+		var h domain.StoreHours
+		if err := rows.Scan(&h.ID, &h.Weekday, &h.OpensAt, &h.ClosesAt); err != nil {
+			return nil, err
+		}
+		hours = append(hours, h)
+	}
+	return hours, rows.Err()
+}
+
+// ReplaceWeeklyHours reemplaza la semana tipo completa en una transacción
+func (r *StoreRepository) ReplaceWeeklyHours(ctx context.Context, hours []domain.StoreHours) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM store_hours`); err != nil {
+		return err
+	}
+
+	for _, h := range hours {
+		query := `INSERT INTO store_hours (weekday, opens_at, closes_at) VALUES ($1, $2::time, $3::time)`
+		if _, err := tx.Exec(ctx, query, h.Weekday, h.OpensAt, h.ClosesAt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// ListExceptions devuelve las excepciones con fecha entre from y to (inclusive)
+func (r *StoreRepository) ListExceptions(ctx context.Context, from, to time.Time) ([]domain.StoreException, error) {
+	query := `SELECT to_char(date, 'YYYY-MM-DD'), closed, COALESCE(to_char(opens_at, 'HH24:MI'), ''),
+	                 COALESCE(to_char(closes_at, 'HH24:MI'), ''), COALESCE(reason, ''), created_at
+	          FROM store_exceptions
+	          WHERE date BETWEEN $1::date AND $2::date
+	          ORDER BY date`
+
+	rows, err := r.db.Query(ctx, query, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exceptions []domain.StoreException
+	for rows.Next() {
+		var e domain.StoreException
+		if err := rows.Scan(&e.Date, &e.Closed, &e.OpensAt, &e.ClosesAt, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		exceptions = append(exceptions, e)
+	}
+	return exceptions, rows.Err()
+}
+
+func (r *StoreRepository) UpsertException(ctx context.Context, e *domain.StoreException) error {
+	query := `
+		INSERT INTO store_exceptions (date, closed, opens_at, closes_at, reason)
+		VALUES ($1::date, $2, NULLIF($3, '')::time, NULLIF($4, '')::time, NULLIF($5, ''))
+		ON CONFLICT (date) DO UPDATE SET
+			closed = EXCLUDED.closed,
+			opens_at = EXCLUDED.opens_at,
+			closes_at = EXCLUDED.closes_at,
+			reason = EXCLUDED.reason
+		RETURNING created_at`
+
+	return r.db.QueryRow(ctx, query, e.Date, e.Closed, e.OpensAt, e.ClosesAt, e.Reason).Scan(&e.CreatedAt)
+}
+
+func (r *StoreRepository) DeleteException(ctx context.Context, date string) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM store_exceptions WHERE date = $1::date`, date)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *StoreRepository) GetPause(ctx context.Context) (domain.StorePause, error) {
+	query := `SELECT paused, COALESCE(reason, ''), COALESCE(paused_by::TEXT, ''), paused_at FROM store_pause WHERE id = 1`
+
+	var p domain.StorePause
+	err := r.db.QueryRow(ctx, query).Scan(&p.Paused, &p.Reason, &p.PausedBy, &p.PausedAt)
+	if err == pgx.ErrNoRows {
+		return domain.StorePause{}, nil
+	}
+	return p, err
+}
+
+func (r *StoreRepository) SetPause(ctx context.Context, paused bool, reason, adminID string) error {
+	query := `
+		INSERT INTO store_pause (id, paused, reason, paused_by, paused_at)
+		VALUES (1, $1, NULLIF($2, ''), NULLIF($3, '')::uuid, CASE WHEN $1 THEN NOW() END)
+		ON CONFLICT (id) DO UPDATE SET
+			paused = EXCLUDED.paused,
+			reason = EXCLUDED.reason,
+			paused_by = EXCLUDED.paused_by,
+			paused_at = EXCLUDED.paused_at`
+
+	_, err := r.db.Exec(ctx, query, paused, reason, adminID)
+	return err
+}
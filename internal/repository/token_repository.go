@@ -3,15 +3,47 @@ package repository
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"time"
+	"tracking/internal/domain"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// Resultado de RotateSession
+const (
+	RotateOK       = 1
+	RotateNotFound = 0
+	RotateReused   = -1
+)
+
 type TokenRepositoryInterface interface {
-	StoreRefreshToken(ctx context.Context, userID, refreshToken string, ttl time.Duration) error
-	IsValidRefreshToken(ctx context.Context, userID, refreshToken string) (bool, error)
-	DeleteRefreshToken(ctx context.Context, userID string) error
+	CreateSession(ctx context.Context, s domain.Session, ttl time.Duration) error
+	RotateSession(ctx context.Context, userID, sessionID, presentedJTI, newJTI string, ttl time.Duration) (int, error)
+	ListSessions(ctx context.Context, userID string) ([]domain.Session, error)
+	DeleteSession(ctx context.Context, userID, sessionID string) (bool, error)
+	DeleteAllSessions(ctx context.Context, userID string) error
+
+	// Revocación de access tokens
+	RevokeAccessToken(ctx context.Context, jti string, ttl time.Duration) error
+	GetTokenVersion(ctx context.Context, userID string) (int64, error)
+	BumpTokenVersion(ctx context.Context, userID string) (int64, error)
+	IsAccessTokenRevoked(ctx context.Context, userID, sessionID, jti string, version int64) (bool, error)
+
+	// Tokens de un solo uso para restablecer la contraseña (se guarda el hash, nunca el token)
+	StorePasswordReset(ctx context.Context, userID, tokenHash string, ttl time.Duration) error
+	ConsumePasswordReset(ctx context.Context, tokenHash string) (string, error)
+	RevokePasswordReset(ctx context.Context, userID string) error
+
+	// Tokens de un solo uso para verificar el email
+	StoreEmailVerification(ctx context.Context, userID, tokenHash string, ttl time.Duration) error
+	ConsumeEmailVerification(ctx context.Context, tokenHash string) (string, error)
+
+	// Desafíos de doble factor entre la contraseña y el código TOTP
+	StoreMFAChallenge(ctx context.Context, userID, tokenHash string, ttl time.Duration) error
+	GetMFAChallenge(ctx context.Context, tokenHash string) (string, error)
+	ConsumeMFAChallenge(ctx context.Context, tokenHash string) (string, error)
 }
 
 type TokenRepository struct {
@@ -22,32 +54,285 @@ func NewTokenRepository(rdb *redis.Client) *TokenRepository {
 	return &TokenRepository{rdb: rdb}
 }
 
-func (r *TokenRepository) StoreRefreshToken(ctx context.Context, userID, refreshToken string, ttl time.Duration) error {
-	key := refreshTokenKey(userID)
-	return r.rdb.Set(ctx, key, refreshToken, ttl).Err()
+// CreateSession guarda la sesión (hash con el jti vigente) y la agrega al índice del usuario
+func (r *TokenRepository) CreateSession(ctx context.Context, s domain.Session, ttl time.Duration) error {
+	key := sessionKey(s.ID)
+	pipe := r.rdb.TxPipeline()
+	pipe.HSet(ctx, key, map[string]interface{}{
+		"user_id":      s.UserID,
+		"jti":          s.CurrentJTI,
+		"user_agent":   s.UserAgent,
+		"ip":           s.IP,
+		"created_at":   s.CreatedAt.Unix(),
+		"last_used_at": s.LastUsedAt.Unix(),
+		"expires_at":   s.ExpiresAt.Unix(),
+	})
+	pipe.PExpire(ctx, key, ttl)
+	pipe.SAdd(ctx, userSessionsKey(s.UserID), s.ID)
+	pipe.PExpire(ctx, userSessionsKey(s.UserID), ttl)
+	_, err := pipe.Exec(ctx)
+	return err
 }
 
-func (r *TokenRepository) IsValidRefreshToken(ctx context.Context, userID, refreshToken string) (bool, error) {
-	key := refreshTokenKey(userID)
-	storedToken, err := r.rdb.Get(ctx, key).Result()
-	if err == redis.Nil {
+// Si el jti presentado no es el vigente, el token ya fue rotado: alguien lo está reusando y se
+// revoca la familia completa (la sesión), también para quien tenga el token nuevo.
+var rotateSessionScript = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], 'jti')
+if not current or redis.call('HGET', KEYS[1], 'user_id') ~= ARGV[1] then
+	return 0
+end
+if current ~= ARGV[2] then
+	redis.call('DEL', KEYS[1])
+	redis.call('SREM', KEYS[2], ARGV[6])
+	return -1
+end
+redis.call('HSET', KEYS[1], 'jti', ARGV[3], 'last_used_at', ARGV[4], 'expires_at', ARGV[5])
+redis.call('PEXPIRE', KEYS[1], ARGV[7])
+redis.call('PEXPIRE', KEYS[2], ARGV[7])
+return 1
+`)
+
+// RotateSession reemplaza el jti vigente por newJTI de forma atómica. Devuelve RotateOK,
+// RotateNotFound (sesión vencida o revocada) o RotateReused (la sesión quedó revocada).
+func (r *TokenRepository) RotateSession(ctx context.Context, userID, sessionID, presentedJTI, newJTI string, ttl time.Duration) (int, error) {
+	now := time.Now()
+	keys := []string{sessionKey(sessionID), userSessionsKey(userID)}
+	res, err := rotateSessionScript.Run(ctx, r.rdb, keys,
+		userID, presentedJTI, newJTI, now.Unix(), now.Add(ttl).Unix(), sessionID, ttl.Milliseconds(),
+	).Int()
+	if err != nil {
+		return RotateNotFound, err
+	}
+	return res, nil
+}
+
+// ListSessions devuelve las sesiones activas del usuario, la más reciente primero.
+// De paso limpia del índice las que ya vencieron.
+func (r *TokenRepository) ListSessions(ctx context.Context, userID string) ([]domain.Session, error) {
+	ids, err := r.rdb.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []domain.Session{}
+	for _, id := range ids {
+		fields, err := r.rdb.HGetAll(ctx, sessionKey(id)).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 {
+			r.rdb.SRem(ctx, userSessionsKey(userID), id)
+			continue
+		}
+		sessions = append(sessions, domain.Session{
+			ID:         id,
+			UserID:     fields["user_id"],
+			CurrentJTI: fields["jti"],
+			UserAgent:  fields["user_agent"],
+			IP:         fields["ip"],
+			CreatedAt:  unixField(fields["created_at"]),
+			LastUsedAt: unixField(fields["last_used_at"]),
+			ExpiresAt:  unixField(fields["expires_at"]),
+		})
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+	return sessions, nil
+}
+
+// DeleteSession revoca una sesión del usuario. Devuelve false si no existe o es de otro usuario.
+func (r *TokenRepository) DeleteSession(ctx context.Context, userID, sessionID string) (bool, error) {
+	owner, err := r.rdb.HGet(ctx, sessionKey(sessionID), "user_id").Result()
+	if err == redis.Nil || (err == nil && owner != userID) {
 		return false, nil
 	}
 	if err != nil {
 		return false, err
 	}
 
-	return storedToken == refreshToken, nil
+	pipe := r.rdb.TxPipeline()
+	pipe.Del(ctx, sessionKey(sessionID))
+	pipe.SRem(ctx, userSessionsKey(userID), sessionID)
+	_, err = pipe.Exec(ctx)
+	return err == nil, err
+}
+
+// DeleteAllSessions revoca todas las sesiones (refresh tokens) del usuario
+func (r *TokenRepository) DeleteAllSessions(ctx context.Context, userID string) error {
+	ids, err := r.rdb.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+
+	pipe := r.rdb.TxPipeline()
+	for _, id := range ids {
+		pipe.Del(ctx, sessionKey(id))
+	}
+	pipe.Del(ctx, userSessionsKey(userID))
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// RevokeAccessToken agrega el jti a la denylist hasta que el token vencería de todos modos
+func (r *TokenRepository) RevokeAccessToken(ctx context.Context, jti string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	return r.rdb.Set(ctx, denylistKey(jti), 1, ttl).Err()
+}
+
+// GetTokenVersion devuelve la versión vigente de los tokens del usuario (0 si nunca se revocaron)
+func (r *TokenRepository) GetTokenVersion(ctx context.Context, userID string) (int64, error) {
+	version, err := r.rdb.Get(ctx, tokenVersionKey(userID)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return version, err
+}
+
+// BumpTokenVersion invalida de una vez todos los access tokens emitidos hasta ahora para el usuario
+func (r *TokenRepository) BumpTokenVersion(ctx context.Context, userID string) (int64, error) {
+	return r.rdb.Incr(ctx, tokenVersionKey(userID)).Result()
+}
+
+// IsAccessTokenRevoked es el chequeo que hace AuthMiddleware en cada request. El token también
+// queda revocado si su sesión ya no existe (cerrada desde /me/sessions o por reuso del refresh).
+func (r *TokenRepository) IsAccessTokenRevoked(ctx context.Context, userID, sessionID, jti string, version int64) (bool, error) {
+	pipe := r.rdb.Pipeline()
+	denied := pipe.Exists(ctx, denylistKey(jti))
+	session := pipe.Exists(ctx, sessionKey(sessionID))
+	current := pipe.Get(ctx, tokenVersionKey(userID))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return false, err
+	}
+
+	if denied.Val() > 0 || session.Val() == 0 {
+		return true, nil
+	}
+	currentVersion, err := current.Int64()
+	if err == redis.Nil {
+		return version != 0, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return version != currentVersion, nil
+}
+
+// Tipos de token de un solo uso
+const (
+	oneTimePasswordReset     = "password_reset"
+	oneTimeEmailVerification = "email_verification"
+	oneTimeMFAChallenge      = "mfa_challenge"
+)
+
+// Cada usuario tiene a lo sumo un token vigente de cada tipo: pedir otro invalida el anterior
+var storeOneTimeTokenScript = redis.NewScript(`
+local previous = redis.call('GET', KEYS[2])
+if previous then
+	redis.call('DEL', ARGV[3] .. previous)
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+redis.call('SET', KEYS[2], ARGV[4], 'PX', ARGV[2])
+return 1
+`)
+
+func (r *TokenRepository) StorePasswordReset(ctx context.Context, userID, tokenHash string, ttl time.Duration) error {
+	return r.storeOneTimeToken(ctx, oneTimePasswordReset, userID, tokenHash, ttl)
+}
+
+// ConsumePasswordReset devuelve el usuario del token y lo borra en la misma operación, así no
+// se puede usar dos veces. Devuelve "" si el token no existe o venció.
+func (r *TokenRepository) ConsumePasswordReset(ctx context.Context, tokenHash string) (string, error) {
+	return r.consumeOneTimeToken(ctx, oneTimePasswordReset, tokenHash)
+}
+
+// RevokePasswordReset invalida el link pendiente del usuario, si tiene uno
+func (r *TokenRepository) RevokePasswordReset(ctx context.Context, userID string) error {
+	return r.revokeOneTimeToken(ctx, oneTimePasswordReset, userID)
+}
+
+func (r *TokenRepository) StoreEmailVerification(ctx context.Context, userID, tokenHash string, ttl time.Duration) error {
+	return r.storeOneTimeToken(ctx, oneTimeEmailVerification, userID, tokenHash, ttl)
 }
 
-func (r *TokenRepository) DeleteRefreshToken(ctx context.Context, userID string) error {
-	key := refreshTokenKey(userID)
-	if err := r.rdb.Del(ctx, key).Err(); err != nil {
+// ConsumeEmailVerification funciona igual que ConsumePasswordReset
+func (r *TokenRepository) ConsumeEmailVerification(ctx context.Context, tokenHash string) (string, error) {
+	return r.consumeOneTimeToken(ctx, oneTimeEmailVerification, tokenHash)
+}
+
+func (r *TokenRepository) StoreMFAChallenge(ctx context.Context, userID, tokenHash string, ttl time.Duration) error {
+	return r.storeOneTimeToken(ctx, oneTimeMFAChallenge, userID, tokenHash, ttl)
+}
+
+// GetMFAChallenge devuelve el usuario del desafío sin consumirlo, así un código mal tipeado no
+// obliga a repetir el login. Devuelve "" si no existe o venció.
+func (r *TokenRepository) GetMFAChallenge(ctx context.Context, tokenHash string) (string, error) {
+	userID, err := r.rdb.Get(ctx, oneTimeTokenKey(oneTimeMFAChallenge, tokenHash)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return userID, err
+}
+
+func (r *TokenRepository) ConsumeMFAChallenge(ctx context.Context, tokenHash string) (string, error) {
+	return r.consumeOneTimeToken(ctx, oneTimeMFAChallenge, tokenHash)
+}
+
+func (r *TokenRepository) storeOneTimeToken(ctx context.Context, kind, userID, tokenHash string, ttl time.Duration) error {
+	keys := []string{oneTimeTokenKey(kind, tokenHash), oneTimeTokenUserKey(kind, userID)}
+	return storeOneTimeTokenScript.Run(ctx, r.rdb, keys, userID, ttl.Milliseconds(), oneTimeTokenKey(kind, ""), tokenHash).Err()
+}
+
+func (r *TokenRepository) consumeOneTimeToken(ctx context.Context, kind, tokenHash string) (string, error) {
+	userID, err := r.rdb.GetDel(ctx, oneTimeTokenKey(kind, tokenHash)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return userID, r.rdb.Del(ctx, oneTimeTokenUserKey(kind, userID)).Err()
+}
+
+func (r *TokenRepository) revokeOneTimeToken(ctx context.Context, kind, userID string) error {
+	tokenHash, err := r.rdb.GetDel(ctx, oneTimeTokenUserKey(kind, userID)).Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
 		return err
 	}
-	return nil
+	return r.rdb.Del(ctx, oneTimeTokenKey(kind, tokenHash)).Err()
+}
+
+func sessionKey(sessionID string) string {
+	return fmt.Sprintf("auth:session:%s", sessionID)
+}
+
+func userSessionsKey(userID string) string {
+	return fmt.Sprintf("auth:sessions:%s", userID)
+}
+
+func denylistKey(jti string) string {
+	return fmt.Sprintf("auth:denylist:%s", jti)
+}
+
+func tokenVersionKey(userID string) string {
+	return fmt.Sprintf("auth:token_version:%s", userID)
+}
+
+func oneTimeTokenKey(kind, tokenHash string) string {
+	return fmt.Sprintf("auth:%s:%s", kind, tokenHash)
+}
+
+func oneTimeTokenUserKey(kind, userID string) string {
+	return fmt.Sprintf("auth:%s_user:%s", kind, userID)
 }
 
-func refreshTokenKey(userID string) string {
-	return fmt.Sprintf("auth:refresh:%s", userID)
+func unixField(v string) time.Time {
+	sec, _ := strconv.ParseInt(v, 10, 64)
+	return time.Unix(sec, 0)
 }
@@ -2,20 +2,34 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"tracking/internal/domain"
 
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrEmailTaken indica que el email ya es de otra cuenta
+var ErrEmailTaken = errors.New("email already in use")
+
+// ErrUnknownRole indica que el rol no existe en la tabla roles
+var ErrUnknownRole = errors.New("unknown role")
+
 type UserRepositoryInterface interface {
 	Create(ctx context.Context, u *domain.User) error
 	GetByEmail(ctx context.Context, email string) (*domain.User, error)
 	GetByID(ctx context.Context, id string) (*domain.User, error)
 	AdminExists(ctx context.Context) (bool, error)
-	ListUsers(ctx context.Context, role string, active *bool) ([]domain.User, error)
+	ListUsers(ctx context.Context, filter domain.UserFilter) ([]domain.User, int, error)
 	DeactivateUser(ctx context.Context, userID string) error
+	ReactivateUser(ctx context.Context, userID string) error
+	UpdateRole(ctx context.Context, userID, role string) error
+	UpdateProfile(ctx context.Context, userID, fullName, email string) error
+	OrderStats(ctx context.Context, userID string) (domain.UserOrderStats, error)
+	UpdatePassword(ctx context.Context, userID, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, userID string) error
 }
 type UserRepository struct {
 	db *pgxpool.Pool
@@ -38,7 +52,7 @@ func (r *UserRepository) Create(ctx context.Context, u *domain.User) error {
 }
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
 
-	query := `SELECT id, email, password_hash, full_name, role, is_active FROM users WHERE email = $1 AND is_active = true`
+	query := `SELECT id, email, password_hash, full_name, role, is_active, email_verified_at FROM users WHERE email = $1 AND is_active = true`
 
 	var u domain.User
 	err := r.db.QueryRow(ctx, query, email).Scan(
@@ -48,6 +62,7 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.
 		&u.FullName,
 		&u.Role,
 		&u.IsActive,
+		&u.EmailVerifiedAt,
 	)
 	if err != nil {
 		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
@@ -60,7 +75,7 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.
 }
 
 func (r *UserRepository) GetByID(ctx context.Context, id string) (*domain.User, error) {
-	query := `SELECT id, email, password_hash, full_name, role, is_active, created_at FROM users WHERE id = $1`
+	query := `SELECT id, email, password_hash, full_name, role, is_active, email_verified_at, created_at FROM users WHERE id = $1`
 
 	var u domain.User
 	err := r.db.QueryRow(ctx, query, id).Scan(
@@ -70,6 +85,7 @@ func (r *UserRepository) GetByID(ctx context.Context, id string) (*domain.User,
 		&u.FullName,
 		&u.Role,
 		&u.IsActive,
+		&u.EmailVerifiedAt,
 		&u.CreatedAt,
 	)
 	if err != nil {
@@ -86,39 +102,50 @@ func (r *UserRepository) AdminExists(ctx context.Context) (bool, error) {
 	return exists, err
 }
 
-func (r *UserRepository) ListUsers(ctx context.Context, role string, active *bool) ([]domain.User, error) {
-	query := "SELECT id, email, COALESCE(full_name, ''), role, COALESCE(is_active, true), COALESCE(created_at, NOW()) FROM users"
+// ListUsers devuelve una página de usuarios y el total que cumple los filtros
+func (r *UserRepository) ListUsers(ctx context.Context, filter domain.UserFilter) ([]domain.User, int, error) {
+	where := " WHERE true"
 	var args []interface{}
 
-	if role != "" && active != nil {
-		query += " WHERE role = $1 AND is_active = $2"
-		args = []interface{}{role, *active}
-	} else if role != "" {
-		query += " WHERE role = $1"
-		args = []interface{}{role}
-	} else if active != nil {
-		query += " WHERE is_active = $1"
-		args = []interface{}{*active}
+	if filter.Role != "" {
+		args = append(args, filter.Role)
+		where += " AND role = $" + strconv.Itoa(len(args))
+	}
+	if filter.Active != nil {
+		args = append(args, *filter.Active)
+		where += " AND is_active = $" + strconv.Itoa(len(args))
+	}
+	if filter.Search != "" {
+		args = append(args, "%"+filter.Search+"%")
+		n := strconv.Itoa(len(args))
+		where += " AND (email ILIKE $" + n + " OR full_name ILIKE $" + n + ")"
 	}
 
-	query += " ORDER BY created_at DESC"
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM users"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := "SELECT id, email, COALESCE(full_name, ''), role, COALESCE(is_active, true), email_verified_at, COALESCE(created_at, NOW()) FROM users" +
+		where + " ORDER BY created_at DESC, id LIMIT $" + strconv.Itoa(len(args)-1) + " OFFSET $" + strconv.Itoa(len(args))
 
 	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var users []domain.User
+	users := []domain.User{}
 	for rows.Next() {
 		var u domain.User
-		if err := rows.Scan(&u.ID, &u.Email, &u.FullName, &u.Role, &u.IsActive, &u.CreatedAt); err != nil {
-			return nil, err
+		if err := rows.Scan(&u.ID, &u.Email, &u.FullName, &u.Role, &u.IsActive, &u.EmailVerifiedAt, &u.CreatedAt); err != nil {
+			return nil, 0, err
 		}
 		users = append(users, u)
 	}
 
-	return users, rows.Err()
+	return users, total, rows.Err()
 }
 
 func (r *UserRepository) DeactivateUser(ctx context.Context, userID string) error {
@@ -134,3 +161,109 @@ func (r *UserRepository) DeactivateUser(ctx context.Context, userID string) erro
 
 	return nil
 }
+
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $2 WHERE id = $1`
+	result, err := r.db.Exec(ctx, query, userID, passwordHash)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("usuario no encontrado")
+	}
+
+	return nil
+}
+
+// MarkEmailVerified conserva la fecha de la primera verificación si el link se usa de nuevo
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, userID string) error {
+	query := `UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1`
+	result, err := r.db.Exec(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("usuario no encontrado")
+	}
+
+	return nil
+}
+
+func (r *UserRepository) ReactivateUser(ctx context.Context, userID string) error {
+	query := `UPDATE users SET is_active = true WHERE id = $1 AND is_active = false`
+	result, err := r.db.Exec(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("usuario no encontrado o ya activo")
+	}
+
+	return nil
+}
+
+func (r *UserRepository) UpdateRole(ctx context.Context, userID, role string) error {
+	query := `UPDATE users SET role = $2 WHERE id = $1`
+	result, err := r.db.Exec(ctx, query, userID, role)
+	if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23503" {
+		return ErrUnknownRole
+	}
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("usuario no encontrado")
+	}
+
+	return nil
+}
+
+// UpdateProfile cambia nombre y email; si el email cambia, la cuenta vuelve a quedar sin verificar
+func (r *UserRepository) UpdateProfile(ctx context.Context, userID, fullName, email string) error {
+	query := `UPDATE users SET full_name = $2, email = $3,
+	              email_verified_at = CASE WHEN email = $3 THEN email_verified_at ELSE NULL END
+	          WHERE id = $1`
+	result, err := r.db.Exec(ctx, query, userID, fullName, email)
+	if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("usuario no encontrado")
+	}
+
+	return nil
+}
+
+func (r *UserRepository) OrderStats(ctx context.Context, userID string) (domain.UserOrderStats, error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE customer_id = $1),
+			COUNT(*) FILTER (WHERE customer_id = $1 AND status = 'DELIVERED'),
+			COUNT(*) FILTER (WHERE customer_id = $1 AND status = 'CANCELLED'),
+			COALESCE(SUM(total_price + tip_amount) FILTER (WHERE customer_id = $1 AND status = 'DELIVERED'), 0),
+			MAX(created_at) FILTER (WHERE customer_id = $1),
+			COUNT(*) FILTER (WHERE driver_id = $1 AND status = 'DELIVERED'),
+			MAX(created_at) FILTER (WHERE driver_id = $1 AND status = 'DELIVERED')
+		FROM orders
+		WHERE customer_id = $1 OR driver_id = $1`
+
+	var stats domain.UserOrderStats
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&stats.OrdersPlaced,
+		&stats.OrdersDelivered,
+		&stats.OrdersCancelled,
+		&stats.TotalSpent,
+		&stats.LastOrderAt,
+		&stats.Deliveries,
+		&stats.LastDeliveryAt,
+	)
+	return stats, err
+}
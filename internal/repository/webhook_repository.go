@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"tracking/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WebhookRepositoryInterface interface {
+	CreateSubscription(ctx context.Context, w *domain.WebhookSubscription) error
+	ListSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, id string) (domain.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, w domain.WebhookSubscription) (domain.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id string) error
+	ListActiveForEvent(ctx context.Context, eventType string) ([]domain.WebhookSubscription, error)
+
+	EnqueueDelivery(ctx context.Context, d *domain.WebhookDelivery) (bool, error)
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]domain.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, id string, attempts, statusCode int) error
+	MarkFailed(ctx context.Context, id string, attempts int, statusCode *int, lastError string, nextAttemptAt time.Time, dead bool) error
+	ListDeliveries(ctx context.Context, subscriptionID, status string) ([]domain.WebhookDelivery, error)
+	RequeueDelivery(ctx context.Context, id string) error
+}
+type WebhookRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookRepository(db *pgxpool.Pool) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+const webhookSubscriptionColumns = `id, url, secret, events, is_active, COALESCE(created_by::TEXT, ''), created_at`
+
+const webhookDeliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts,
+	next_attempt_at, last_status_code, last_error, delivered_at, created_at`
+
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, w *domain.WebhookSubscription) error {
+	query := `INSERT INTO webhook_subscriptions (url, secret, events, is_active, created_by)
+	          VALUES ($1, $2, $3, true, NULLIF($4, '')::uuid)
+	          RETURNING id, is_active, created_at`
+
+	return r.db.QueryRow(ctx, query, w.URL, w.Secret, w.Events, w.CreatedBy).Scan(&w.ID, &w.IsActive, &w.CreatedAt)
+}
+
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	query := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions ORDER BY created_at DESC`
+	return r.querySubscriptions(ctx, query)
+}
+
+func (r *WebhookRepository) GetSubscription(ctx context.Context, id string) (domain.WebhookSubscription, error) {
+	query := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE id = $1`
+
+	var w domain.WebhookSubscription
+	err := r.db.QueryRow(ctx, query, id).Scan(&w.ID, &w.URL, &w.Secret, &w.Events, &w.IsActive, &w.CreatedBy, &w.CreatedAt)
+	return w, err
+}
+
+func (r *WebhookRepository) UpdateSubscription(ctx context.Context, w domain.WebhookSubscription) (domain.WebhookSubscription, error) {
+	query := `UPDATE webhook_subscriptions SET url = $1, events = $2, is_active = $3
+	          WHERE id = $4
+	          RETURNING ` + webhookSubscriptionColumns
+
+	var updated domain.WebhookSubscription
+	err := r.db.QueryRow(ctx, query, w.URL, w.Events, w.IsActive, w.ID).Scan(
+		&updated.ID, &updated.URL, &updated.Secret, &updated.Events, &updated.IsActive, &updated.CreatedBy, &updated.CreatedAt,
+	)
+	return updated, err
+}
+
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id string) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *WebhookRepository) ListActiveForEvent(ctx context.Context, eventType string) ([]domain.WebhookSubscription, error) {
+	query := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions
+	          WHERE is_active = true AND $1 = ANY(events)`
+	return r.querySubscriptions(ctx, query, eventType)
+}
+
+func (r *WebhookRepository) querySubscriptions(ctx context.Context, query string, args ...interface{}) ([]domain.WebhookSubscription, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []domain.WebhookSubscription
+	for rows.Next() {
+		var w domain.WebhookSubscription
+		if err := rows.Scan(&w.ID, &w.URL, &w.Secret, &w.Events, &w.IsActive, &w.CreatedBy, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		subs = append(subs, w)
+	}
+	return subs, rows.Err()
+}
+
+// EnqueueDelivery agenda la entrega de un evento. Si el mismo evento ya estaba
+// encolado para la suscripción no se duplica y devuelve false.
+func (r *WebhookRepository) EnqueueDelivery(ctx context.Context, d *domain.WebhookDelivery) (bool, error) {
+	query := `INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+	          VALUES ($1, $2, $3, $4)
+	          ON CONFLICT (subscription_id, event_id) DO NOTHING
+	          RETURNING id, status, next_attempt_at, created_at`
+
+	err := r.db.QueryRow(ctx, query, d.SubscriptionID, d.EventID, d.EventType, d.Payload).Scan(
+		&d.ID, &d.Status, &d.NextAttemptAt, &d.CreatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ClaimDueDeliveries toma las entregas vencidas y corre su próximo intento por lease,
+// para que otra instancia del worker no las procese al mismo tiempo.
+func (r *WebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]domain.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries SET next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'PENDING' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + webhookDeliveryColumns
+
+	return r.queryDeliveries(ctx, query, limit, lease.Seconds())
+}
+
+func (r *WebhookRepository) MarkDelivered(ctx context.Context, id string, attempts, statusCode int) error {
+	query := `UPDATE webhook_deliveries
+	          SET status = 'DELIVERED', attempts = $2, last_status_code = $3, last_error = NULL, delivered_at = NOW()
+	          WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, id, attempts, statusCode)
+	return err
+}
+
+func (r *WebhookRepository) MarkFailed(ctx context.Context, id string, attempts int, statusCode *int, lastError string, nextAttemptAt time.Time, dead bool) error {
+	status := domain.WebhookDeliveryPending
+	if dead {
+		status = domain.WebhookDeliveryDead
+	}
+
+	query := `UPDATE webhook_deliveries
+	          SET status = $2, attempts = $3, last_status_code = $4, last_error = $5, next_attempt_at = $6
+	          WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, id, status, attempts, statusCode, lastError, nextAttemptAt)
+	return err
+}
+
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, subscriptionID, status string) ([]domain.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries
+	          WHERE subscription_id = $1 AND ($2 = '' OR status = $2)
+	          ORDER BY created_at DESC
+	          LIMIT 200`
+	return r.queryDeliveries(ctx, query, subscriptionID, status)
+}
+
+// RequeueDelivery vuelve a poner en cola una entrega que quedó en dead-letter
+func (r *WebhookRepository) RequeueDelivery(ctx context.Context, id string) error {
+	query := `UPDATE webhook_deliveries SET status = 'PENDING', attempts = 0, next_attempt_at = NOW()
+	          WHERE id = $1 AND status = 'DEAD'`
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *WebhookRepository) queryDeliveries(ctx context.Context, query string, args ...interface{}) ([]domain.WebhookDelivery, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []domain.WebhookDelivery
+	for rows.Next() {
+		var d domain.WebhookDelivery
+		if err := rows.Scan(
+			&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.DeliveredAt, &d.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"tracking/internal/domain"
+	"tracking/internal/handler"
+	"tracking/internal/middleware"
+	"tracking/internal/repository"
+	"tracking/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func RegisterAddressRoutes(r *gin.Engine, db *pgxpool.Pool) {
+	repo := repository.NewAddressRepository(db)
+	svc := service.NewAddressService(repo)
+	h := handler.NewAddressHandler(svc)
+
+	addresses := r.Group("/api/me/addresses")
+	addresses.Use(middleware.AuthMiddleware(), middleware.RequirePermission(domain.PermAddressesManage))
+	{
+		addresses.GET("", h.ListAddresses)
+		addresses.POST("", h.CreateAddress)
+		addresses.PATCH("/:id", h.UpdateAddress)
+		addresses.PUT("/:id/pin", h.UpdateAddressPin)
+		addresses.DELETE("/:id", h.DeleteAddress)
+	}
+}
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"tracking/internal/domain"
+	"tracking/internal/handler"
+	"tracking/internal/middleware"
+	"tracking/internal/repository"
+	"tracking/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func RegisterAPIKeyRoutes(r *gin.Engine, db *pgxpool.Pool) {
+	repo := repository.NewAPIKeyRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	svc := service.NewAPIKeyService(repo, userRepo)
+	h := handler.NewAPIKeyHandler(svc)
+
+	// AuthOrAPIKey valida las keys con este servicio
+	middleware.UseAPIKeys(svc)
+
+	admin := r.Group("/api/admin/api-keys")
+	admin.Use(middleware.AuthMiddleware(), middleware.RequirePermission(domain.PermAPIKeysManage))
+	{
+		admin.POST("", h.CreateAPIKey)
+		admin.GET("", h.ListAPIKeys)
+		admin.GET("/:id", h.GetAPIKey)
+		admin.POST("/:id/revoke", h.RevokeAPIKey)
+		admin.GET("/:id/usage", h.GetAPIKeyUsage)
+	}
+}
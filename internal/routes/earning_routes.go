@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"context"
+	"tracking/internal/domain"
+	"tracking/internal/handler"
+	"tracking/internal/middleware"
+	"tracking/internal/repository"
+	"tracking/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func RegisterEarningRoutes(r *gin.Engine, db *pgxpool.Pool) {
+	repo := repository.NewEarningRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	svc := service.NewEarningService(repo, userRepo)
+	h := handler.NewEarningHandler(svc)
+
+	// Completa el libro con las entregas que no tienen movimiento (anteriores al libro)
+	go svc.BackfillDeliveries(context.Background())
+
+	drivers := r.Group("/api/drivers/me")
+	drivers.Use(middleware.AuthMiddleware(), middleware.RequirePermission(domain.PermEarningsReadOwn))
+	{
+		drivers.GET("/earnings", h.GetMyEarnings)
+		drivers.GET("/earnings/statement", h.GetMyStatement)
+	}
+
+	admin := r.Group("/api/admin")
+	admin.Use(middleware.AuthMiddleware())
+	{
+		admin.GET("/drivers/:id/earnings", middleware.RequirePermission(domain.PermEarningsReadAny), h.GetDriverEarnings)
+		admin.GET("/drivers/:id/earnings/statement", middleware.RequirePermission(domain.PermEarningsReadAny), h.GetDriverStatement)
+		admin.POST("/payouts", middleware.RequirePermission(domain.PermPayoutsManage), h.CreatePayoutBatch)
+		admin.GET("/payouts", middleware.RequirePermission(domain.PermPayoutsManage), h.ListPayoutBatches)
+		admin.GET("/payouts/:id", middleware.RequirePermission(domain.PermPayoutsManage), h.GetPayoutBatch)
+	}
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"context"
+	"tracking/internal/repository"
+	"tracking/internal/service"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// StartEventWorkers levanta el relay del outbox y un consumidor del stream por cada
+// canal que reacciona a los eventos de pedidos.
+func StartEventWorkers(db *pgxpool.Pool, rdb *redis.Client) {
+	ctx := context.Background()
+	stream := repository.NewEventStreamRepository(rdb)
+
+	relay := service.NewOutboxRelay(repository.NewOutboxRepository(db), stream)
+	go relay.Run(ctx)
+
+	consumers := map[string]service.EventPublisher{
+		"log":              service.LogEventPublisher{},
+		"tracking":         repository.NewRedisEventPublisher(rdb),
+		"webhooks":         service.NewWebhookService(repository.NewWebhookRepository(db)),
+		"location-cleanup": service.NewLocationCleanupHandler(repository.NewLocationRepository(rdb)),
+		"notifications":    newNotificationService(db),
+	}
+	for group, handler := range consumers {
+		go service.NewStreamConsumer(stream, group, handler).Run(ctx)
+	}
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"tracking/internal/handler"
+	"tracking/internal/middleware"
+	"tracking/internal/repository"
+	"tracking/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+func RegisterGeoRoutes(r *gin.Engine, rdb *redis.Client) {
+	cacheRepo := repository.NewGeoCacheRepository(rdb)
+	limiterRepo := repository.NewRateLimitRepository(rdb)
+	svc := service.NewGeoService(cacheRepo, limiterRepo)
+	h := handler.NewGeoHandler(svc)
+
+	geo := r.Group("/api/geo")
+	geo.Use(middleware.AuthMiddleware())
+	{
+		geo.GET("/autocomplete", h.Autocomplete)
+		geo.GET("/reverse", h.Reverse)
+	}
+}
@@ -0,0 +1,46 @@
+package routes
+
+import (
+	"context"
+	"tracking/internal/domain"
+	"tracking/internal/handler"
+	"tracking/internal/middleware"
+	"tracking/internal/notification"
+	"tracking/internal/repository"
+	"tracking/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func RegisterNotificationRoutes(r *gin.Engine, db *pgxpool.Pool) {
+	svc := newNotificationService(db)
+	h := handler.NewNotificationHandler(svc)
+
+	// Reintento de los envíos que quedaron en PENDING
+	go svc.RunPendingSweeper(context.Background())
+
+	me := r.Group("/api/me")
+	me.Use(middleware.AuthMiddleware())
+	{
+		me.GET("/notification-preferences", h.GetPreferences)
+		me.PUT("/notification-preferences", h.UpdatePreferences)
+		me.GET("/notifications", h.ListMyNotifications)
+	}
+
+	admin := r.Group("/api/admin/notifications")
+	admin.Use(middleware.AuthMiddleware(), middleware.RequirePermission(domain.PermNotifManage))
+	{
+		admin.GET("", h.ListNotifications)
+		admin.GET("/captured-emails", h.ListCapturedEmails)
+	}
+}
+
+func newNotificationService(db *pgxpool.Pool) *service.NotificationService {
+	return service.NewNotificationService(
+		repository.NewNotificationRepository(db),
+		repository.NewOrderRepository(db),
+		repository.NewUserRepository(db),
+		notification.SendersFromEnv(),
+	)
+}
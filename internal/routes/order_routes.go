@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"context"
+	"tracking/internal/domain"
 	"tracking/internal/handler"
 	"tracking/internal/middleware"
 	"tracking/internal/repository"
@@ -13,28 +15,68 @@ import (
 
 func RegisterOrderRoutes(r *gin.Engine, db *pgxpool.Pool, rdb *redis.Client) {
 	// Setup Órdenes (Postgres)
-	orderRepo := repository.NewOrderRepository(db, rdb)
+	orderRepo := repository.NewOrderRepository(db)
 	productRepo := repository.NewProductRepository(db)
 	userRepo := repository.NewUserRepository(db)
-	orderSvc := service.NewOrderService(orderRepo, productRepo, userRepo)
+	eventRepo := repository.NewOrderEventRepository(db)
+	addressRepo := repository.NewAddressRepository(db)
+	storeRepo := repository.NewStoreRepository(db)
+	orderSvc := service.NewOrderService(orderRepo, productRepo, userRepo, eventRepo, addressRepo, storeRepo)
 
 	//  Setup Ubicación (Redis)
 	locRepo := repository.NewLocationRepository(rdb)
-	locSvc := service.NewLocationService(locRepo, orderRepo, userRepo)
+	anomalyRepo := repository.NewLocationAnomalyRepository(db)
+	historyRepo := repository.NewLocationHistoryRepository(db)
+	locSvc := service.NewLocationService(locRepo, orderRepo, userRepo, eventRepo, anomalyRepo, historyRepo)
+
+	// Liberación de pedidos programados cuando se acerca su franja
+	go orderSvc.RunScheduledOrderReleaser(context.Background())
+
+	// Limpieza de posiciones de drivers que dejaron de reportar
+	go locSvc.RunStaleLocationSweeper(context.Background())
 
 	h := handler.NewOrderHandler(orderSvc, locSvc)
 
+	// Setup links públicos de seguimiento
+	shareRepo := repository.NewShareLinkRepository(db)
+	shareSvc := service.NewShareService(shareRepo, orderRepo, locRepo)
+	shareH := handler.NewShareHandler(shareSvc)
+
+	// Rutas abiertas también a integraciones con API key (POS de comercios)
+	integrations := r.Group("/api/orders")
+	{
+		integrations.POST("/", middleware.AuthOrAPIKey(domain.ScopeOrdersWrite), middleware.RequirePermission(domain.PermOrdersCreate), h.Create)
+		integrations.GET("/slots", middleware.AuthOrAPIKey(domain.ScopeOrdersWrite), middleware.RequirePermission(domain.PermOrdersCreate), h.ListDeliverySlots)
+		integrations.PATCH("/:id/cancel", middleware.AuthOrAPIKey(domain.ScopeOrdersWrite), middleware.RequirePermission(domain.PermOrdersCancelOwn, domain.PermOrdersCancelAny), h.Cancel)
+		integrations.GET("/:id/location", middleware.AuthOrAPIKey(domain.ScopeOrdersRead), middleware.RequirePermission(domain.PermOrdersReadOwn, domain.PermOrdersReadAny), h.GetOrderLocation)
+		integrations.GET("/:id/timeline", middleware.AuthOrAPIKey(domain.ScopeOrdersRead), middleware.RequirePermission(domain.PermOrdersReadOwn, domain.PermOrdersReadAny), h.GetTimeline)
+		integrations.GET("/history", middleware.AuthOrAPIKey(domain.ScopeOrdersRead), middleware.RequirePermission(domain.PermOrdersReadOwn, domain.PermOrdersReadAny), h.GetHistory)
+	}
+
 	orders := r.Group("/api/orders")
 	orders.Use(middleware.AuthMiddleware())
 	{
-		orders.POST("/", middleware.RoleBlock("customer"), h.Create)
+		orders.GET("/pending", middleware.RequirePermission(domain.PermOrdersDeliver), h.GetPending)
+		orders.PATCH("/:id/accept", middleware.RequirePermission(domain.PermOrdersDeliver), h.Accept)
+		orders.PATCH("/:id/pickup", middleware.RequirePermission(domain.PermOrdersDeliver), h.PickUp)
+		orders.PATCH("/:id/complete", middleware.RequirePermission(domain.PermOrdersDeliver), h.Complete)
+		orders.POST("/:id/tip", middleware.RequirePermission(domain.PermOrdersTip), h.AddTip)
+		orders.POST("/location", middleware.RequirePermission(domain.PermOrdersDeliver), h.UpdateLocation)
 
-		orders.GET("/pending", middleware.RoleBlock("driver"), h.GetPending)
-		orders.PATCH("/:id/accept", middleware.RoleBlock("driver"), h.Accept)
-		orders.PATCH("/:id/complete", middleware.RoleBlock("driver"), h.Complete)
-		orders.POST("/location", middleware.RoleBlock("driver"), h.UpdateLocation)
+		orders.POST("/:id/share", middleware.RequirePermission(domain.PermOrdersShare), shareH.CreateShareLink)
+		orders.GET("/:id/share", middleware.RequirePermission(domain.PermOrdersShare), shareH.ListShareLinks)
+		orders.DELETE("/:id/share/:linkId", middleware.RequirePermission(domain.PermOrdersShare), shareH.RevokeShareLink)
+	}
 
-		orders.GET("/:id/location", middleware.RoleBlock("customer", "admin"), h.GetOrderLocation)
-		orders.GET("/history", middleware.RoleBlock("customer", "driver", "admin"), h.GetHistory)
+	// Sin AuthMiddleware: el token firmado del link es la credencial
+	public := r.Group("/api/public")
+	{
+		public.GET("/track/:token", shareH.PublicTrack)
+	}
+
+	admin := r.Group("/api/admin")
+	admin.Use(middleware.AuthMiddleware(), middleware.RequirePermission(domain.PermLocationsAudit))
+	{
+		admin.GET("/location-anomalies", h.ListLocationAnomalies)
 	}
 }
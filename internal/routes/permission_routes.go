@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"tracking/internal/domain"
+	"tracking/internal/handler"
+	"tracking/internal/middleware"
+	"tracking/internal/policy"
+	"tracking/internal/repository"
+	"tracking/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func RegisterPermissionRoutes(r *gin.Engine, db *pgxpool.Pool) {
+	repo := repository.NewPermissionRepository(db)
+	svc := service.NewPermissionService(repo)
+	h := handler.NewPermissionHandler(svc)
+
+	// RequirePermission y policy.CanAccess leen los permisos de esta tabla
+	policy.Use(repo)
+
+	admin := r.Group("/api/admin")
+	admin.Use(middleware.AuthMiddleware(), middleware.RequirePermission(domain.PermRolesManage))
+	{
+		admin.GET("/permissions", h.ListPermissions)
+		admin.GET("/roles", h.ListRoles)
+		admin.POST("/roles", h.CreateRole)
+		admin.PUT("/roles/:name/permissions", h.SetRolePermissions)
+		admin.DELETE("/roles/:name", h.DeleteRole)
+	}
+
+	me := r.Group("/api/me")
+	me.Use(middleware.AuthMiddleware())
+	{
+		me.GET("/permissions", h.GetMyPermissions)
+	}
+}
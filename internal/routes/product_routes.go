@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"tracking/internal/domain"
 	"tracking/internal/handler"
 	"tracking/internal/middleware"
 	"tracking/internal/repository"
@@ -16,37 +17,37 @@ func RegisterProductRoutes(r *gin.Engine, db *pgxpool.Pool) {
 	h := handler.NewProductHandler(svc)
 	productGroup := r.Group("/api/products")
 	{
-		// Catalogo visible solo para usuarios autenticados.
+		// Catalogo visible solo para usuarios autenticados (o integraciones con products:read).
 		productGroup.GET("",
-			middleware.AuthMiddleware(),
-			middleware.RoleBlock("customer", "driver", "admin"),
+			middleware.AuthOrAPIKey(domain.ScopeProductsRead),
+			middleware.RequirePermission(domain.PermProductsRead),
 			h.GetProductsHandler,
 		)
 		productGroup.GET("/",
-			middleware.AuthMiddleware(),
-			middleware.RoleBlock("customer", "driver", "admin"),
+			middleware.AuthOrAPIKey(domain.ScopeProductsRead),
+			middleware.RequirePermission(domain.PermProductsRead),
 			h.GetProductsHandler,
 		)
 
-		// Protegido: ABM completo solo para admin.
+		// Protegido: ABM completo solo con products:write.
 		productGroup.POST("/",
 			middleware.AuthMiddleware(),
-			middleware.RoleBlock("admin"),
+			middleware.RequirePermission(domain.PermProductsWrite),
 			h.CreateProductHandler,
 		)
 		productGroup.PUT("/:id",
 			middleware.AuthMiddleware(),
-			middleware.RoleBlock("admin"),
+			middleware.RequirePermission(domain.PermProductsWrite),
 			h.UpdateProductHandler,
 		)
 		productGroup.PATCH("/:id",
 			middleware.AuthMiddleware(),
-			middleware.RoleBlock("admin"),
+			middleware.RequirePermission(domain.PermProductsWrite),
 			h.UpdateProductHandler,
 		)
 		productGroup.DELETE("/:id",
 			middleware.AuthMiddleware(),
-			middleware.RoleBlock("admin"),
+			middleware.RequirePermission(domain.PermProductsWrite),
 			h.DeleteProductHandler,
 		)
 	}
@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"tracking/internal/domain"
+	"tracking/internal/handler"
+	"tracking/internal/middleware"
+	"tracking/internal/repository"
+	"tracking/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func RegisterReviewRoutes(r *gin.Engine, db *pgxpool.Pool) {
+	repo := repository.NewReviewRepository(db)
+	orderRepo := repository.NewOrderRepository(db)
+	eventRepo := repository.NewOrderEventRepository(db)
+	svc := service.NewReviewService(repo, orderRepo, eventRepo)
+	h := handler.NewReviewHandler(svc)
+
+	orders := r.Group("/api/orders")
+	orders.Use(middleware.AuthMiddleware())
+	{
+		orders.POST("/:id/review", middleware.RequirePermission(domain.PermReviewsCreate), h.CreateReview)
+		orders.GET("/:id/review", middleware.RequirePermission(domain.PermReviewsReadOwn, domain.PermReviewsReadAny), h.GetOrderReview)
+	}
+
+	drivers := r.Group("/api/drivers/me")
+	drivers.Use(middleware.AuthMiddleware(), middleware.RequirePermission(domain.PermReviewsReadOwn))
+	{
+		drivers.GET("/rating", h.GetMyRating)
+	}
+
+	admin := r.Group("/api/admin/reviews")
+	admin.Use(middleware.AuthMiddleware(), middleware.RequirePermission(domain.PermReviewsModerate))
+	{
+		admin.GET("", h.ListReviews)
+		admin.PATCH("/:id/hide", h.HideReview)
+		admin.PATCH("/:id/unhide", h.UnhideReview)
+	}
+}
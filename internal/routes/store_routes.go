@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"tracking/internal/domain"
+	"tracking/internal/handler"
+	"tracking/internal/middleware"
+	"tracking/internal/repository"
+	"tracking/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func RegisterStoreRoutes(r *gin.Engine, db *pgxpool.Pool) {
+	repo := repository.NewStoreRepository(db)
+	svc := service.NewStoreService(repo)
+	h := handler.NewStoreHandler(svc)
+
+	// Público: la app lo consulta antes de armar el carrito
+	r.GET("/api/store/status", h.GetStatus)
+
+	admin := r.Group("/api/admin/store")
+	admin.Use(middleware.AuthMiddleware(), middleware.RequirePermission(domain.PermStoreManage))
+	{
+		admin.GET("/hours", h.GetHours)
+		admin.PUT("/hours", h.UpdateHours)
+		admin.GET("/exceptions", h.ListExceptions)
+		admin.PUT("/exceptions", h.SaveException)
+		admin.DELETE("/exceptions/:date", h.DeleteException)
+		admin.POST("/pause", h.Pause)
+		admin.DELETE("/pause", h.Resume)
+	}
+}
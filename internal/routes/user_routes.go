@@ -1,8 +1,10 @@
 package routes
 
 import (
+	"tracking/internal/domain"
 	"tracking/internal/handler"
 	"tracking/internal/middleware"
+	"tracking/internal/notification"
 	"tracking/internal/repository"
 	"tracking/internal/service"
 
@@ -15,22 +17,61 @@ func RegisterUserRoutes(r *gin.Engine, db *pgxpool.Pool, rdb *redis.Client) {
 
 	repo := repository.NewUserRepository(db)
 	tokenRepo := repository.NewTokenRepository(rdb)
-	svc := service.NewUserService(repo, tokenRepo)
+	mfaRepo := repository.NewMFARepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	limiter := repository.NewRateLimitRepository(rdb)
+	attemptRepo := repository.NewLoginAttemptRepository(db)
+	mailer := notification.SendersFromEnv()[notification.ChannelEmail]
+	svc := service.NewUserService(repo, tokenRepo, mfaRepo, apiKeyRepo, limiter, attemptRepo, mailer)
 	h := handler.NewUserHandler(svc)
 
+	// AuthMiddleware rechaza los access tokens revocados (logout, logout-all, usuario desactivado)
+	middleware.UseTokenRevocation(tokenRepo)
+
+	// Claves públicas para que otros servicios verifiquen nuestros tokens
+	r.GET("/.well-known/jwks.json", h.JWKS)
+
 	auth := r.Group("/api/auth")
 	{
 		auth.POST("/register", h.Register)
 		auth.POST("/login", h.Login)
 		auth.POST("/refresh", h.RefreshToken)
 		auth.POST("/bootstrap-admin", h.BootstrapAdmin)
+		auth.POST("/forgot-password", h.ForgotPassword)
+		auth.POST("/reset-password", h.ResetPassword)
+		auth.POST("/verify-email", h.VerifyEmail)
+		auth.POST("/mfa/enroll", h.StartMFAEnrollment)
+		auth.POST("/mfa/verify", h.VerifyMFA)
+		auth.POST("/logout", middleware.AuthMiddleware(), h.Logout)
+		auth.POST("/logout-all", middleware.AuthMiddleware(), h.LogoutAll)
+	}
+
+	me := r.Group("/api/me")
+	me.Use(middleware.AuthMiddleware())
+	{
+		me.GET("/sessions", h.ListSessions)
+		me.DELETE("/sessions/:id", h.RevokeSession)
+		me.POST("/password", h.ChangePassword)
+		me.POST("/email-verification", h.ResendVerification)
+		me.GET("/mfa", h.GetMFAStatus)
+		me.POST("/mfa/totp", h.StartTOTPEnrollment)
+		me.POST("/mfa/totp/confirm", h.ConfirmTOTP)
+		me.POST("/mfa/disable", h.DisableTOTP)
+		me.POST("/mfa/recovery-codes", h.RegenerateRecoveryCodes)
 	}
 
 	admin := r.Group("/api/admin")
-	admin.Use(middleware.AuthMiddleware(), middleware.RoleBlock("admin"))
+	admin.Use(middleware.AuthMiddleware(), middleware.RequirePermission(domain.PermUsersManage))
 	{
 		admin.GET("/users", h.ListUsers)
+		admin.GET("/users/:id", h.GetUser)
+		admin.PATCH("/users/:id", h.UpdateUser)
+		admin.PATCH("/users/:id/role", h.ChangeUserRole)
 		admin.PATCH("/users/:id/deactivate", h.DeactivateUser)
+		admin.PATCH("/users/:id/reactivate", h.ReactivateUser)
+		admin.POST("/users/:id/unlock", h.UnlockUser)
+		admin.POST("/users/:id/mfa/reset", h.ResetMFA)
+		admin.GET("/login-attempts", h.ListLoginAttempts)
 		admin.PATCH("/drivers/:id/deactivate", h.DeactivateUser)
 	}
 }
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"context"
+	"tracking/internal/domain"
+	"tracking/internal/handler"
+	"tracking/internal/middleware"
+	"tracking/internal/repository"
+	"tracking/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func RegisterWebhookRoutes(r *gin.Engine, db *pgxpool.Pool) {
+	repo := repository.NewWebhookRepository(db)
+	svc := service.NewWebhookService(repo)
+	h := handler.NewWebhookHandler(svc)
+
+	// Envío de las entregas pendientes con reintentos
+	go svc.RunDeliveryWorker(context.Background())
+
+	webhooks := r.Group("/api/admin/webhooks")
+	webhooks.Use(middleware.AuthMiddleware(), middleware.RequirePermission(domain.PermWebhooksManage))
+	{
+		webhooks.POST("", h.CreateWebhook)
+		webhooks.GET("", h.ListWebhooks)
+		webhooks.PATCH("/:id", h.UpdateWebhook)
+		webhooks.DELETE("/:id", h.DeleteWebhook)
+		webhooks.GET("/:id/deliveries", h.ListDeliveries)
+		webhooks.POST("/deliveries/:deliveryId/retry", h.RetryDelivery)
+	}
+}
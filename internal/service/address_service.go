@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"tracking/internal/domain"
+	"tracking/internal/dto"
+	"tracking/internal/repository"
+	"tracking/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type AddressServiceInterface interface {
+	CreateAddress(ctx context.Context, userID string, req dto.CreateAddressRequest) (dto.AddressResponse, error)
+	ListAddresses(ctx context.Context, userID string) ([]dto.AddressResponse, error)
+	UpdateAddress(ctx context.Context, userID, addressID string, req dto.UpdateAddressRequest) (dto.AddressResponse, error)
+	UpdatePin(ctx context.Context, userID, addressID string, req dto.UpdateAddressPinRequest) (dto.AddressResponse, error)
+	DeleteAddress(ctx context.Context, userID, addressID string) error
+}
+type AddressService struct {
+	repo repository.AddressRepositoryInterface
+}
+
+func NewAddressService(repo repository.AddressRepositoryInterface) *AddressService {
+	return &AddressService{repo: repo}
+}
+
+// CreateAddress geocodifica la dirección una sola vez; los pedidos que la usen no vuelven a llamar a Nominatim
+func (s *AddressService) CreateAddress(ctx context.Context, userID string, req dto.CreateAddressRequest) (dto.AddressResponse, error) {
+	lat, lng, err := geocodeAddress(ctx, req.Address)
+	if err != nil {
+		slog.Error("error geocoding", "address", req.Address, "error", err)
+		return dto.AddressResponse{}, utils.ErrInvalidAddress
+	}
+
+	address := domain.Address{
+		UserID:       userID,
+		Label:        strings.TrimSpace(req.Label),
+		Address:      req.Address,
+		Apartment:    req.Apartment,
+		Instructions: req.Instructions,
+		Lat:          lat,
+		Lng:          lng,
+	}
+	if err := s.repo.Create(ctx, &address); err != nil {
+		return dto.AddressResponse{}, s.mapError(err)
+	}
+	return utils.ToAddressResponse(address), nil
+}
+
+func (s *AddressService) ListAddresses(ctx context.Context, userID string) ([]dto.AddressResponse, error) {
+	addresses, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		slog.Error("error al listar direcciones", "user_id", userID, "error", err)
+		return nil, utils.ErrInternal
+	}
+	return utils.SliceAddressDomainToResponseDto(addresses), nil
+}
+
+func (s *AddressService) UpdateAddress(ctx context.Context, userID, addressID string, req dto.UpdateAddressRequest) (dto.AddressResponse, error) {
+	address, err := s.repo.GetByID(ctx, addressID, userID)
+	if err != nil {
+		return dto.AddressResponse{}, s.mapError(err)
+	}
+
+	if req.Label != nil {
+		address.Label = strings.TrimSpace(*req.Label)
+	}
+	if req.Apartment != nil {
+		address.Apartment = *req.Apartment
+	}
+	if req.Instructions != nil {
+		address.Instructions = *req.Instructions
+	}
+	// Una dirección nueva se vuelve a geocodificar y descarta el pin manual anterior
+	if req.Address != nil && *req.Address != address.Address {
+		lat, lng, err := geocodeAddress(ctx, *req.Address)
+		if err != nil {
+			slog.Error("error geocoding", "address", *req.Address, "error", err)
+			return dto.AddressResponse{}, utils.ErrInvalidAddress
+		}
+		address.Address = *req.Address
+		address.Lat = lat
+		address.Lng = lng
+		address.PinAdjusted = false
+	}
+
+	if err := s.repo.Update(ctx, &address); err != nil {
+		return dto.AddressResponse{}, s.mapError(err)
+	}
+	return utils.ToAddressResponse(address), nil
+}
+
+// UpdatePin reemplaza las coordenadas geocodificadas por las del pin del mapa
+func (s *AddressService) UpdatePin(ctx context.Context, userID, addressID string, req dto.UpdateAddressPinRequest) (dto.AddressResponse, error) {
+	if !insideRafaela(req.Lat, req.Lng) {
+		return dto.AddressResponse{}, utils.ErrAddressOutsideArea
+	}
+
+	address, err := s.repo.GetByID(ctx, addressID, userID)
+	if err != nil {
+		return dto.AddressResponse{}, s.mapError(err)
+	}
+
+	address.Lat = req.Lat
+	address.Lng = req.Lng
+	address.PinAdjusted = true
+	if err := s.repo.Update(ctx, &address); err != nil {
+		return dto.AddressResponse{}, s.mapError(err)
+	}
+	return utils.ToAddressResponse(address), nil
+}
+
+func (s *AddressService) DeleteAddress(ctx context.Context, userID, addressID string) error {
+	return s.mapError(s.repo.Delete(ctx, addressID, userID))
+}
+
+func (s *AddressService) mapError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, pgx.ErrNoRows):
+		return utils.ErrAddressNotFound
+	case errors.Is(err, repository.ErrDuplicateAddressLabel):
+		return utils.ErrAddressLabelTaken
+	default:
+		slog.Error("error en libreta de direcciones", "error", err)
+		return utils.ErrInternal
+	}
+}
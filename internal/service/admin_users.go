@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"tracking/internal/domain"
+	"tracking/internal/repository"
+	"tracking/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultUserPageSize = 50
+	maxUserPageSize     = 200
+)
+
+// ListUsers pagina el listado; sin limit devuelve 50 usuarios
+func (s *UserService) ListUsers(ctx context.Context, filter domain.UserFilter) (domain.UserPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultUserPageSize
+	}
+	if filter.Limit > maxUserPageSize {
+		filter.Limit = maxUserPageSize
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	filter.Search = strings.TrimSpace(filter.Search)
+
+	users, total, err := s.repo.ListUsers(ctx, filter)
+	if err != nil {
+		slog.Error("error al listar usuarios", "error", err)
+		return domain.UserPage{}, utils.ErrInternal
+	}
+	return domain.UserPage{Users: users, Total: total, Limit: filter.Limit, Offset: filter.Offset}, nil
+}
+
+// GetUserDetail devuelve el usuario con el resumen de sus pedidos
+func (s *UserService) GetUserDetail(ctx context.Context, userID string) (domain.UserDetail, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return domain.UserDetail{}, err
+	}
+
+	stats, err := s.repo.OrderStats(ctx, userID)
+	if err != nil {
+		slog.Error("error al obtener estadísticas de pedidos", "user_id", userID, "error", err)
+		return domain.UserDetail{}, utils.ErrInternal
+	}
+	return domain.UserDetail{User: *user, OrderStats: stats}, nil
+}
+
+// ReactivateUser revierte DeactivateUser. Las sesiones revocadas no vuelven: el usuario inicia sesión de nuevo.
+func (s *UserService) ReactivateUser(ctx context.Context, actorRole, userID string) (*domain.User, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := guardAdminTarget(actorRole, user); err != nil {
+		return nil, err
+	}
+	if user.IsActive {
+		return nil, utils.ErrUserAlreadyActive
+	}
+
+	if err := s.repo.ReactivateUser(ctx, userID); err != nil {
+		slog.Error("error al reactivar usuario", "user_id", userID, "error", err)
+		return nil, utils.ErrInternal
+	}
+	slog.Info("usuario reactivado", "user_id", userID)
+	return s.getUser(ctx, userID)
+}
+
+// ChangeRole asigna un rol existente. Nadie cambia su propio rol y solo un admin da o quita el rol
+// admin; así siempre queda al menos el admin que hizo el cambio. El rol va en el access token, así
+// que se revocan los tokens del usuario para que el cambio aplique en el acto. Si el rol nuevo es
+// privilegiado también se revocan sus API keys: toman el rol actual de la cuenta y no pasan por MFA.
+func (s *UserService) ChangeRole(ctx context.Context, actorUserID, actorRole, targetUserID, role string) (*domain.User, error) {
+	if actorUserID == targetUserID {
+		return nil, utils.ErrCannotChangeOwnRole
+	}
+	user, err := s.getUser(ctx, targetUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	role = strings.ToLower(strings.TrimSpace(role))
+	if user.Role == role {
+		return user, nil
+	}
+	if (role == domain.AdminRole || user.Role == domain.AdminRole) && actorRole != domain.AdminRole {
+		return nil, utils.ErrAdminRoleChange
+	}
+
+	err = s.repo.UpdateRole(ctx, targetUserID, role)
+	if errors.Is(err, repository.ErrUnknownRole) {
+		return nil, utils.ErrRoleNotFound
+	}
+	if err != nil {
+		slog.Error("error al cambiar rol", "user_id", targetUserID, "error", err)
+		return nil, utils.ErrInternal
+	}
+	if err := s.revokeAllTokens(ctx, targetUserID); err != nil {
+		slog.Error("error al revocar tokens después del cambio de rol", "user_id", targetUserID, "error", err)
+		return nil, utils.ErrInternal
+	}
+	if roleIsPrivileged(ctx, role) {
+		revoked, err := s.apiKeyRepo.RevokeByUser(ctx, targetUserID)
+		if err != nil {
+			slog.Error("error al revocar API keys después del cambio de rol", "user_id", targetUserID, "error", err)
+			return nil, utils.ErrInternal
+		}
+		if revoked > 0 {
+			slog.Warn("API keys revocadas por cambio a rol privilegiado", "user_id", targetUserID, "role", role, "count", revoked)
+		}
+	}
+
+	slog.Info("rol de usuario cambiado", "user_id", targetUserID, "from", user.Role, "to", role, "actor_id", actorUserID)
+	user.Role = role
+	return user, nil
+}
+
+// UpdateProfile edita nombre y email. Un email nuevo queda sin verificar, se le manda el link y se
+// invalida cualquier link de restablecer contraseña pendiente, que iba al email anterior.
+func (s *UserService) UpdateProfile(ctx context.Context, actorRole, userID, fullName, email string) (*domain.User, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := guardAdminTarget(actorRole, user); err != nil {
+		return nil, err
+	}
+
+	email = normalizeEmail(email)
+	fullName = strings.TrimSpace(fullName)
+	emailChanged := email != normalizeEmail(user.Email)
+
+	err = s.repo.UpdateProfile(ctx, userID, fullName, email)
+	if errors.Is(err, repository.ErrEmailTaken) {
+		return nil, utils.ErrEmailTaken
+	}
+	if err != nil {
+		slog.Error("error al actualizar usuario", "user_id", userID, "error", err)
+		return nil, utils.ErrInternal
+	}
+
+	user, err = s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if emailChanged {
+		if err := s.tokenRepo.RevokePasswordReset(ctx, userID); err != nil {
+			slog.Error("error al invalidar link de restablecer contraseña", "user_id", userID, "error", err)
+			return nil, utils.ErrInternal
+		}
+		if err := s.sendVerificationEmail(ctx, user); err != nil {
+			slog.Warn("no se pudo enviar el email de verificación", "user_id", userID, "error", err)
+		}
+	}
+	return user, nil
+}
+
+// guardAdminTarget impide que un rol con users:manage que no es admin tome el control de una
+// cuenta admin (cambiarle el email, reiniciarle el doble factor, etc.)
+func guardAdminTarget(actorRole string, target *domain.User) error {
+	if target.Role == domain.AdminRole && actorRole != domain.AdminRole {
+		return utils.ErrAdminTarget
+	}
+	return nil
+}
+
+func (s *UserService) getUser(ctx context.Context, userID string) (*domain.User, error) {
+	user, err := s.repo.GetByID(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, utils.ErrUserNotFound
+	}
+	if err != nil {
+		slog.Error("error al obtener usuario", "user_id", userID, "error", err)
+		return nil, utils.ErrInternal
+	}
+	return user, nil
+}
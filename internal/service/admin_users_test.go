@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"tracking/internal/domain"
+	"tracking/internal/repository"
+	"tracking/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// fakeUserRepo guarda los usuarios en memoria; err simula una caída de la base
+type fakeUserRepo struct {
+	repository.UserRepositoryInterface
+	users       map[string]*domain.User
+	err         error
+	reactivated []string
+}
+
+func (r *fakeUserRepo) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	u, ok := r.users[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	user := *u
+	return &user, nil
+}
+
+func (r *fakeUserRepo) ReactivateUser(ctx context.Context, userID string) error {
+	r.reactivated = append(r.reactivated, userID)
+	r.users[userID].IsActive = true
+	return nil
+}
+
+func (r *fakeUserRepo) UpdateRole(ctx context.Context, userID, role string) error {
+	r.users[userID].Role = role
+	return nil
+}
+
+type fakeTokenRepo struct {
+	repository.TokenRepositoryInterface
+}
+
+func (fakeTokenRepo) BumpTokenVersion(ctx context.Context, userID string) (int64, error) {
+	return 1, nil
+}
+
+func (fakeTokenRepo) DeleteAllSessions(ctx context.Context, userID string) error {
+	return nil
+}
+
+type fakeAPIKeyRepo struct {
+	repository.APIKeyRepositoryInterface
+	key        domain.APIKey
+	revokedFor []string
+}
+
+func (r *fakeAPIKeyRepo) GetByHash(ctx context.Context, keyHash string) (domain.APIKey, error) {
+	return r.key, nil
+}
+
+func (r *fakeAPIKeyRepo) RevokeByUser(ctx context.Context, userID string) (int64, error) {
+	r.revokedFor = append(r.revokedFor, userID)
+	return 1, nil
+}
+
+func TestReactivateUser(t *testing.T) {
+	tests := []struct {
+		name      string
+		actorRole string
+		target    domain.User
+		wantErr   error
+	}{
+		{"admin reactiva un admin", domain.AdminRole, domain.User{ID: "u1", Role: domain.AdminRole}, nil},
+		{"soporte reactiva un cliente", "support", domain.User{ID: "u1", Role: "customer"}, nil},
+		{"soporte no reactiva un admin", "support", domain.User{ID: "u1", Role: domain.AdminRole}, utils.ErrAdminTarget},
+		{"ya activo", domain.AdminRole, domain.User{ID: "u1", Role: "customer", IsActive: true}, utils.ErrUserAlreadyActive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := tt.target
+			repo := &fakeUserRepo{users: map[string]*domain.User{target.ID: &target}}
+			svc := &UserService{repo: repo}
+
+			_, err := svc.ReactivateUser(context.Background(), tt.actorRole, target.ID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, se esperaba %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && len(repo.reactivated) > 0 {
+				t.Error("se reactivó la cuenta pese al error")
+			}
+		})
+	}
+}
+
+func TestGetUserErrors(t *testing.T) {
+	svc := &UserService{repo: &fakeUserRepo{users: map[string]*domain.User{}}}
+	if _, err := svc.getUser(context.Background(), "no-existe"); !errors.Is(err, utils.ErrUserNotFound) {
+		t.Errorf("usuario inexistente: error = %v, se esperaba ErrUserNotFound", err)
+	}
+
+	svc = &UserService{repo: &fakeUserRepo{err: errors.New("sin conexión")}}
+	if _, err := svc.getUser(context.Background(), "u1"); !errors.Is(err, utils.ErrInternal) {
+		t.Errorf("base caída: error = %v, se esperaba ErrInternal", err)
+	}
+}
+
+func TestChangeRoleToAdminRevokesAPIKeys(t *testing.T) {
+	merchant := domain.User{ID: "m1", Role: "merchant", IsActive: true}
+	keys := &fakeAPIKeyRepo{}
+	svc := &UserService{
+		repo:       &fakeUserRepo{users: map[string]*domain.User{merchant.ID: &merchant}},
+		tokenRepo:  fakeTokenRepo{},
+		apiKeyRepo: keys,
+	}
+
+	user, err := svc.ChangeRole(context.Background(), "admin-1", domain.AdminRole, merchant.ID, "admin")
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if user.Role != domain.AdminRole {
+		t.Errorf("rol = %s, se esperaba admin", user.Role)
+	}
+	if len(keys.revokedFor) != 1 || keys.revokedFor[0] != merchant.ID {
+		t.Errorf("keys revocadas para %v, se esperaba [%s]", keys.revokedFor, merchant.ID)
+	}
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"time"
+	"tracking/internal/domain"
+	"tracking/internal/dto"
+	"tracking/internal/repository"
+	"tracking/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type APIKeyServiceInterface interface {
+	CreateAPIKey(ctx context.Context, req dto.CreateAPIKeyRequest, adminID string) (domain.APIKey, string, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]domain.APIKey, error)
+	GetAPIKey(ctx context.Context, id string) (domain.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) (domain.APIKey, error)
+	GetAPIKeyUsage(ctx context.Context, id, from, to string) ([]domain.APIKeyUsage, error)
+	AuthenticateAPIKey(ctx context.Context, key, ip string) (domain.APIKey, error)
+	RecordAPIKeyUsage(ctx context.Context, keyID, ip string, status int)
+}
+type APIKeyService struct {
+	repo     repository.APIKeyRepositoryInterface
+	userRepo repository.UserRepositoryInterface
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepositoryInterface, userRepo repository.UserRepositoryInterface) *APIKeyService {
+	return &APIKeyService{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+// CreateAPIKey emite una key para la cuenta del comercio. La key completa se devuelve solo acá;
+// en la base queda el hash. Las cuentas admin no pueden tener keys.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, req dto.CreateAPIKeyRequest, adminID string) (domain.APIKey, string, error) {
+	if err := validateAPIKeyScopes(req.Scopes); err != nil {
+		return domain.APIKey{}, "", err
+	}
+	allowedIPs, err := normalizeAllowedIPs(req.AllowedIPs)
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return domain.APIKey{}, "", utils.ErrInvalidDate
+	}
+
+	user, err := s.userRepo.GetByID(ctx, req.UserID)
+	if err != nil || !user.IsActive {
+		return domain.APIKey{}, "", utils.ErrUserNotFound
+	}
+	if user.Role == domain.AdminRole {
+		return domain.APIKey{}, "", utils.ErrAPIKeyAdminAccount
+	}
+
+	key, prefix, err := newAPIKey()
+	if err != nil {
+		return domain.APIKey{}, "", utils.ErrInternal
+	}
+
+	apiKey := domain.APIKey{
+		Name:       req.Name,
+		Prefix:     prefix,
+		UserID:     user.ID,
+		UserRole:   user.Role,
+		Scopes:     req.Scopes,
+		AllowedIPs: allowedIPs,
+		ExpiresAt:  req.ExpiresAt,
+		CreatedBy:  adminID,
+	}
+	if err := s.repo.Create(ctx, &apiKey, hashToken(key)); err != nil {
+		slog.Error("error al crear API key", "user_id", user.ID, "error", err)
+		return domain.APIKey{}, "", utils.ErrInternal
+	}
+	slog.Info("API key creada", "api_key_id", apiKey.ID, "user_id", user.ID, "admin_id", adminID)
+	return apiKey, key, nil
+}
+
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	keys, err := s.repo.List(ctx, userID)
+	if err != nil {
+		slog.Error("error al listar API keys", "error", err)
+		return nil, utils.ErrInternal
+	}
+	if keys == nil {
+		keys = []domain.APIKey{}
+	}
+	return keys, nil
+}
+
+func (s *APIKeyService) GetAPIKey(ctx context.Context, id string) (domain.APIKey, error) {
+	key, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.APIKey{}, utils.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		slog.Error("error al obtener API key", "api_key_id", id, "error", err)
+		return domain.APIKey{}, utils.ErrInternal
+	}
+	return key, nil
+}
+
+// RevokeAPIKey corta la key en el acto: el siguiente request con ella ya es rechazado
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id string) (domain.APIKey, error) {
+	key, err := s.repo.Revoke(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.APIKey{}, utils.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		slog.Error("error al revocar API key", "api_key_id", id, "error", err)
+		return domain.APIKey{}, utils.ErrInternal
+	}
+	slog.Info("API key revocada", "api_key_id", id)
+	return key, nil
+}
+
+// GetAPIKeyUsage devuelve los requests por día del período (por defecto, el mes en curso)
+func (s *APIKeyService) GetAPIKeyUsage(ctx context.Context, id, from, to string) ([]domain.APIKeyUsage, error) {
+	if _, err := s.GetAPIKey(ctx, id); err != nil {
+		return nil, err
+	}
+
+	start, end, err := parseEarningsPeriod(from, to, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := s.repo.ListUsage(ctx, id, start, end)
+	if err != nil {
+		slog.Error("error al obtener uso de API key", "api_key_id", id, "error", err)
+		return nil, utils.ErrInternal
+	}
+	if usage == nil {
+		usage = []domain.APIKeyUsage{}
+	}
+	return usage, nil
+}
+
+// AuthenticateAPIKey valida la key que llega en el request. Las keys revocadas, vencidas o de
+// cuentas desactivadas o admin dan ErrInvalidAPIKey; una IP fuera de la lista, ErrAPIKeyIPNotAllowed.
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, key, ip string) (domain.APIKey, error) {
+	if !strings.HasPrefix(key, domain.APIKeyPrefix) {
+		return domain.APIKey{}, utils.ErrInvalidAPIKey
+	}
+
+	apiKey, err := s.repo.GetByHash(ctx, hashToken(key))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.APIKey{}, utils.ErrInvalidAPIKey
+	}
+	if err != nil {
+		slog.Error("error al validar API key", "error", err)
+		return domain.APIKey{}, utils.ErrInternal
+	}
+
+	if apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && time.Now().After(*apiKey.ExpiresAt)) {
+		return domain.APIKey{}, utils.ErrInvalidAPIKey
+	}
+	// La cuenta pasó a admin después de crear la key: el rol se lee en cada request
+	if apiKey.UserRole == domain.AdminRole {
+		slog.Warn("API key de una cuenta admin rechazada", "api_key_id", apiKey.ID, "user_id", apiKey.UserID)
+		return domain.APIKey{}, utils.ErrInvalidAPIKey
+	}
+	if !ipAllowed(apiKey.AllowedIPs, ip) {
+		slog.Warn("API key usada desde una IP no permitida", "api_key_id", apiKey.ID, "ip", ip)
+		return domain.APIKey{}, utils.ErrAPIKeyIPNotAllowed
+	}
+	return apiKey, nil
+}
+
+// RecordAPIKeyUsage suma el request a las estadísticas de la key. Un error acá no afecta la respuesta.
+func (s *APIKeyService) RecordAPIKeyUsage(ctx context.Context, keyID, ip string, status int) {
+	if err := s.repo.RecordUsage(ctx, keyID, ip, status >= 400); err != nil {
+		slog.Error("error al registrar uso de API key", "api_key_id", keyID, "error", err)
+	}
+}
+
+// newAPIKey devuelve la key completa (trk_<prefijo>_<secreto>) y el prefijo visible que la identifica
+func newAPIKey() (string, string, error) {
+	b := make([]byte, 36)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	prefix := domain.APIKeyPrefix + hex.EncodeToString(b[:4])
+	return prefix + "_" + hex.EncodeToString(b[4:]), prefix, nil
+}
+
+func validateAPIKeyScopes(scopes []string) error {
+	seen := map[string]bool{}
+	for _, scope := range scopes {
+		if seen[scope] || !isAPIKeyScope(scope) {
+			return utils.ErrInvalidAPIKeyScope
+		}
+		seen[scope] = true
+	}
+	return nil
+}
+
+func isAPIKeyScope(scope string) bool {
+	for _, s := range domain.APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeAllowedIPs acepta IPs sueltas o rangos CIDR y los guarda como prefijos
+func normalizeAllowedIPs(ips []string) ([]string, error) {
+	out := make([]string, 0, len(ips))
+	for _, raw := range ips {
+		raw = strings.TrimSpace(raw)
+		if prefix, err := netip.ParsePrefix(raw); err == nil {
+			out = append(out, prefix.Masked().String())
+			continue
+		}
+		addr, err := netip.ParseAddr(raw)
+		if err != nil {
+			return nil, utils.ErrInvalidAllowedIP
+		}
+		out = append(out, netip.PrefixFrom(addr, addr.BitLen()).String())
+	}
+	return out, nil
+}
+
+func ipAllowed(allowed []string, ip string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, raw := range allowed {
+		prefix, err := netip.ParsePrefix(raw)
+		if err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
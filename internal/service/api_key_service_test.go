@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"tracking/internal/domain"
+	"tracking/internal/utils"
+)
+
+func TestNormalizeAllowedIPs(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []string
+		want    []string
+		wantErr error
+	}{
+		{"sin restricción", nil, []string{}, nil},
+		{"IPv4 suelta", []string{"203.0.113.7"}, []string{"203.0.113.7/32"}, nil},
+		{"IPv6 suelta", []string{"2001:db8::1"}, []string{"2001:db8::1/128"}, nil},
+		{"CIDR con bits de host", []string{"203.0.113.77/24"}, []string{"203.0.113.0/24"}, nil},
+		{"con espacios", []string{" 10.0.0.0/8 ", "192.0.2.1"}, []string{"10.0.0.0/8", "192.0.2.1/32"}, nil},
+		{"texto inválido", []string{"203.0.113.7", "oficina"}, nil, utils.ErrInvalidAllowedIP},
+		{"máscara inválida", []string{"203.0.113.0/33"}, nil, utils.ErrInvalidAllowedIP},
+		{"vacía", []string{""}, nil, utils.ErrInvalidAllowedIP},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeAllowedIPs(tt.in)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, se esperaba %v", err, tt.wantErr)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("normalizeAllowedIPs = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPAllowed(t *testing.T) {
+	allowed := []string{"203.0.113.0/24", "198.51.100.7/32", "2001:db8::/32"}
+
+	tests := []struct {
+		name    string
+		allowed []string
+		ip      string
+		want    bool
+	}{
+		{"sin lista se permite todo", nil, "192.0.2.1", true},
+		{"dentro del rango", allowed, "203.0.113.200", true},
+		{"IP exacta", allowed, "198.51.100.7", true},
+		{"IP vecina de la exacta", allowed, "198.51.100.8", false},
+		{"fuera de la lista", allowed, "192.0.2.1", false},
+		{"IPv6 dentro del rango", allowed, "2001:db8:1::5", true},
+		{"IPv4 mapeada en IPv6", allowed, "::ffff:203.0.113.9", true},
+		{"IP inválida", allowed, "no-es-ip", false},
+		{"IP vacía", allowed, "", false},
+	}
+
+	for _, tt := range tests {
+		if got := ipAllowed(tt.allowed, tt.ip); got != tt.want {
+			t.Errorf("%s: ipAllowed(%q) = %v, se esperaba %v", tt.name, tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestAuthenticateAPIKeyRejectsAdminAccount(t *testing.T) {
+	key := domain.APIKeyPrefix + "abcd1234_secreto"
+
+	tests := []struct {
+		role    string
+		wantErr error
+	}{
+		{"merchant", nil},
+		{domain.AdminRole, utils.ErrInvalidAPIKey},
+	}
+
+	for _, tt := range tests {
+		svc := NewAPIKeyService(&fakeAPIKeyRepo{key: domain.APIKey{ID: "k1", UserID: "u1", UserRole: tt.role}}, nil)
+		if _, err := svc.AuthenticateAPIKey(context.Background(), key, "203.0.113.7"); !errors.Is(err, tt.wantErr) {
+			t.Errorf("rol %s: error = %v, se esperaba %v", tt.role, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"os"
+	"strconv"
+	"time"
+	"tracking/internal/domain"
+	"tracking/internal/repository"
+	"tracking/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultBasePerDelivery = 800.0
+	defaultPerKm           = 250.0
+
+	// Período máximo de un resumen de ganancias
+	maxEarningsPeriodDays = 366
+)
+
+type EarningServiceInterface interface {
+	GetEarnings(ctx context.Context, driverID, from, to string) (domain.EarningsReport, error)
+	CreatePayoutBatch(ctx context.Context, until *time.Time, adminID string) (domain.PayoutBatch, error)
+	ListPayoutBatches(ctx context.Context) ([]domain.PayoutBatch, error)
+	GetPayoutBatch(ctx context.Context, id string) (domain.PayoutBatch, error)
+}
+type EarningService struct {
+	repo     repository.EarningRepositoryInterface
+	userRepo repository.UserRepositoryInterface
+}
+
+func NewEarningService(repo repository.EarningRepositoryInterface, userRepo repository.UserRepositoryInterface) *EarningService {
+	return &EarningService{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+// GetEarnings arma el resumen del repartidor entre dos fechas AAAA-MM-DD (ambas inclusive, hora
+// local del local). Sin fechas devuelve el mes en curso.
+func (s *EarningService) GetEarnings(ctx context.Context, driverID, from, to string) (domain.EarningsReport, error) {
+	start, end, err := parseEarningsPeriod(from, to, time.Now())
+	if err != nil {
+		return domain.EarningsReport{}, err
+	}
+
+	driver, err := s.userRepo.GetByID(ctx, driverID)
+	if err != nil || driver.Role != "driver" {
+		return domain.EarningsReport{}, utils.ErrDriverNotFound
+	}
+
+	orders, deliveries, err := s.repo.ListOrderEarnings(ctx, driverID, start, end)
+	if err != nil {
+		slog.Error("error al obtener ganancias", "driver_id", driverID, "error", err)
+		return domain.EarningsReport{}, utils.ErrInternal
+	}
+	if orders == nil {
+		orders = []domain.OrderEarning{}
+	}
+
+	report := domain.EarningsReport{
+		DriverID:   driverID,
+		DriverName: driver.FullName,
+		From:       start,
+		To:         end,
+		Orders:     orders,
+	}
+	report.Summary.Deliveries = deliveries
+	for _, o := range orders {
+		report.Summary.DistanceKm += o.DistanceKm
+		report.Summary.BaseAmount += o.BaseAmount
+		report.Summary.DistanceAmount += o.DistanceAmount
+		report.Summary.TipAmount += o.TipAmount
+		report.Summary.Total += o.Total
+		report.Summary.Paid += o.PaidAmount
+	}
+	report.Summary.DistanceKm = roundAmount(report.Summary.DistanceKm)
+	report.Summary.BaseAmount = roundAmount(report.Summary.BaseAmount)
+	report.Summary.DistanceAmount = roundAmount(report.Summary.DistanceAmount)
+	report.Summary.TipAmount = roundAmount(report.Summary.TipAmount)
+	report.Summary.Total = roundAmount(report.Summary.Total)
+	report.Summary.Paid = roundAmount(report.Summary.Paid)
+	report.Summary.Pending = roundAmount(report.Summary.Total - report.Summary.Paid)
+	return report, nil
+}
+
+// CreatePayoutBatch liquida lo pendiente hasta until (por defecto, ahora)
+func (s *EarningService) CreatePayoutBatch(ctx context.Context, until *time.Time, adminID string) (domain.PayoutBatch, error) {
+	now := time.Now()
+	periodEnd := now
+	if until != nil {
+		if until.After(now) {
+			return domain.PayoutBatch{}, utils.ErrInvalidPeriod
+		}
+		periodEnd = *until
+	}
+
+	batch, err := s.repo.CreatePayoutBatch(ctx, periodEnd, adminID)
+	if errors.Is(err, utils.ErrNoEarningsToPay) {
+		return batch, err
+	}
+	if err != nil {
+		slog.Error("error al crear liquidación", "error", err)
+		return batch, utils.ErrInternal
+	}
+	slog.Info("liquidación creada", "batch_id", batch.ID, "total", batch.TotalAmount, "drivers", len(batch.Payouts))
+	return batch, nil
+}
+
+func (s *EarningService) ListPayoutBatches(ctx context.Context) ([]domain.PayoutBatch, error) {
+	batches, err := s.repo.ListPayoutBatches(ctx)
+	if err != nil {
+		slog.Error("error al listar liquidaciones", "error", err)
+		return nil, utils.ErrInternal
+	}
+	if batches == nil {
+		batches = []domain.PayoutBatch{}
+	}
+	return batches, nil
+}
+
+func (s *EarningService) GetPayoutBatch(ctx context.Context, id string) (domain.PayoutBatch, error) {
+	batch, err := s.repo.GetPayoutBatch(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return batch, utils.ErrPayoutNotFound
+	}
+	if err != nil {
+		slog.Error("error al obtener liquidación", "batch_id", id, "error", err)
+		return batch, utils.ErrInternal
+	}
+	return batch, nil
+}
+
+// BackfillDeliveries registra en el libro las entregas que no tienen movimiento, por ejemplo las
+// hechas antes de que existiera. Usan la comisión vigente y la fecha de entrega original.
+func (s *EarningService) BackfillDeliveries(ctx context.Context) {
+	deliveries, err := s.repo.ListUnrecordedDeliveries(ctx)
+	if err != nil {
+		slog.Error("error al buscar entregas sin ganancia registrada", "error", err)
+		return
+	}
+
+	model := getCommissionModel()
+	recorded := 0
+	for _, d := range deliveries {
+		earning := newDeliveryEarning(d.Order, d.Order.DriverID, model)
+		earning.CreatedAt = d.DeliveredAt
+		created, err := s.repo.InsertBackfilledEarning(ctx, earning)
+		if err != nil {
+			slog.Error("error al registrar ganancia de entrega anterior", "order_id", d.Order.ID, "error", err)
+			continue
+		}
+		if created {
+			recorded++
+		}
+	}
+	if recorded > 0 {
+		slog.Info("entregas anteriores registradas en el libro de ganancias", "count", recorded)
+	}
+}
+
+// newDeliveryEarning calcula lo que cobra el repartidor por la entrega: monto fijo, un monto por km
+// en línea recta del local al destino y la propina que se dejó al pedir.
+func newDeliveryEarning(order domain.Order, driverID string, model domain.CommissionModel) *domain.DriverEarning {
+	km := roundAmount(distanceMeters(order.OriginLat, order.OriginLng, order.DestLat, order.DestLng) / 1000)
+	distanceAmount := roundAmount(km * model.PerKm)
+
+	return &domain.DriverEarning{
+		DriverID:       driverID,
+		OrderID:        order.ID,
+		Kind:           domain.EarningKindDelivery,
+		DistanceKm:     km,
+		BaseAmount:     model.BasePerDelivery,
+		DistanceAmount: distanceAmount,
+		TipAmount:      order.TipAmount,
+		Amount:         roundAmount(model.BasePerDelivery + distanceAmount + order.TipAmount),
+	}
+}
+
+// parseEarningsPeriod convierte las fechas del query en el intervalo [start, end)
+func parseEarningsPeriod(from, to string, now time.Time) (time.Time, time.Time, error) {
+	today := startOfDay(now.In(storeLocation))
+
+	start := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, storeLocation)
+	if from != "" {
+		day, err := time.ParseInLocation("2006-01-02", from, storeLocation)
+		if err != nil {
+			return time.Time{}, time.Time{}, utils.ErrInvalidDate
+		}
+		start = day
+	}
+
+	end := today.AddDate(0, 0, 1)
+	if to != "" {
+		day, err := time.ParseInLocation("2006-01-02", to, storeLocation)
+		if err != nil {
+			return time.Time{}, time.Time{}, utils.ErrInvalidDate
+		}
+		end = day.AddDate(0, 0, 1)
+	}
+
+	if !end.After(start) || end.Sub(start) > maxEarningsPeriodDays*24*time.Hour {
+		return time.Time{}, time.Time{}, utils.ErrInvalidPeriod
+	}
+	return start, end, nil
+}
+
+func roundAmount(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// getCommissionModel lee la comisión vigente. Se aplica a las entregas nuevas; las ya
+// registradas conservan la comisión con la que se calcularon.
+func getCommissionModel() domain.CommissionModel {
+	return domain.CommissionModel{
+		BasePerDelivery: getEnvAmount("EARNINGS_BASE_PER_DELIVERY", defaultBasePerDelivery),
+		PerKm:           getEnvAmount("EARNINGS_PER_KM", defaultPerKm),
+	}
+}
+
+func getEnvAmount(key string, fallback float64) float64 {
+	v, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err == nil && v >= 0 {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"tracking/internal/domain"
+	"tracking/internal/utils"
+)
+
+func TestNewDeliveryEarning(t *testing.T) {
+	model := domain.CommissionModel{BasePerDelivery: 800, PerKm: 250}
+
+	tests := []struct {
+		name         string
+		order        domain.Order
+		wantKm       float64
+		wantDistance float64
+		wantAmount   float64
+	}{
+		{
+			name:         "sin distancia ni propina",
+			order:        domain.Order{ID: "o1", OriginLat: -31.25, OriginLng: -61.49, DestLat: -31.25, DestLng: -61.49},
+			wantKm:       0,
+			wantDistance: 0,
+			wantAmount:   800,
+		},
+		{
+			name:         "un km con propina",
+			order:        domain.Order{ID: "o2", OriginLat: 0, OriginLng: 0, DestLat: 0.008993, DestLng: 0, TipAmount: 300},
+			wantKm:       1,
+			wantDistance: 250,
+			wantAmount:   1350,
+		},
+		{
+			name:         "km redondeados a dos decimales",
+			order:        domain.Order{ID: "o3", OriginLat: 0, OriginLng: 0, DestLat: 0.0234, DestLng: 0},
+			wantKm:       2.6,
+			wantDistance: 650,
+			wantAmount:   1450,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newDeliveryEarning(tt.order, "driver-1", model)
+			if e.Kind != domain.EarningKindDelivery || e.DriverID != "driver-1" || e.OrderID != tt.order.ID {
+				t.Errorf("movimiento mal armado: %+v", e)
+			}
+			if e.DistanceKm != tt.wantKm || e.DistanceAmount != tt.wantDistance || e.Amount != tt.wantAmount {
+				t.Errorf("km/monto km/total = %v/%v/%v, se esperaba %v/%v/%v",
+					e.DistanceKm, e.DistanceAmount, e.Amount, tt.wantKm, tt.wantDistance, tt.wantAmount)
+			}
+			if e.BaseAmount != model.BasePerDelivery || e.TipAmount != tt.order.TipAmount {
+				t.Errorf("base/propina = %v/%v", e.BaseAmount, e.TipAmount)
+			}
+		})
+	}
+}
+
+func TestGetCommissionModel(t *testing.T) {
+	t.Setenv("EARNINGS_BASE_PER_DELIVERY", "1000.50")
+	t.Setenv("EARNINGS_PER_KM", "-5")
+
+	model := getCommissionModel()
+	if model.BasePerDelivery != 1000.50 {
+		t.Errorf("BasePerDelivery = %v, se esperaba 1000.50", model.BasePerDelivery)
+	}
+	if model.PerKm != defaultPerKm {
+		t.Errorf("un monto negativo debería ignorarse, PerKm = %v", model.PerKm)
+	}
+}
+
+func TestParseEarningsPeriod(t *testing.T) {
+	now := at(19, 15, 0)
+
+	tests := []struct {
+		name               string
+		from, to           string
+		wantStart, wantEnd time.Time
+		wantErr            error
+	}{
+		{"mes en curso por defecto", "", "", at(1, 0, 0), at(20, 0, 0), nil},
+		{"un día", "2026-10-05", "2026-10-05", at(5, 0, 0), at(6, 0, 0), nil},
+		{"solo desde", "2026-10-10", "", at(10, 0, 0), at(20, 0, 0), nil},
+		{"fecha inválida", "05/10/2026", "", time.Time{}, time.Time{}, utils.ErrInvalidDate},
+		{"hasta anterior a desde", "2026-10-10", "2026-10-09", time.Time{}, time.Time{}, utils.ErrInvalidPeriod},
+		{"más de un año", "2025-01-01", "2026-10-01", time.Time{}, time.Time{}, utils.ErrInvalidPeriod},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := parseEarningsPeriod(tt.from, tt.to, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, se esperaba %v", err, tt.wantErr)
+			}
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("período = [%v, %v), se esperaba [%v, %v)", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestRoundAmount(t *testing.T) {
+	tests := map[float64]float64{
+		1.234:     1.23,
+		1.235001:  1.24,
+		10.0049:   10,
+		0.1 + 0.2: 0.3,
+		-2.555001: -2.56,
+	}
+	for in, want := range tests {
+		if got := roundAmount(in); got != want {
+			t.Errorf("roundAmount(%v) = %v, se esperaba %v", in, got, want)
+		}
+	}
+}
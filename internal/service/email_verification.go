@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+	"tracking/internal/domain"
+	"tracking/internal/notification"
+	"tracking/internal/utils"
+)
+
+const (
+	defaultEmailVerificationTTL = 48 * time.Hour
+	defaultEmailVerificationURL = "http://localhost:8081/verify-email"
+
+	// Reenvíos del link de verificación por usuario y por hora
+	verificationResendLimitPerHour = 3
+)
+
+// VerifyEmail marca el email como verificado con el token del link. El token sirve una sola vez.
+func (s *UserService) VerifyEmail(ctx context.Context, token string) error {
+	userID, err := s.tokenRepo.ConsumeEmailVerification(ctx, hashToken(token))
+	if err != nil {
+		slog.Error("error al validar token de verificación", "error", err)
+		return utils.ErrInternal
+	}
+	if userID == "" {
+		return utils.ErrInvalidVerificationToken
+	}
+
+	if err := s.repo.MarkEmailVerified(ctx, userID); err != nil {
+		slog.Error("error al marcar email verificado", "user_id", userID, "error", err)
+		return utils.ErrInternal
+	}
+	slog.Info("email verificado", "user_id", userID)
+	return nil
+}
+
+// ResendVerification manda otro link de verificación e invalida el anterior
+func (s *UserService) ResendVerification(ctx context.Context, userID string) error {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return utils.ErrUserNotFound
+	}
+	if user.EmailVerifiedAt != nil {
+		return utils.ErrEmailAlreadyVerified
+	}
+
+	count, resetIn, err := s.guard.limiter.Hit(ctx, "verify-email:"+userID, time.Hour)
+	if err != nil {
+		slog.Error("error al contar reenvíos de verificación", "user_id", userID, "error", err)
+		return utils.ErrInternal
+	}
+	if count > verificationResendLimitPerHour {
+		return &utils.RateLimitError{RetryAfter: resetIn, Err: utils.ErrTooManyVerificationEmails}
+	}
+
+	return s.sendVerificationEmail(ctx, user)
+}
+
+func (s *UserService) sendVerificationEmail(ctx context.Context, user *domain.User) error {
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return utils.ErrInternal
+	}
+	if err := s.tokenRepo.StoreEmailVerification(ctx, user.ID, tokenHash, getEmailVerificationTTL()); err != nil {
+		slog.Error("error al guardar token de verificación", "user_id", user.ID, "error", err)
+		return utils.ErrInternal
+	}
+
+	subject, body, err := notification.Render(notification.TemplateEmailVerification, notification.DefaultLocale, notification.TemplateData{
+		Name: user.FullName,
+		Link: linkWithToken(getEmailVerificationURL(), token),
+	})
+	if err != nil {
+		return utils.ErrInternal
+	}
+	if err := s.mailer.Send(ctx, notification.Message{To: user.Email, Subject: subject, Body: body, Sensitive: true}); err != nil {
+		slog.Error("error al enviar email de verificación", "user_id", user.ID, "error", err)
+		return utils.ErrInternal
+	}
+	return nil
+}
+
+func getEmailVerificationTTL() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv("EMAIL_VERIFICATION_TTL_HOURS"))
+	if err == nil && hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return defaultEmailVerificationTTL
+}
+
+func getEmailVerificationURL() string {
+	if u := os.Getenv("EMAIL_VERIFICATION_URL"); u != "" {
+		return u
+	}
+	return defaultEmailVerificationURL
+}
@@ -0,0 +1,21 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"tracking/internal/domain"
+)
+
+// EventPublisher es cualquier canal por el que se avisan los eventos de un pedido.
+// Cada uno consume el stream de eventos con su propio consumer group (ver StreamConsumer).
+type EventPublisher interface {
+	Publish(ctx context.Context, event domain.OrderEvent) error
+}
+
+// LogEventPublisher deja los eventos en el log, útil en desarrollo
+type LogEventPublisher struct{}
+
+func (LogEventPublisher) Publish(ctx context.Context, event domain.OrderEvent) error {
+	slog.Info("evento de pedido", "order_id", event.OrderID, "type", event.Type, "actor_id", event.ActorID)
+	return nil
+}
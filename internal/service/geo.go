@@ -0,0 +1,52 @@
+package service
+
+import (
+	"math"
+	"os"
+	"strconv"
+)
+
+const (
+	earthRadiusMeters      = 6371000.0
+	defaultGeofenceRadiusM = 100.0
+	defaultAvgSpeedKmh     = 25.0
+)
+
+// distanceMeters calcula la distancia en metros entre dos coordenadas (haversine)
+func distanceMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
+
+func getGeofenceRadius() float64 {
+	meters, err := strconv.ParseFloat(os.Getenv("GEOFENCE_RADIUS_METERS"), 64)
+	if err == nil && meters > 0 {
+		return meters
+	}
+	return defaultGeofenceRadiusM
+}
+
+// estimateETAMinutes estima los minutos para recorrer la distancia a la velocidad promedio de reparto
+func estimateETAMinutes(distance float64) int {
+	minutes := distance / 1000 / getAverageSpeed() * 60
+	return int(math.Ceil(minutes))
+}
+
+// coarseCoordinate redondea a 3 decimales (~100 m) para no exponer la posición exacta
+func coarseCoordinate(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
+
+func getAverageSpeed() float64 {
+	kmh, err := strconv.ParseFloat(os.Getenv("DELIVERY_AVG_SPEED_KMH"), 64)
+	if err == nil && kmh > 0 {
+		return kmh
+	}
+	return defaultAvgSpeedKmh
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"tracking/internal/dto"
+	"tracking/internal/repository"
+	"tracking/internal/utils"
+)
+
+const (
+	defaultGeoRateLimit = 30
+	geoRateLimitWindow  = time.Minute
+	defaultGeoCacheTTL  = 24 * time.Hour
+	autocompleteLimit   = 5
+)
+
+type GeoServiceInterface interface {
+	Autocomplete(ctx context.Context, userID, query string) ([]dto.GeoSuggestionResponse, error)
+	Reverse(ctx context.Context, userID string, lat, lng float64) (dto.GeoSuggestionResponse, error)
+}
+
+// GeoService expone el geocoding de Nominatim a la app con cache en Redis y límite por usuario
+type GeoService struct {
+	cache   repository.GeoCacheRepositoryInterface
+	limiter repository.RateLimitRepositoryInterface
+}
+
+func NewGeoService(cache repository.GeoCacheRepositoryInterface, limiter repository.RateLimitRepositoryInterface) *GeoService {
+	return &GeoService{cache: cache, limiter: limiter}
+}
+
+func (s *GeoService) Autocomplete(ctx context.Context, userID, query string) ([]dto.GeoSuggestionResponse, error) {
+	normalized := strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	cacheKey := "search:" + normalized
+
+	suggestions := []dto.GeoSuggestionResponse{}
+	if s.fromCache(ctx, cacheKey, &suggestions) {
+		return suggestions, nil
+	}
+
+	if err := s.checkRateLimit(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	results, err := searchNominatim(ctx, normalized, autocompleteLimit)
+	if err != nil {
+		slog.Error("error en autocompletado de direcciones", "query", normalized, "error", err)
+		return nil, utils.ErrGeocodingUnavailable
+	}
+
+	for _, r := range results {
+		lat, lng, err := r.coordinates()
+		if err != nil {
+			continue
+		}
+		suggestions = append(suggestions, dto.GeoSuggestionResponse{
+			Address:     r.shortAddress(),
+			DisplayName: r.DisplayName,
+			Lat:         lat,
+			Lng:         lng,
+		})
+	}
+
+	s.toCache(ctx, cacheKey, suggestions)
+	return suggestions, nil
+}
+
+func (s *GeoService) Reverse(ctx context.Context, userID string, lat, lng float64) (dto.GeoSuggestionResponse, error) {
+	if !insideRafaela(lat, lng) {
+		return dto.GeoSuggestionResponse{}, utils.ErrAddressOutsideArea
+	}
+
+	// ~1 metro de precisión: pines casi iguales comparten la entrada de cache
+	cacheKey := fmt.Sprintf("reverse:%.5f,%.5f", lat, lng)
+
+	var suggestion dto.GeoSuggestionResponse
+	if s.fromCache(ctx, cacheKey, &suggestion) {
+		return suggestion, nil
+	}
+
+	if err := s.checkRateLimit(ctx, userID); err != nil {
+		return suggestion, err
+	}
+
+	result, err := reverseNominatim(ctx, lat, lng)
+	if err != nil {
+		slog.Error("error en reverse geocoding", "lat", lat, "lng", lng, "error", err)
+		return suggestion, utils.ErrGeocodingUnavailable
+	}
+
+	suggestion = dto.GeoSuggestionResponse{
+		Address:     result.shortAddress(),
+		DisplayName: result.DisplayName,
+		Lat:         lat,
+		Lng:         lng,
+	}
+	s.toCache(ctx, cacheKey, suggestion)
+	return suggestion, nil
+}
+
+// checkRateLimit solo se aplica a las consultas que llegan a Nominatim; los hits de cache son libres
+func (s *GeoService) checkRateLimit(ctx context.Context, userID string) error {
+	count, resetIn, err := s.limiter.Hit(ctx, "geo:"+userID, geoRateLimitWindow)
+	if err != nil {
+		// Si Redis falla no bloqueamos al usuario; el throttle global sigue protegiendo a Nominatim
+		slog.Warn("error al aplicar rate limit de geocoding", "user_id", userID, "error", err)
+		return nil
+	}
+	if count > int64(getGeoRateLimit()) {
+		return &utils.RateLimitError{RetryAfter: resetIn, Err: utils.ErrGeoRateLimited}
+	}
+	return nil
+}
+
+func (s *GeoService) fromCache(ctx context.Context, key string, out interface{}) bool {
+	found, err := s.cache.Get(ctx, key, out)
+	if err != nil {
+		slog.Warn("error al leer cache de geocoding", "key", key, "error", err)
+		return false
+	}
+	return found
+}
+
+func (s *GeoService) toCache(ctx context.Context, key string, value interface{}) {
+	if err := s.cache.Set(ctx, key, value, getGeoCacheTTL()); err != nil {
+		slog.Warn("error al guardar cache de geocoding", "key", key, "error", err)
+	}
+}
+
+func getGeoRateLimit() int {
+	limit, err := strconv.Atoi(os.Getenv("GEO_RATE_LIMIT_PER_MINUTE"))
+	if err == nil && limit > 0 {
+		return limit
+	}
+	return defaultGeoRateLimit
+}
+
+func getGeoCacheTTL() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv("GEO_CACHE_TTL_HOURS"))
+	if err == nil && hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return defaultGeoCacheTTL
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+	"tracking/internal/domain"
+	"tracking/internal/repository"
+)
+
+func TestDistanceMeters(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lng1, lat2, lng2 float64
+		want                   float64
+	}{
+		{"mismo punto", -31.25, -61.49, -31.25, -61.49, 0},
+		{"un grado sobre el ecuador", 0, 0, 0, 1, 111194.93},
+		{"un grado de latitud", 0, 0, 1, 0, 111194.93},
+		{"cuadra en Rafaela", -31.2500, -61.4900, -31.2509, -61.4900, 100.08},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := distanceMeters(tt.lat1, tt.lng1, tt.lat2, tt.lng2)
+			if math.Abs(got-tt.want) > 0.5 {
+				t.Errorf("distanceMeters() = %.2f, se esperaba %.2f", got, tt.want)
+			}
+			if back := distanceMeters(tt.lat2, tt.lng2, tt.lat1, tt.lng1); math.Abs(back-got) > 1e-6 {
+				t.Errorf("la distancia no es simétrica: %.6f vs %.6f", got, back)
+			}
+		})
+	}
+}
+
+// fakeEventRepo guarda los eventos creados; el resto de la interfaz no se usa
+type fakeEventRepo struct {
+	repository.OrderEventRepositoryInterface
+	events []domain.OrderEvent
+}
+
+func (r *fakeEventRepo) CreateOnce(ctx context.Context, e *domain.OrderEvent) (bool, error) {
+	r.events = append(r.events, *e)
+	return true, nil
+}
+
+func TestCheckGeofences(t *testing.T) {
+	t.Setenv("GEOFENCE_RADIUS_METERS", "")
+
+	order := domain.Order{
+		ID:        "order-1",
+		OriginLat: -31.2500, OriginLng: -61.4900,
+		DestLat: -31.2600, DestLng: -61.5000,
+	}
+
+	tests := []struct {
+		name     string
+		lat, lng float64
+		want     []string
+	}{
+		{"en el local", -31.2500, -61.4900, []string{domain.OrderEventArrivedAtPickup}},
+		{"a 50 m del cliente", -31.26045, -61.5000, []string{domain.OrderEventArrivedAtDropoff}},
+		{"a 150 m del cliente", -31.26135, -61.5000, nil},
+		{"en el camino", -31.2550, -61.4950, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := &fakeEventRepo{}
+			svc := &LocationService{eventRepo: events}
+
+			svc.checkGeofences(context.Background(), order, "driver-1", tt.lat, tt.lng)
+
+			if len(events.events) != len(tt.want) {
+				t.Fatalf("se emitieron %d eventos, se esperaban %d", len(events.events), len(tt.want))
+			}
+			for i, e := range events.events {
+				if e.Type != tt.want[i] {
+					t.Errorf("evento %d = %s, se esperaba %s", i, e.Type, tt.want[i])
+				}
+				if e.OrderID != order.ID || e.ActorID != "driver-1" {
+					t.Errorf("evento con pedido %q y actor %q", e.OrderID, e.ActorID)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckGeofencesCustomRadius(t *testing.T) {
+	t.Setenv("GEOFENCE_RADIUS_METERS", "200")
+
+	events := &fakeEventRepo{}
+	svc := &LocationService{eventRepo: events}
+	order := domain.Order{ID: "order-1", OriginLat: 0, OriginLng: 0, DestLat: 1, DestLng: 1}
+
+	// ~150 m al norte del local: fuera del radio por defecto, dentro del configurado
+	svc.checkGeofences(context.Background(), order, "driver-1", 0.00135, 0)
+
+	if len(events.events) != 1 || events.events[0].Type != domain.OrderEventArrivedAtPickup {
+		t.Fatalf("eventos = %+v, se esperaba ARRIVED_AT_PICKUP", events.events)
+	}
+}
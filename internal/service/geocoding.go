@@ -1,52 +1,190 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 // Este struct solo se usa aca para mapear la respuesta de la API externa
 type GeocodeResponse struct {
-	Lat string `json:"lat"`
-	Lon string `json:"lon"`
+	Lat         string         `json:"lat"`
+	Lon         string         `json:"lon"`
+	DisplayName string         `json:"display_name"`
+	Address     nominatimParts `json:"address"`
 }
 
+// Partes de la dirección que devuelve Nominatim con addressdetails=1
+type nominatimParts struct {
+	Road        string `json:"road"`
+	HouseNumber string `json:"house_number"`
+	Suburb      string `json:"suburb"`
+}
+
+// Área de Rafaela: las búsquedas se limitan a esta caja y los pines manuales deben caer dentro
+const (
+	rafaelaMinLat = -31.33
+	rafaelaMaxLat = -31.19
+	rafaelaMinLng = -61.57
+	rafaelaMaxLng = -61.41
+)
+
+const nominatimBaseURL = "https://nominatim.openstreetmap.org"
+
+// La política de uso de Nominatim permite como máximo un request por segundo por aplicación
+const nominatimMinInterval = time.Second
+
+var nominatimThrottle = struct {
+	sync.Mutex
+	last time.Time
+}{}
+
 // GetCoordinates es un método del service para mantener la coherencia
-func (s *OrderService) GetCoordinates(address string) (float64, float64, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
-	
+func (s *OrderService) GetCoordinates(ctx context.Context, address string) (float64, float64, error) {
+	return geocodeAddress(ctx, address)
+}
+
+// geocodeAddress busca las coordenadas de una dirección de Rafaela en Nominatim
+func geocodeAddress(ctx context.Context, address string) (float64, float64, error) {
+	results, err := searchNominatim(ctx, address, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if len(results) == 0 {
+		return 0, 0, errors.New("no se encontró la dirección en Rafaela")
+	}
+
+	lat, lon, err := results[0].coordinates()
+	if err != nil {
+		return 0, 0, err
+	}
+	return lat, lon, nil
+}
+
+// searchNominatim busca direcciones dentro de Rafaela. Lo usan el geocoding de pedidos y el autocompletado.
+func searchNominatim(ctx context.Context, text string, limit int) ([]GeocodeResponse, error) {
 	// Filtro por Rafaela para que la búsqueda sea precisa
-	query := fmt.Sprintf("%s, Rafaela, Argentina", address)
-	apiURL := fmt.Sprintf("https://nominatim.openstreetmap.org/search?q=%s&format=json&limit=1", url.QueryEscape(query))
+	params := url.Values{}
+	params.Set("q", fmt.Sprintf("%s, Rafaela, Argentina", text))
+	params.Set("format", "json")
+	params.Set("addressdetails", "1")
+	params.Set("limit", strconv.Itoa(limit))
+	params.Set("viewbox", rafaelaViewbox())
+	params.Set("bounded", "1")
 
-	req, _ := http.NewRequest("GET", apiURL, nil)
+	var results []GeocodeResponse
+	if err := nominatimGet(ctx, "/search", params, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// reverseNominatim devuelve la dirección más cercana a un punto
+func reverseNominatim(ctx context.Context, lat, lng float64) (GeocodeResponse, error) {
+	params := url.Values{}
+	params.Set("lat", strconv.FormatFloat(lat, 'f', 6, 64))
+	params.Set("lon", strconv.FormatFloat(lng, 'f', 6, 64))
+	params.Set("format", "json")
+	params.Set("addressdetails", "1")
+
+	var result GeocodeResponse
+	if err := nominatimGet(ctx, "/reverse", params, &result); err != nil {
+		return result, err
+	}
+	if result.DisplayName == "" {
+		return result, errors.New("no se encontró una dirección para esa ubicación")
+	}
+	return result, nil
+}
+
+func nominatimGet(ctx context.Context, path string, params url.Values, out interface{}) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", nominatimBaseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
 	req.Header.Set("User-Agent", "TrackingApp-Zoe-StudentProject") // Requerido por Nominatim
+	req.Header.Set("Accept-Language", "es")
+
+	if err := waitNominatimTurn(ctx); err != nil {
+		return err
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return 0, 0, err
+		return err
 	}
 	defer resp.Body.Close()
 
-	var results []GeocodeResponse
-	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
-		return 0, 0, err
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("nominatim respondió %d", resp.StatusCode)
 	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
 
-	if len(results) == 0 {
-		return 0, 0, errors.New("no se encontró la dirección en Rafaela")
+// waitNominatimTurn espacia los requests salientes para no pasar el límite de Nominatim. Reserva
+// el próximo turno bajo el lock y espera afuera, así un request cancelado no frena a los demás.
+func waitNominatimTurn(ctx context.Context) error {
+	wait := reserveNominatimTurn(time.Now())
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserveNominatimTurn anota el turno siguiente al último reservado y devuelve cuánto falta para él
+func reserveNominatimTurn(now time.Time) time.Duration {
+	nominatimThrottle.Lock()
+	defer nominatimThrottle.Unlock()
+
+	turn := nominatimThrottle.last.Add(nominatimMinInterval)
+	if turn.Before(now) {
+		turn = now
 	}
+	nominatimThrottle.last = turn
+	return turn.Sub(now)
+}
 
-	lat, errLat := strconv.ParseFloat(results[0].Lat, 64)
-	lon, errLon := strconv.ParseFloat(results[0].Lon, 64)
+func (g GeocodeResponse) coordinates() (float64, float64, error) {
+	lat, errLat := strconv.ParseFloat(g.Lat, 64)
+	lon, errLon := strconv.ParseFloat(g.Lon, 64)
 
 	if errLat != nil || errLon != nil {
-        return 0, 0, errors.New("formato de coordenadas inválido de la API externa")
-    }
+		return 0, 0, errors.New("formato de coordenadas inválido de la API externa")
+	}
 	return lat, lon, nil
-}
\ No newline at end of file
+}
+
+// shortAddress arma "calle número" a partir de las partes; si no hay calle usa el nombre completo
+func (g GeocodeResponse) shortAddress() string {
+	if g.Address.Road == "" {
+		return g.DisplayName
+	}
+	return strings.TrimSpace(g.Address.Road + " " + g.Address.HouseNumber)
+}
+
+// rafaelaViewbox arma el parámetro viewbox de Nominatim (lng/lat de las esquinas)
+func rafaelaViewbox() string {
+	return fmt.Sprintf("%f,%f,%f,%f", rafaelaMinLng, rafaelaMaxLat, rafaelaMaxLng, rafaelaMinLat)
+}
+
+func insideRafaela(lat, lng float64) bool {
+	return lat >= rafaelaMinLat && lat <= rafaelaMaxLat && lng >= rafaelaMinLng && lng <= rafaelaMaxLng
+}
@@ -4,59 +4,409 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+	"tracking/internal/domain"
 	"tracking/internal/repository"
+	"tracking/internal/utils"
 
-	"github.com/redis/go-redis/v9"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultMaxDriverSpeedKmh = 150.0
+	defaultMaxFixAge         = 2 * time.Minute
+	maxFixClockSkew          = 30 * time.Second
+	// Por debajo de esta distancia el salto se considera ruido normal del GPS
+	minJumpDistanceMeters = 200.0
+
+	maxBatchSize       = 500
+	defaultMaxBatchAge = 12 * time.Hour
+
+	defaultDriverLocationTTL   = 5 * time.Minute
+	defaultLocationSweepPeriod = time.Minute
 )
 
 type LocationServiceInterface interface {
-	UpdateLocation(ctx context.Context, driverID string, lat, lng float64) error
-	GetLocation(ctx context.Context, driverID string) (*redis.GeoLocation, error)
+	UpdateLocation(ctx context.Context, driverID string, fix domain.LocationFix) error
+	UpdateLocationBatch(ctx context.Context, driverID string, fixes []domain.LocationFix) (int, int, int, error)
+	GetLocation(ctx context.Context, driverID string) (*domain.DriverLocation, error)
+	SweepStaleLocations(ctx context.Context) (int, error)
+	ListAnomalies(ctx context.Context, driverID, orderID string) ([]domain.LocationAnomaly, error)
 }
 
 type LocationService struct {
-	repo      repository.LocationRepositoryInterface
-	orderRepo repository.OrderRepositoryInterface
-	userRepo  repository.UserRepositoryInterface
+	repo        repository.LocationRepositoryInterface
+	orderRepo   repository.OrderRepositoryInterface
+	userRepo    repository.UserRepositoryInterface
+	eventRepo   repository.OrderEventRepositoryInterface
+	anomalyRepo repository.LocationAnomalyRepositoryInterface
+	historyRepo repository.LocationHistoryRepositoryInterface
 }
 
-func NewLocationService(repo repository.LocationRepositoryInterface, orderRepo repository.OrderRepositoryInterface, userRepo repository.UserRepositoryInterface) *LocationService {
+func NewLocationService(repo repository.LocationRepositoryInterface, orderRepo repository.OrderRepositoryInterface, userRepo repository.UserRepositoryInterface, eventRepo repository.OrderEventRepositoryInterface, anomalyRepo repository.LocationAnomalyRepositoryInterface, historyRepo repository.LocationHistoryRepositoryInterface) *LocationService {
 	return &LocationService{
-		repo:      repo,
-		orderRepo: orderRepo,
-		userRepo:  userRepo,
+		repo:        repo,
+		orderRepo:   orderRepo,
+		userRepo:    userRepo,
+		eventRepo:   eventRepo,
+		anomalyRepo: anomalyRepo,
+		historyRepo: historyRepo,
 	}
 }
 
-func (s *LocationService) UpdateLocation(ctx context.Context, driverID string, lat, lng float64) error {
-	driver, err := s.userRepo.GetByID(ctx, driverID)
+// UpdateLocation guarda un fix del driver. Si el cliente no manda recorded_at se usa la hora del servidor.
+func (s *LocationService) UpdateLocation(ctx context.Context, driverID string, fix domain.LocationFix) error {
+	if !validCoordinates(fix.Lat, fix.Lng) {
+		return errors.New("coordenadas geográficas inválidas")
+	}
+
+	order, err := s.activeOrderForDriver(ctx, driverID)
 	if err != nil {
 		return err
 	}
+
+	clientTimestamp := !fix.RecordedAt.IsZero()
+	if !clientTimestamp {
+		fix.RecordedAt = time.Now()
+	}
+
+	// Igual que en el lote: un fix que no es posterior al último aceptado no puede pisarlo
+	prev := s.lastFix(ctx, driverID)
+	if prev != nil && !fix.RecordedAt.After(prev.RecordedAt) {
+		return utils.ErrStaleLocationFix
+	}
+	if err := s.checkAnomalies(ctx, driverID, order.ID, prev, fix, clientTimestamp); err != nil {
+		return err
+	}
+
+	if err := s.repo.SaveDriverLocation(ctx, driverID, fix.Lat, fix.Lng, fix.RecordedAt); err != nil {
+		return err
+	}
+	if err := s.repo.SaveLastFix(ctx, driverID, fix); err != nil {
+		slog.Error("error al guardar último fix", "driver_id", driverID, "error", err)
+	}
+	if _, err := s.historyRepo.InsertFixes(ctx, driverID, order.ID, []domain.LocationFix{fix}); err != nil {
+		slog.Error("error al guardar historial de ubicación", "driver_id", driverID, "error", err)
+	}
+
+	// La ubicación ya quedó guardada: un fallo en las geocercas no debe rechazar el update
+	s.checkGeofences(ctx, order, driverID, fix.Lat, fix.Lng)
+	return nil
+}
+
+// UpdateLocationBatch procesa los fixes acumulados mientras el driver estuvo sin señal.
+// Se valida el lote completo; los fixes duplicados o anteriores al último aceptado se descartan,
+// los que implican una velocidad imposible se rechazan, todos los demás van al historial y solo
+// el más nuevo actualiza la posición en vivo. Devuelve aceptados, descartados y rechazados.
+func (s *LocationService) UpdateLocationBatch(ctx context.Context, driverID string, fixes []domain.LocationFix) (int, int, int, error) {
+	if err := validateBatch(fixes); err != nil {
+		return 0, 0, 0, err
+	}
+
+	order, err := s.activeOrderForDriver(ctx, driverID)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	sorted := make([]domain.LocationFix, len(fixes))
+	copy(sorted, fixes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].RecordedAt.Before(sorted[j].RecordedAt)
+	})
+
+	prev := s.lastFix(ctx, driverID)
+	var accepted []domain.LocationFix
+	rejected := 0
+	for _, fix := range sorted {
+		if prev != nil && !fix.RecordedAt.After(prev.RecordedAt) {
+			continue
+		}
+		// Los fixes offline son viejos por definición, así que no se marcan como stale
+		if err := s.checkAnomalies(ctx, driverID, order.ID, prev, fix, false); err != nil {
+			rejected++
+			continue
+		}
+		accepted = append(accepted, fix)
+		current := fix
+		prev = &current
+	}
+
+	if len(accepted) == 0 {
+		return 0, len(fixes) - rejected, rejected, nil
+	}
+
+	inserted, err := s.historyRepo.InsertFixes(ctx, driverID, order.ID, accepted)
+	if err != nil {
+		slog.Error("error al guardar lote de ubicaciones", "driver_id", driverID, "error", err)
+		return 0, 0, 0, utils.ErrInternal
+	}
+
+	newest := accepted[len(accepted)-1]
+	if err := s.repo.SaveDriverLocation(ctx, driverID, newest.Lat, newest.Lng, newest.RecordedAt); err != nil {
+		return 0, 0, 0, err
+	}
+	if err := s.repo.SaveLastFix(ctx, driverID, newest); err != nil {
+		slog.Error("error al guardar último fix", "driver_id", driverID, "error", err)
+	}
+
+	for _, fix := range accepted {
+		s.checkGeofences(ctx, order, driverID, fix.Lat, fix.Lng)
+	}
+
+	return inserted, len(fixes) - inserted - rejected, rejected, nil
+}
+
+func (s *LocationService) GetLocation(ctx context.Context, driverID string) (*domain.DriverLocation, error) {
+
+	return s.repo.GetDriverLocation(ctx, driverID)
+}
+
+// SweepStaleLocations borra las posiciones en vivo que no se actualizan hace más del TTL configurado
+func (s *LocationService) SweepStaleLocations(ctx context.Context) (int, error) {
+	return s.repo.EvictStaleLocations(ctx, time.Now().Add(-GetDriverLocationTTL()))
+}
+
+// RunStaleLocationSweeper ejecuta SweepStaleLocations periódicamente hasta que se cancele el contexto
+func (s *LocationService) RunStaleLocationSweeper(ctx context.Context) {
+	// Las posiciones guardadas antes de registrar last_seen nunca vencerían sin esto
+	seeded, err := s.repo.SeedMissingLastSeen(ctx)
+	if err != nil {
+		slog.Error("error al completar last_seen de ubicaciones", "error", err)
+	} else if seeded > 0 {
+		slog.Info("ubicaciones sin last_seen marcadas como vencidas", "count", seeded)
+	}
+
+	ticker := time.NewTicker(getLocationSweepInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			evicted, err := s.SweepStaleLocations(ctx)
+			if err != nil {
+				slog.Error("error al limpiar ubicaciones vencidas", "error", err)
+				continue
+			}
+			if evicted > 0 {
+				slog.Info("ubicaciones vencidas eliminadas", "count", evicted)
+			}
+		}
+	}
+}
+
+// activeOrderForDriver valida que quien reporta sea un driver activo con un pedido asignado
+func (s *LocationService) activeOrderForDriver(ctx context.Context, driverID string) (domain.Order, error) {
+	driver, err := s.userRepo.GetByID(ctx, driverID)
+	if err != nil {
+		return domain.Order{}, err
+	}
 	if !driver.IsActive {
-		return errors.New("usuario inactivo")
+		return domain.Order{}, errors.New("usuario inactivo")
 	}
 	if driver.Role != "driver" {
-		return errors.New("solo conductores activos pueden reportar ubicación")
+		return domain.Order{}, errors.New("solo conductores activos pueden reportar ubicación")
 	}
 
-	if lat < -90 || lat > 90 || lng < -180 || lng > 180 {
-		return errors.New("coordenadas geográficas inválidas")
+	order, err := s.orderRepo.GetActiveByDriver(ctx, driverID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		slog.Warn("intento de update de ubicación de driver sin orden activa", "driver_id", driverID)
+		return domain.Order{}, errors.New("no puedes reportar ubicación sin un pedido asignado")
 	}
+	return order, err
+}
 
-	active, err := s.orderRepo.HasActiveOrder(ctx, driverID)
+func (s *LocationService) lastFix(ctx context.Context, driverID string) *domain.LocationFix {
+	prev, err := s.repo.GetLastFix(ctx, driverID)
 	if err != nil {
-		return err
+		slog.Error("error al leer último fix", "driver_id", driverID, "error", err)
+		return nil
 	}
-	if !active {
-		slog.Warn("intento de update de ubicación de driver sin orden activa", "driver_id", driverID)
-		return errors.New("no puedes reportar ubicación sin un pedido asignado")
+	return prev
+}
+
+func (s *LocationService) ListAnomalies(ctx context.Context, driverID, orderID string) ([]domain.LocationAnomaly, error) {
+	anomalies, err := s.anomalyRepo.List(ctx, driverID, orderID)
+	if err != nil {
+		slog.Error("error al listar anomalías de ubicación", "driver_id", driverID, "order_id", orderID, "error", err)
+		return nil, utils.ErrInternal
+	}
+	if anomalies == nil {
+		return []domain.LocationAnomaly{}, nil
+	}
+	return anomalies, nil
+}
+
+// checkAnomalies compara el fix con el anterior del driver. Los saltos a velocidades
+// imposibles se rechazan; timestamps viejos y posiciones repetidas solo se registran.
+func (s *LocationService) checkAnomalies(ctx context.Context, driverID, orderID string, prev *domain.LocationFix, fix domain.LocationFix, clientTimestamp bool) error {
+	now := time.Now()
+	if clientTimestamp && (now.Sub(fix.RecordedAt) > getMaxFixAge() || fix.RecordedAt.Sub(now) > maxFixClockSkew) {
+		s.logAnomaly(ctx, domain.LocationAnomaly{
+			DriverID:   driverID,
+			OrderID:    orderID,
+			Type:       domain.AnomalyStaleTimestamp,
+			Lat:        fix.Lat,
+			Lng:        fix.Lng,
+			RecordedAt: fix.RecordedAt,
+		})
+	}
+
+	if prev == nil {
+		return nil
+	}
+
+	if prev.Lat == fix.Lat && prev.Lng == fix.Lng {
+		s.logAnomaly(ctx, domain.LocationAnomaly{
+			DriverID:   driverID,
+			OrderID:    orderID,
+			Type:       domain.AnomalyRepeatedPosition,
+			Lat:        fix.Lat,
+			Lng:        fix.Lng,
+			PrevLat:    &prev.Lat,
+			PrevLng:    &prev.Lng,
+			RecordedAt: fix.RecordedAt,
+		})
+		return nil
+	}
+
+	distance := distanceMeters(prev.Lat, prev.Lng, fix.Lat, fix.Lng)
+	if distance < minJumpDistanceMeters {
+		return nil
 	}
 
-	return s.repo.SaveDriverLocation(ctx, driverID, lat, lng)
+	elapsed := fix.RecordedAt.Sub(prev.RecordedAt).Seconds()
+	if elapsed < 1 {
+		elapsed = 1
+	}
+	speedKmh := distance / elapsed * 3.6
+	if speedKmh <= getMaxDriverSpeed() {
+		return nil
+	}
+
+	s.logAnomaly(ctx, domain.LocationAnomaly{
+		DriverID:   driverID,
+		OrderID:    orderID,
+		Type:       domain.AnomalyImpossibleSpeed,
+		Lat:        fix.Lat,
+		Lng:        fix.Lng,
+		PrevLat:    &prev.Lat,
+		PrevLng:    &prev.Lng,
+		SpeedKmh:   &speedKmh,
+		Rejected:   true,
+		RecordedAt: fix.RecordedAt,
+	})
+	slog.Warn("fix rechazado por velocidad imposible", "driver_id", driverID, "speed_kmh", speedKmh)
+	return utils.ErrImpossibleLocationJump
 }
 
-func (s *LocationService) GetLocation(ctx context.Context, driverID string) (*redis.GeoLocation, error) {
+func (s *LocationService) logAnomaly(ctx context.Context, anomaly domain.LocationAnomaly) {
+	if err := s.anomalyRepo.Create(ctx, &anomaly); err != nil {
+		slog.Error("error al registrar anomalía de ubicación", "driver_id", anomaly.DriverID, "type", anomaly.Type, "error", err)
+	}
+}
 
-	return s.repo.GetDriverLocation(ctx, driverID)
+// checkGeofences emite ARRIVED_AT_PICKUP / ARRIVED_AT_DROPOFF la primera vez que el
+// driver entra en el radio configurado alrededor del origen o del destino del pedido.
+func (s *LocationService) checkGeofences(ctx context.Context, order domain.Order, driverID string, lat, lng float64) {
+	radius := getGeofenceRadius()
+
+	fences := []struct {
+		eventType string
+		lat, lng  float64
+	}{
+		{domain.OrderEventArrivedAtPickup, order.OriginLat, order.OriginLng},
+		{domain.OrderEventArrivedAtDropoff, order.DestLat, order.DestLng},
+	}
+
+	for _, f := range fences {
+		distance := distanceMeters(lat, lng, f.lat, f.lng)
+		if distance > radius {
+			continue
+		}
+
+		event := domain.OrderEvent{
+			OrderID: order.ID,
+			Type:    f.eventType,
+			ActorID: driverID,
+			Data: map[string]interface{}{
+				"lat":             lat,
+				"lng":             lng,
+				"distance_meters": distance,
+			},
+		}
+
+		// CreateOnce también lo encola en el outbox para que llegue a los canales de notificación
+		if _, err := s.eventRepo.CreateOnce(ctx, &event); err != nil {
+			slog.Error("error al registrar evento de geocerca", "order_id", order.ID, "type", f.eventType, "error", err)
+		}
+	}
+}
+
+func getMaxDriverSpeed() float64 {
+	kmh, err := strconv.ParseFloat(os.Getenv("MAX_DRIVER_SPEED_KMH"), 64)
+	if err == nil && kmh > 0 {
+		return kmh
+	}
+	return defaultMaxDriverSpeedKmh
+}
+
+func getMaxFixAge() time.Duration {
+	seconds, err := strconv.Atoi(os.Getenv("LOCATION_MAX_FIX_AGE_SECONDS"))
+	if err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return defaultMaxFixAge
+}
+
+func validCoordinates(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
+
+// validateBatch rechaza el lote completo si algún fix es inválido
+func validateBatch(fixes []domain.LocationFix) error {
+	if len(fixes) == 0 || len(fixes) > maxBatchSize {
+		return utils.ErrInvalidLocationBatch
+	}
+
+	now := time.Now()
+	maxAge := getMaxBatchAge()
+	for _, fix := range fixes {
+		if !validCoordinates(fix.Lat, fix.Lng) || fix.RecordedAt.IsZero() {
+			return utils.ErrInvalidLocationBatch
+		}
+		if fix.RecordedAt.Sub(now) > maxFixClockSkew || now.Sub(fix.RecordedAt) > maxAge {
+			return utils.ErrInvalidLocationBatch
+		}
+	}
+	return nil
+}
+
+func getMaxBatchAge() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv("LOCATION_BATCH_MAX_AGE_HOURS"))
+	if err == nil && hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return defaultMaxBatchAge
+}
+
+// GetDriverLocationTTL es el tiempo tras el cual una posición sin actualizar se considera vencida
+func GetDriverLocationTTL() time.Duration {
+	seconds, err := strconv.Atoi(os.Getenv("DRIVER_LOCATION_TTL_SECONDS"))
+	if err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return defaultDriverLocationTTL
+}
+
+func getLocationSweepInterval() time.Duration {
+	seconds, err := strconv.Atoi(os.Getenv("DRIVER_LOCATION_SWEEP_SECONDS"))
+	if err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return defaultLocationSweepPeriod
 }
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"tracking/internal/domain"
+	"tracking/internal/repository"
+	"tracking/internal/utils"
+)
+
+// fakeAnomalyRepo guarda las anomalías registradas
+type fakeAnomalyRepo struct {
+	repository.LocationAnomalyRepositoryInterface
+	anomalies []domain.LocationAnomaly
+}
+
+func (r *fakeAnomalyRepo) Create(ctx context.Context, a *domain.LocationAnomaly) error {
+	r.anomalies = append(r.anomalies, *a)
+	return nil
+}
+
+func TestCheckAnomalies(t *testing.T) {
+	t.Setenv("MAX_DRIVER_SPEED_KMH", "")
+	t.Setenv("LOCATION_MAX_FIX_AGE_SECONDS", "")
+
+	now := time.Now()
+	prev := &domain.LocationFix{Lat: -31.2500, Lng: -61.4900, RecordedAt: now.Add(-time.Minute)}
+
+	tests := []struct {
+		name            string
+		prev            *domain.LocationFix
+		fix             domain.LocationFix
+		clientTimestamp bool
+		wantErr         error
+		wantAnomalies   []string
+	}{
+		{
+			name: "primer fix del driver",
+			fix:  domain.LocationFix{Lat: -31.25, Lng: -61.49, RecordedAt: now},
+		},
+		{
+			name: "500 m en un minuto",
+			prev: prev,
+			fix:  domain.LocationFix{Lat: -31.2545, Lng: -61.4900, RecordedAt: now},
+		},
+		{
+			name:          "5 km en diez segundos",
+			prev:          &domain.LocationFix{Lat: -31.2500, Lng: -61.4900, RecordedAt: now.Add(-10 * time.Second)},
+			fix:           domain.LocationFix{Lat: -31.2950, Lng: -61.4900, RecordedAt: now},
+			wantErr:       utils.ErrImpossibleLocationJump,
+			wantAnomalies: []string{domain.AnomalyImpossibleSpeed},
+		},
+		{
+			name: "ruido del GPS por debajo del salto mínimo",
+			prev: &domain.LocationFix{Lat: -31.2500, Lng: -61.4900, RecordedAt: now},
+			fix:  domain.LocationFix{Lat: -31.2510, Lng: -61.4900, RecordedAt: now},
+		},
+		{
+			name:          "misma posición repetida",
+			prev:          prev,
+			fix:           domain.LocationFix{Lat: prev.Lat, Lng: prev.Lng, RecordedAt: now},
+			wantAnomalies: []string{domain.AnomalyRepeatedPosition},
+		},
+		{
+			name:            "timestamp viejo informado por el cliente",
+			fix:             domain.LocationFix{Lat: -31.25, Lng: -61.49, RecordedAt: now.Add(-10 * time.Minute)},
+			clientTimestamp: true,
+			wantAnomalies:   []string{domain.AnomalyStaleTimestamp},
+		},
+		{
+			name:            "timestamp en el futuro",
+			fix:             domain.LocationFix{Lat: -31.25, Lng: -61.49, RecordedAt: now.Add(5 * time.Minute)},
+			clientTimestamp: true,
+			wantAnomalies:   []string{domain.AnomalyStaleTimestamp},
+		},
+		{
+			name: "timestamp viejo en un lote offline",
+			fix:  domain.LocationFix{Lat: -31.25, Lng: -61.49, RecordedAt: now.Add(-10 * time.Minute)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anomalies := &fakeAnomalyRepo{}
+			svc := &LocationService{anomalyRepo: anomalies}
+
+			err := svc.checkAnomalies(context.Background(), "driver-1", "order-1", tt.prev, tt.fix, tt.clientTimestamp)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, se esperaba %v", err, tt.wantErr)
+			}
+			if len(anomalies.anomalies) != len(tt.wantAnomalies) {
+				t.Fatalf("anomalías = %+v, se esperaban %v", anomalies.anomalies, tt.wantAnomalies)
+			}
+			for i, a := range anomalies.anomalies {
+				if a.Type != tt.wantAnomalies[i] {
+					t.Errorf("anomalía %d = %s, se esperaba %s", i, a.Type, tt.wantAnomalies[i])
+				}
+				if a.Rejected != (tt.wantErr != nil) {
+					t.Errorf("anomalía %d con rejected = %v", i, a.Rejected)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckAnomaliesCustomMaxSpeed(t *testing.T) {
+	t.Setenv("MAX_DRIVER_SPEED_KMH", "20")
+
+	now := time.Now()
+	prev := &domain.LocationFix{Lat: -31.2500, Lng: -61.4900, RecordedAt: now.Add(-time.Minute)}
+	// 500 m en un minuto son 30 km/h: se aceptan con el tope por defecto, no con 20 km/h
+	fix := domain.LocationFix{Lat: -31.2545, Lng: -61.4900, RecordedAt: now}
+
+	svc := &LocationService{anomalyRepo: &fakeAnomalyRepo{}}
+	err := svc.checkAnomalies(context.Background(), "driver-1", "order-1", prev, fix, false)
+	if !errors.Is(err, utils.ErrImpossibleLocationJump) {
+		t.Fatalf("error = %v, se esperaba ErrImpossibleLocationJump", err)
+	}
+}
+
+type fakeDriverRepo struct {
+	repository.UserRepositoryInterface
+}
+
+func (fakeDriverRepo) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	return &domain.User{ID: id, Role: "driver", IsActive: true}, nil
+}
+
+type fakeActiveOrderRepo struct {
+	repository.OrderRepositoryInterface
+}
+
+func (fakeActiveOrderRepo) GetActiveByDriver(ctx context.Context, driverID string) (domain.Order, error) {
+	return domain.Order{ID: "order-1", DriverID: driverID, OriginLat: 0, OriginLng: 0, DestLat: 1, DestLng: 1}, nil
+}
+
+// fakeLiveLocationRepo guarda el último fix y la posición en vivo en memoria
+type fakeLiveLocationRepo struct {
+	repository.LocationRepositoryInterface
+	last *domain.LocationFix
+	live *domain.LocationFix
+}
+
+func (r *fakeLiveLocationRepo) GetLastFix(ctx context.Context, driverID string) (*domain.LocationFix, error) {
+	return r.last, nil
+}
+
+func (r *fakeLiveLocationRepo) SaveLastFix(ctx context.Context, driverID string, fix domain.LocationFix) error {
+	r.last = &fix
+	return nil
+}
+
+func (r *fakeLiveLocationRepo) SaveDriverLocation(ctx context.Context, driverID string, lat, lng float64, recordedAt time.Time) error {
+	r.live = &domain.LocationFix{Lat: lat, Lng: lng, RecordedAt: recordedAt}
+	return nil
+}
+
+type fakeHistoryRepo struct {
+	fixes []domain.LocationFix
+}
+
+func (r *fakeHistoryRepo) InsertFixes(ctx context.Context, driverID, orderID string, fixes []domain.LocationFix) (int, error) {
+	r.fixes = append(r.fixes, fixes...)
+	return len(fixes), nil
+}
+
+func newTestLocationService(last *domain.LocationFix) (*LocationService, *fakeLiveLocationRepo, *fakeHistoryRepo) {
+	live := &fakeLiveLocationRepo{last: last}
+	history := &fakeHistoryRepo{}
+	svc := NewLocationService(live, fakeActiveOrderRepo{}, fakeDriverRepo{}, &fakeEventRepo{}, &fakeAnomalyRepo{}, history)
+	return svc, live, history
+}
+
+func TestUpdateLocationRejectsOutOfOrderFix(t *testing.T) {
+	now := time.Now()
+	last := &domain.LocationFix{Lat: -31.2500, Lng: -61.4900, RecordedAt: now}
+
+	tests := []struct {
+		name       string
+		recordedAt time.Time
+		wantErr    error
+	}{
+		{"anterior al último", now.Add(-10 * time.Second), utils.ErrStaleLocationFix},
+		{"mismo instante que el último", now, utils.ErrStaleLocationFix},
+		{"posterior al último", now.Add(10 * time.Second), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, live, _ := newTestLocationService(last)
+			fix := domain.LocationFix{Lat: -31.2501, Lng: -61.4900, RecordedAt: tt.recordedAt}
+
+			err := svc.UpdateLocation(context.Background(), "driver-1", fix)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, se esperaba %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && live.live != nil {
+				t.Errorf("un fix rechazado actualizó la posición en vivo")
+			}
+		})
+	}
+}
+
+func TestUpdateLocationBatchCounts(t *testing.T) {
+	t.Setenv("MAX_DRIVER_SPEED_KMH", "")
+
+	now := time.Now()
+	last := &domain.LocationFix{Lat: -31.2500, Lng: -61.4900, RecordedAt: now.Add(-5 * time.Minute)}
+	fixes := []domain.LocationFix{
+		// anterior al último aceptado: se descarta
+		{Lat: -31.2490, Lng: -61.4900, RecordedAt: now.Add(-6 * time.Minute)},
+		{Lat: -31.2510, Lng: -61.4900, RecordedAt: now.Add(-4 * time.Minute)},
+		// 5 km en 10 segundos: se rechaza
+		{Lat: -31.2960, Lng: -61.4900, RecordedAt: now.Add(-4*time.Minute + 10*time.Second)},
+		{Lat: -31.2520, Lng: -61.4900, RecordedAt: now.Add(-3 * time.Minute)},
+	}
+
+	svc, live, history := newTestLocationService(last)
+	accepted, dropped, rejected, err := svc.UpdateLocationBatch(context.Background(), "driver-1", fixes)
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if accepted != 2 || dropped != 1 || rejected != 1 {
+		t.Errorf("accepted/dropped/rejected = %d/%d/%d, se esperaba 2/1/1", accepted, dropped, rejected)
+	}
+	if len(history.fixes) != 2 {
+		t.Errorf("se guardaron %d fixes en el historial, se esperaban 2", len(history.fixes))
+	}
+	if live.live == nil || !live.live.RecordedAt.Equal(fixes[3].RecordedAt) {
+		t.Errorf("la posición en vivo debería ser el fix más nuevo aceptado, es %+v", live.live)
+	}
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"tracking/internal/domain"
+	"tracking/internal/repository"
+	"tracking/internal/utils"
+)
+
+const (
+	defaultLoginMaxAttempts      = 5
+	defaultLoginMaxAttemptsPerIP = 20
+	defaultLoginDelayAfter       = 3
+	defaultLoginWindow           = 15 * time.Minute
+	defaultLoginLockout          = 15 * time.Minute
+
+	// Tope de la espera progresiva entre intentos antes del bloqueo
+	maxLoginDelay = time.Minute
+)
+
+// loginGuard frena la fuerza bruta: cuenta fallos por email y por IP en Redis, impone una espera
+// que crece con cada fallo y bloquea temporalmente al llegar al máximo.
+type loginGuard struct {
+	limiter  repository.RateLimitRepositoryInterface
+	attempts repository.LoginAttemptRepositoryInterface
+}
+
+// check devuelve un RateLimitError si el email o la IP todavía tienen que esperar
+func (g loginGuard) check(ctx context.Context, email, ip string) error {
+	for _, key := range []string{loginEmailKey(email), loginIPKey(ip)} {
+		wait, err := g.limiter.BlockedFor(ctx, key)
+		if err != nil {
+			return err
+		}
+		if wait > 0 {
+			return &utils.RateLimitError{RetryAfter: wait, Err: utils.ErrTooManyLoginAttempts}
+		}
+	}
+	return nil
+}
+
+func (g loginGuard) registerFailure(ctx context.Context, email, ip string) {
+	count, _, err := g.limiter.Hit(ctx, loginEmailKey(email), getLoginWindow())
+	if err != nil {
+		slog.Error("error al contar login fallido", "email", email, "error", err)
+		return
+	}
+
+	switch {
+	case count >= int64(getLoginMaxAttempts()):
+		slog.Warn("cuenta bloqueada por intentos fallidos", "email", email, "ip", ip, "attempts", count)
+		g.block(ctx, loginEmailKey(email), getLoginLockout())
+	case count >= int64(getLoginDelayAfter()):
+		g.block(ctx, loginEmailKey(email), loginDelay(count))
+	}
+
+	ipCount, _, err := g.limiter.Hit(ctx, loginIPKey(ip), getLoginWindow())
+	if err != nil {
+		slog.Error("error al contar login fallido", "ip", ip, "error", err)
+		return
+	}
+	if ipCount >= int64(getLoginMaxAttemptsPerIP()) {
+		slog.Warn("IP bloqueada por intentos fallidos", "ip", ip, "attempts", ipCount)
+		g.block(ctx, loginIPKey(ip), getLoginLockout())
+	}
+}
+
+// registerSuccess reinicia el contador del email; el de la IP sigue corriendo
+func (g loginGuard) registerSuccess(ctx context.Context, email string) {
+	if err := g.limiter.Reset(ctx, loginEmailKey(email)); err != nil {
+		slog.Error("error al reiniciar contador de login", "email", email, "error", err)
+	}
+}
+
+// unlock levanta el bloqueo del email y reinicia su contador
+func (g loginGuard) unlock(ctx context.Context, email string) error {
+	if err := g.limiter.Unblock(ctx, loginEmailKey(email)); err != nil {
+		return err
+	}
+	return g.limiter.Reset(ctx, loginEmailKey(email))
+}
+
+// record deja el intento en la auditoría. Un fallo al auditar no impide el login.
+func (g loginGuard) record(ctx context.Context, attempt domain.LoginAttempt) {
+	if err := g.attempts.Create(ctx, attempt); err != nil {
+		slog.Error("error al registrar intento de login", "email", attempt.Email, "error", err)
+	}
+}
+
+func (g loginGuard) block(ctx context.Context, key string, ttl time.Duration) {
+	if err := g.limiter.Block(ctx, key, ttl); err != nil {
+		slog.Error("error al bloquear login", "key", key, "error", err)
+	}
+}
+
+// loginDelay duplica la espera con cada fallo a partir de LOGIN_DELAY_AFTER: 1s, 2s, 4s...
+func loginDelay(failures int64) time.Duration {
+	exp := float64(failures - int64(getLoginDelayAfter()))
+	delay := time.Duration(math.Pow(2, exp)) * time.Second
+	if delay > maxLoginDelay {
+		return maxLoginDelay
+	}
+	return delay
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func loginEmailKey(email string) string {
+	return "login:email:" + email
+}
+
+func loginIPKey(ip string) string {
+	return "login:ip:" + ip
+}
+
+func getLoginMaxAttempts() int {
+	return getEnvInt("LOGIN_MAX_ATTEMPTS", defaultLoginMaxAttempts)
+}
+
+func getLoginMaxAttemptsPerIP() int {
+	return getEnvInt("LOGIN_MAX_ATTEMPTS_PER_IP", defaultLoginMaxAttemptsPerIP)
+}
+
+func getLoginDelayAfter() int {
+	return getEnvInt("LOGIN_DELAY_AFTER", defaultLoginDelayAfter)
+}
+
+func getLoginWindow() time.Duration {
+	return time.Duration(getEnvInt("LOGIN_WINDOW_MINUTES", int(defaultLoginWindow/time.Minute))) * time.Minute
+}
+
+func getLoginLockout() time.Duration {
+	return time.Duration(getEnvInt("LOGIN_LOCKOUT_MINUTES", int(defaultLoginLockout/time.Minute))) * time.Minute
+}
+
+func getEnvInt(key string, fallback int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err == nil && v > 0 {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,500 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"tracking/internal/auth"
+	"tracking/internal/domain"
+	"tracking/internal/policy"
+	"tracking/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultMFAIssuer = "Tracking"
+
+	// El desafío cubre el tiempo de abrir la app de autenticación (o configurarla por primera vez)
+	mfaChallengeTTL = 10 * time.Minute
+
+	// Códigos fallidos permitidos por desafío antes de tener que repetir el login
+	mfaMaxAttempts = 5
+
+	// Códigos fallidos por usuario, sumando todos los desafíos y endpoints, antes de bloquear el doble factor
+	defaultMFAMaxFailures = 10
+	defaultMFALockout     = 24 * time.Hour
+
+	recoveryCodeCount = 10
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// mfaChallenge decide si el login necesita segundo factor. Devuelve nil si se pueden emitir los
+// tokens directamente.
+func (s *UserService) mfaChallenge(ctx context.Context, user *domain.User) (*domain.LoginResult, error) {
+	enrollment, err := s.getTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	enabled := enrollment.EnabledAt != nil
+	if !enabled && !mfaRequiredForRole(ctx, user.Role) {
+		return nil, nil
+	}
+
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return nil, utils.ErrInternal
+	}
+	if err := s.tokenRepo.StoreMFAChallenge(ctx, user.ID, tokenHash, mfaChallengeTTL); err != nil {
+		slog.Error("error al guardar desafío de doble factor", "user_id", user.ID, "error", err)
+		return nil, utils.ErrInternal
+	}
+	return &domain.LoginResult{User: user, MFARequired: true, MFAToken: token, EnrollmentNeeded: !enabled}, nil
+}
+
+// StartMFAEnrollment genera el secreto TOTP para un usuario que debe configurar doble factor
+// antes de terminar su primer login (admins, y drivers si MFA_REQUIRED_FOR_DRIVERS)
+func (s *UserService) StartMFAEnrollment(ctx context.Context, mfaToken string) (domain.TOTPSetup, error) {
+	userID, err := s.tokenRepo.GetMFAChallenge(ctx, hashToken(mfaToken))
+	if err != nil {
+		slog.Error("error al leer desafío de doble factor", "error", err)
+		return domain.TOTPSetup{}, utils.ErrInternal
+	}
+	if userID == "" {
+		return domain.TOTPSetup{}, utils.ErrInvalidMFAToken
+	}
+	return s.StartTOTPEnrollment(ctx, userID)
+}
+
+// VerifyMFA completa el login con un código TOTP o un código de recuperación. Si el usuario estaba
+// configurando el doble factor, el primer código lo activa y se devuelven los códigos de recuperación.
+func (s *UserService) VerifyMFA(ctx context.Context, mfaToken, code, recoveryCode, userAgent, ip string) (*domain.LoginResult, error) {
+	tokenHash := hashToken(mfaToken)
+	userID, err := s.tokenRepo.GetMFAChallenge(ctx, tokenHash)
+	if err != nil {
+		slog.Error("error al leer desafío de doble factor", "error", err)
+		return nil, utils.ErrInternal
+	}
+	if userID == "" {
+		return nil, utils.ErrInvalidMFAToken
+	}
+
+	if err := s.checkMFALock(ctx, userID); err != nil {
+		return nil, err
+	}
+	count, _, err := s.guard.limiter.Hit(ctx, mfaChallengeAttemptsKey(tokenHash), mfaChallengeTTL)
+	if err != nil {
+		slog.Error("error al contar intentos de doble factor", "user_id", userID, "error", err)
+		return nil, utils.ErrInternal
+	}
+	if count > mfaMaxAttempts {
+		s.tokenRepo.ConsumeMFAChallenge(ctx, tokenHash)
+		slog.Warn("desafío de doble factor descartado por intentos fallidos", "user_id", userID)
+		return nil, utils.ErrTooManyMFAAttempts
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil || !user.IsActive {
+		return nil, utils.ErrInvalidMFAToken
+	}
+
+	enrollment, err := s.getTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var recoveryCodes []string
+	switch {
+	case enrollment.Secret == "":
+		return nil, utils.ErrMFANotEnrolled
+	case enrollment.EnabledAt == nil:
+		recoveryCodes, err = s.enableTOTP(ctx, enrollment, code)
+	case recoveryCode != "":
+		err = s.useRecoveryCode(ctx, userID, recoveryCode)
+	default:
+		err = s.checkTOTP(ctx, enrollment, code)
+	}
+	if err := s.recordMFAResult(ctx, userID, err); err != nil {
+		return nil, err
+	}
+
+	// Consumir el desafío evita que dos requests en paralelo abran dos sesiones con el mismo login
+	if consumed, err := s.tokenRepo.ConsumeMFAChallenge(ctx, tokenHash); err != nil || consumed == "" {
+		return nil, utils.ErrInvalidMFAToken
+	}
+
+	result, err := s.issueSession(ctx, user, userAgent, ip)
+	if err != nil {
+		return nil, err
+	}
+	result.RecoveryCodes = recoveryCodes
+	return result, nil
+}
+
+func (s *UserService) GetMFAStatus(ctx context.Context, userID, role string) (domain.MFAStatus, error) {
+	enrollment, err := s.getTOTP(ctx, userID)
+	if err != nil {
+		return domain.MFAStatus{}, err
+	}
+
+	status := domain.MFAStatus{
+		Enabled:   enrollment.EnabledAt != nil,
+		Required:  mfaRequiredForRole(ctx, role),
+		EnabledAt: enrollment.EnabledAt,
+	}
+	if status.Enabled {
+		if status.RecoveryCodesLeft, err = s.mfaRepo.CountRecoveryCodes(ctx, userID); err != nil {
+			slog.Error("error al contar códigos de recuperación", "user_id", userID, "error", err)
+			return domain.MFAStatus{}, utils.ErrInternal
+		}
+	}
+	return status, nil
+}
+
+// StartTOTPEnrollment genera un secreto nuevo. El doble factor no se exige hasta confirmar un código.
+func (s *UserService) StartTOTPEnrollment(ctx context.Context, userID string) (domain.TOTPSetup, error) {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return domain.TOTPSetup{}, utils.ErrUserNotFound
+	}
+
+	enrollment, err := s.getTOTP(ctx, userID)
+	if err != nil {
+		return domain.TOTPSetup{}, err
+	}
+	if enrollment.EnabledAt != nil {
+		return domain.TOTPSetup{}, utils.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return domain.TOTPSetup{}, utils.ErrInternal
+	}
+	if err := s.mfaRepo.SaveTOTPSecret(ctx, userID, secret); err != nil {
+		slog.Error("error al guardar secreto TOTP", "user_id", userID, "error", err)
+		return domain.TOTPSetup{}, utils.ErrInternal
+	}
+
+	return domain.TOTPSetup{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(secret, user.Email, getMFAIssuer()),
+	}, nil
+}
+
+// ConfirmTOTP activa el doble factor con el primer código de la app y devuelve los códigos de recuperación
+func (s *UserService) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	enrollment, err := s.getTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment.Secret == "" {
+		return nil, utils.ErrMFANotEnrolled
+	}
+	if enrollment.EnabledAt != nil {
+		return nil, utils.ErrMFAAlreadyEnabled
+	}
+	if err := s.checkMFALock(ctx, userID); err != nil {
+		return nil, err
+	}
+	codes, err := s.enableTOTP(ctx, enrollment, code)
+	if err := s.recordMFAResult(ctx, userID, err); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP quita el doble factor. No se permite en roles que lo exigen.
+func (s *UserService) DisableTOTP(ctx context.Context, userID, role, code string) error {
+	if mfaRequiredForRole(ctx, role) {
+		return utils.ErrMFARequired
+	}
+
+	enrollment, err := s.enabledTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.verifyTOTPWithLimit(ctx, enrollment, code); err != nil {
+		return err
+	}
+
+	if err := s.mfaRepo.DisableTOTP(ctx, userID); err != nil {
+		slog.Error("error al desactivar doble factor", "user_id", userID, "error", err)
+		return utils.ErrInternal
+	}
+	slog.Info("doble factor desactivado", "user_id", userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes invalida los códigos de recuperación anteriores y devuelve otros
+func (s *UserService) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	enrollment, err := s.enabledTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyTOTPWithLimit(ctx, enrollment, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, utils.ErrInternal
+	}
+	if err := s.mfaRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		slog.Error("error al regenerar códigos de recuperación", "user_id", userID, "error", err)
+		return nil, utils.ErrInternal
+	}
+	return codes, nil
+}
+
+// ResetMFA es para cuando el usuario perdió el teléfono y los códigos de recuperación. Cierra sus
+// sesiones; si el rol exige doble factor, lo vuelve a configurar en el próximo login.
+func (s *UserService) ResetMFA(ctx context.Context, actorRole, userID string) error {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := guardAdminTarget(actorRole, user); err != nil {
+		return err
+	}
+
+	if err := s.mfaRepo.DisableTOTP(ctx, userID); err != nil {
+		slog.Error("error al reiniciar doble factor", "user_id", userID, "error", err)
+		return utils.ErrInternal
+	}
+	if err := s.unlockMFA(ctx, userID); err != nil {
+		slog.Error("error al levantar bloqueo de doble factor", "user_id", userID, "error", err)
+		return utils.ErrInternal
+	}
+	if err := s.revokeAllTokens(ctx, userID); err != nil {
+		slog.Error("error al revocar sesiones tras reiniciar doble factor", "user_id", userID, "error", err)
+		return utils.ErrInternal
+	}
+	slog.Warn("doble factor reiniciado por un admin", "user_id", userID)
+	return nil
+}
+
+func (s *UserService) enableTOTP(ctx context.Context, enrollment domain.TOTPEnrollment, code string) ([]string, error) {
+	step, ok := auth.ValidateTOTP(enrollment.Secret, code, time.Now())
+	if !ok {
+		return nil, utils.ErrInvalidMFACode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, utils.ErrInternal
+	}
+	if err := s.mfaRepo.EnableTOTP(ctx, enrollment.UserID, step, hashes); err != nil {
+		slog.Error("error al activar doble factor", "user_id", enrollment.UserID, "error", err)
+		return nil, utils.ErrInternal
+	}
+	slog.Info("doble factor activado", "user_id", enrollment.UserID)
+	return codes, nil
+}
+
+// verifyTOTPWithLimit valida un código de un usuario logueado contando los fallos igual que en el login
+func (s *UserService) verifyTOTPWithLimit(ctx context.Context, enrollment domain.TOTPEnrollment, code string) error {
+	if err := s.checkMFALock(ctx, enrollment.UserID); err != nil {
+		return err
+	}
+	return s.recordMFAResult(ctx, enrollment.UserID, s.checkTOTP(ctx, enrollment, code))
+}
+
+// checkMFALock devuelve un RateLimitError si el doble factor del usuario está bloqueado por fallos
+func (s *UserService) checkMFALock(ctx context.Context, userID string) error {
+	wait, err := s.guard.limiter.BlockedFor(ctx, mfaFailuresKey(userID))
+	if err != nil {
+		slog.Error("error al consultar bloqueo de doble factor", "user_id", userID, "error", err)
+		return utils.ErrInternal
+	}
+	if wait > 0 {
+		return &utils.RateLimitError{RetryAfter: wait, Err: utils.ErrTooManyMFAAttempts}
+	}
+	return nil
+}
+
+// recordMFAResult cuenta los códigos inválidos del usuario en una ventana larga, sin reiniciarla en
+// cada login, y bloquea el doble factor al llegar al máximo. Un código válido reinicia la cuenta.
+func (s *UserService) recordMFAResult(ctx context.Context, userID string, result error) error {
+	if result == nil {
+		if err := s.guard.limiter.Reset(ctx, mfaFailuresKey(userID)); err != nil {
+			slog.Error("error al reiniciar fallos de doble factor", "user_id", userID, "error", err)
+		}
+		return nil
+	}
+	if !errors.Is(result, utils.ErrInvalidMFACode) {
+		return result
+	}
+
+	count, _, err := s.guard.limiter.Hit(ctx, mfaFailuresKey(userID), getMFALockout())
+	if err != nil {
+		slog.Error("error al contar fallos de doble factor", "user_id", userID, "error", err)
+		return result
+	}
+	if count >= int64(getMFAMaxFailures()) {
+		slog.Warn("doble factor bloqueado por códigos inválidos", "user_id", userID, "failures", count)
+		if err := s.guard.limiter.Block(ctx, mfaFailuresKey(userID), getMFALockout()); err != nil {
+			slog.Error("error al bloquear doble factor", "user_id", userID, "error", err)
+		}
+		return &utils.RateLimitError{RetryAfter: getMFALockout(), Err: utils.ErrTooManyMFAAttempts}
+	}
+	return result
+}
+
+// unlockMFA levanta el bloqueo por códigos inválidos y reinicia la cuenta de fallos
+func (s *UserService) unlockMFA(ctx context.Context, userID string) error {
+	if err := s.guard.limiter.Unblock(ctx, mfaFailuresKey(userID)); err != nil {
+		return err
+	}
+	return s.guard.limiter.Reset(ctx, mfaFailuresKey(userID))
+}
+
+// checkTOTP valida el código y que no se haya usado antes
+func (s *UserService) checkTOTP(ctx context.Context, enrollment domain.TOTPEnrollment, code string) error {
+	step, ok := auth.ValidateTOTP(enrollment.Secret, code, time.Now())
+	if !ok || step <= enrollment.LastUsedStep {
+		return utils.ErrInvalidMFACode
+	}
+
+	used, err := s.mfaRepo.UseTOTPStep(ctx, enrollment.UserID, step)
+	if err != nil {
+		slog.Error("error al registrar código TOTP", "user_id", enrollment.UserID, "error", err)
+		return utils.ErrInternal
+	}
+	if !used {
+		return utils.ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (s *UserService) useRecoveryCode(ctx context.Context, userID, code string) error {
+	used, err := s.mfaRepo.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		slog.Error("error al usar código de recuperación", "user_id", userID, "error", err)
+		return utils.ErrInternal
+	}
+	if !used {
+		return utils.ErrInvalidMFACode
+	}
+	slog.Warn("login con código de recuperación", "user_id", userID)
+	return nil
+}
+
+// getTOTP devuelve un enrolamiento vacío si el usuario nunca configuró doble factor
+func (s *UserService) getTOTP(ctx context.Context, userID string) (domain.TOTPEnrollment, error) {
+	enrollment, err := s.mfaRepo.GetTOTP(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.TOTPEnrollment{UserID: userID}, nil
+	}
+	if err != nil {
+		slog.Error("error al leer doble factor", "user_id", userID, "error", err)
+		return domain.TOTPEnrollment{}, utils.ErrInternal
+	}
+	return enrollment, nil
+}
+
+func (s *UserService) enabledTOTP(ctx context.Context, userID string) (domain.TOTPEnrollment, error) {
+	enrollment, err := s.getTOTP(ctx, userID)
+	if err != nil {
+		return enrollment, err
+	}
+	if enrollment.EnabledAt == nil {
+		return enrollment, utils.ErrMFANotEnrolled
+	}
+	return enrollment, nil
+}
+
+// newRecoveryCodes genera los códigos que se muestran una sola vez y sus hashes para guardar
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		codes[i] = raw[:4] + "-" + raw[4:]
+		hashes[i] = hashToken(raw)
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// Permisos que equivalen a ser admin: con ellos se pueden dar otros permisos o tomar otras cuentas
+var privilegedPermissions = []string{domain.PermRolesManage, domain.PermUsersManage, domain.PermAPIKeysManage}
+
+// mfaRequiredForRole: los admins y cualquier rol con permisos privilegiados siempre; los drivers si
+// MFA_REQUIRED_FOR_DRIVERS=true. Si no se pueden leer los permisos del rol, se exige.
+func mfaRequiredForRole(ctx context.Context, role string) bool {
+	switch role {
+	case domain.AdminRole:
+		return true
+	case "driver":
+		if required, _ := strconv.ParseBool(os.Getenv("MFA_REQUIRED_FOR_DRIVERS")); required {
+			return true
+		}
+	}
+
+	return roleIsPrivileged(ctx, role)
+}
+
+// roleIsPrivileged: admin o un rol con alguno de los permisos privilegiados. Si no se pueden leer
+// los permisos del rol se lo trata como privilegiado.
+func roleIsPrivileged(ctx context.Context, role string) bool {
+	if role == domain.AdminRole {
+		return true
+	}
+	permissions, err := policy.RolePermissions(ctx, role)
+	if err != nil {
+		slog.Error("error al leer permisos del rol", "role", role, "error", err)
+		return true
+	}
+	for _, p := range privilegedPermissions {
+		if permissions[p] {
+			return true
+		}
+	}
+	return false
+}
+
+func mfaChallengeAttemptsKey(tokenHash string) string {
+	return "mfa_challenge:" + tokenHash
+}
+
+func mfaFailuresKey(userID string) string {
+	return "mfa_failures:" + userID
+}
+
+func getMFAMaxFailures() int {
+	max, err := strconv.Atoi(os.Getenv("MFA_MAX_FAILURES"))
+	if err == nil && max > 0 {
+		return max
+	}
+	return defaultMFAMaxFailures
+}
+
+func getMFALockout() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv("MFA_LOCKOUT_HOURS"))
+	if err == nil && hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return defaultMFALockout
+}
+
+func getMFAIssuer() string {
+	if issuer := os.Getenv("MFA_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return defaultMFAIssuer
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"abcd-efgh", "abcdefgh"},
+		{"ABCD-EFGH", "abcdefgh"},
+		{"  abcd-efgh\n", "abcdefgh"},
+		{"abcd efgh", "abcdefgh"},
+		{"abcdefgh", "abcdefgh"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeRecoveryCode(tt.in); got != tt.want {
+			t.Errorf("normalizeRecoveryCode(%q) = %q, se esperaba %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("códigos/hashes = %d/%d, se esperaban %d", len(codes), len(hashes), recoveryCodeCount)
+	}
+
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if len(code) != 9 || code[4] != '-' {
+			t.Errorf("código %q sin el formato xxxx-xxxx", code)
+		}
+		// El código tal como se muestra, tipeado en mayúsculas, tiene que dar el hash guardado
+		if got := hashToken(normalizeRecoveryCode(" " + strings.ToUpper(code) + " ")); got != hashes[i] {
+			t.Errorf("el código %q normalizado no coincide con su hash", code)
+		}
+		if seen[code] {
+			t.Errorf("código repetido %q", code)
+		}
+		seen[code] = true
+	}
+}
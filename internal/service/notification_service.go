@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+	"tracking/internal/domain"
+	"tracking/internal/dto"
+	"tracking/internal/notification"
+	"tracking/internal/repository"
+	"tracking/internal/utils"
+)
+
+// Plantilla que se usa para cada tipo de evento de pedido. Los eventos que no figuran no se notifican.
+var notificationTemplates = map[string]string{
+	domain.OrderEventAccepted:         notification.TemplateOrderAccepted,
+	domain.OrderEventPickedUp:         notification.TemplateOrderPickedUp,
+	domain.OrderEventArrivedAtDropoff: notification.TemplateDriverArriving,
+	domain.OrderEventDelivered:        notification.TemplateOrderDelivered,
+	domain.OrderEventCancelled:        notification.TemplateOrderCancelled,
+}
+
+const (
+	notificationSweepInterval = time.Minute
+	notificationSweepBatch    = 50
+	defaultNotificationRetry  = 5 * time.Minute
+	defaultNotificationMaxAge = 24 * time.Hour
+)
+
+type NotificationServiceInterface interface {
+	GetPreferences(ctx context.Context, userID string) (domain.NotificationPreferences, error)
+	UpdatePreferences(ctx context.Context, userID string, req dto.UpdateNotificationPreferencesRequest) (domain.NotificationPreferences, error)
+	ListNotifications(ctx context.Context, userID, orderID, status string) ([]domain.Notification, error)
+	CapturedEmails() []dto.CapturedEmailResponse
+	Publish(ctx context.Context, event domain.OrderEvent) error
+}
+type NotificationService struct {
+	repo      repository.NotificationRepositoryInterface
+	orderRepo repository.OrderRepositoryInterface
+	userRepo  repository.UserRepositoryInterface
+	senders   map[string]notification.Sender
+}
+
+func NewNotificationService(repo repository.NotificationRepositoryInterface, orderRepo repository.OrderRepositoryInterface, userRepo repository.UserRepositoryInterface, senders map[string]notification.Sender) *NotificationService {
+	return &NotificationService{
+		repo:      repo,
+		orderRepo: orderRepo,
+		userRepo:  userRepo,
+		senders:   senders,
+	}
+}
+
+func (s *NotificationService) GetPreferences(ctx context.Context, userID string) (domain.NotificationPreferences, error) {
+	prefs, err := s.repo.GetPreferences(ctx, userID)
+	if err != nil {
+		slog.Error("error al obtener preferencias de notificación", "user_id", userID, "error", err)
+		return prefs, utils.ErrInternal
+	}
+	return prefs, nil
+}
+
+func (s *NotificationService) UpdatePreferences(ctx context.Context, userID string, req dto.UpdateNotificationPreferencesRequest) (domain.NotificationPreferences, error) {
+	prefs, err := s.GetPreferences(ctx, userID)
+	if err != nil {
+		return prefs, err
+	}
+
+	if req.Locale != nil {
+		if !notification.IsSupportedLocale(*req.Locale) {
+			return prefs, utils.ErrInvalidLocale
+		}
+		prefs.Locale = *req.Locale
+	}
+	if req.EmailEnabled != nil {
+		prefs.EmailEnabled = *req.EmailEnabled
+	}
+	if req.SMSEnabled != nil {
+		prefs.SMSEnabled = *req.SMSEnabled
+	}
+	if req.PushEnabled != nil {
+		prefs.PushEnabled = *req.PushEnabled
+	}
+	if req.Phone != nil {
+		prefs.Phone = *req.Phone
+	}
+	if req.PushToken != nil {
+		prefs.PushToken = *req.PushToken
+	}
+
+	if prefs.SMSEnabled && prefs.Phone == "" {
+		return prefs, utils.ErrPhoneRequired
+	}
+	if prefs.PushEnabled && prefs.PushToken == "" {
+		return prefs, utils.ErrPushTokenRequired
+	}
+
+	if err := s.repo.SavePreferences(ctx, &prefs); err != nil {
+		slog.Error("error al guardar preferencias de notificación", "user_id", userID, "error", err)
+		return prefs, utils.ErrInternal
+	}
+	return prefs, nil
+}
+
+func (s *NotificationService) ListNotifications(ctx context.Context, userID, orderID, status string) ([]domain.Notification, error) {
+	notifications, err := s.repo.List(ctx, userID, orderID, status)
+	if err != nil {
+		slog.Error("error al listar notificaciones", "user_id", userID, "error", err)
+		return nil, utils.ErrInternal
+	}
+	return notifications, nil
+}
+
+// CapturedEmails devuelve los emails retenidos cuando el canal de email usa el driver "capture".
+// Los que llevan links de acceso salen sin cuerpo: con el link se puede tomar la cuenta.
+func (s *NotificationService) CapturedEmails() []dto.CapturedEmailResponse {
+	capture, ok := s.senders[notification.ChannelEmail].(*notification.CaptureSender)
+	if !ok {
+		return []dto.CapturedEmailResponse{}
+	}
+
+	messages := capture.Messages()
+	out := make([]dto.CapturedEmailResponse, 0, len(messages))
+	for i := len(messages) - 1; i >= 0; i-- {
+		m := messages[i]
+		email := dto.CapturedEmailResponse{To: m.To, Subject: m.Subject, Body: m.Body, SentAt: m.SentAt}
+		if m.Sensitive {
+			email.Body = ""
+			email.Redacted = true
+		}
+		out = append(out, email)
+	}
+	return out
+}
+
+// Publish recibe los eventos del stream y avisa al cliente (y al repartidor si se cancela
+// un pedido que ya tenía asignado) por los canales que tenga activos.
+func (s *NotificationService) Publish(ctx context.Context, event domain.OrderEvent) error {
+	templateKey, ok := notificationTemplates[event.Type]
+	if !ok {
+		return nil
+	}
+
+	order, err := s.orderRepo.GetOrderById(ctx, event.OrderID)
+	if err != nil {
+		return err
+	}
+
+	recipients := []string{order.CustomerID}
+	if event.Type == domain.OrderEventCancelled {
+		if driverID, _ := event.Data["driver_id"].(string); driverID != "" {
+			recipients = append(recipients, driverID)
+		}
+	}
+
+	for _, userID := range recipients {
+		// Quien hizo la acción no necesita que le avisemos
+		if userID == event.ActorID {
+			continue
+		}
+		if err := s.notifyUser(ctx, userID, templateKey, event, order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *NotificationService) notifyUser(ctx context.Context, userID, templateKey string, event domain.OrderEvent, order domain.Order) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	prefs, err := s.repo.GetPreferences(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	data := notification.TemplateData{
+		Name:       user.FullName,
+		OrderID:    order.ID,
+		DriverName: order.DriverName,
+		Address:    order.DestinationAddress,
+	}
+	subject, body, err := notification.Render(templateKey, prefs.Locale, data)
+	if err != nil {
+		return err
+	}
+
+	targets := map[string]string{}
+	if prefs.EmailEnabled {
+		targets[notification.ChannelEmail] = user.Email
+	}
+	if prefs.SMSEnabled && prefs.Phone != "" {
+		targets[notification.ChannelSMS] = prefs.Phone
+	}
+	if prefs.PushEnabled && prefs.PushToken != "" {
+		targets[notification.ChannelPush] = prefs.PushToken
+	}
+
+	for channel, recipient := range targets {
+		n := domain.Notification{
+			UserID:    userID,
+			OrderID:   order.ID,
+			EventID:   event.ID,
+			Channel:   channel,
+			Template:  templateKey,
+			Recipient: recipient,
+			Subject:   subject,
+			Body:      body,
+		}
+		created, err := s.repo.CreateOnce(ctx, &n)
+		if err != nil {
+			return err
+		}
+		if !created {
+			continue
+		}
+		s.send(ctx, n)
+	}
+	return nil
+}
+
+// send entrega por el canal y deja registrado el resultado. Un canal caído no frena
+// al resto ni reintenta el evento: el envío queda en FAILED con el motivo.
+func (s *NotificationService) send(ctx context.Context, n domain.Notification) {
+	sender, ok := s.senders[n.Channel]
+	if !ok {
+		s.markFailed(ctx, n, "canal no configurado")
+		return
+	}
+
+	msg := notification.Message{To: n.Recipient, Subject: n.Subject, Body: n.Body}
+	if err := sender.Send(ctx, msg); err != nil {
+		s.markFailed(ctx, n, err.Error())
+		return
+	}
+
+	if err := s.repo.MarkSent(ctx, n.ID); err != nil {
+		slog.Error("error al marcar notificación enviada", "notification_id", n.ID, "error", err)
+	}
+}
+
+// RunPendingSweeper reintenta los envíos que quedaron en PENDING, por ejemplo porque el proceso
+// se cayó entre registrarlos y mandarlos. El registro ya existe, así que el consumidor del stream
+// no los vuelve a generar.
+func (s *NotificationService) RunPendingSweeper(ctx context.Context) {
+	ticker := time.NewTicker(notificationSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.retryPending(ctx)
+		}
+	}
+}
+
+func (s *NotificationService) retryPending(ctx context.Context) {
+	expired, err := s.repo.ExpirePending(ctx, time.Now().Add(-getNotificationMaxAge()))
+	if err != nil {
+		slog.Error("error al vencer notificaciones pendientes", "error", err)
+	} else if expired > 0 {
+		slog.Warn("notificaciones pendientes vencidas sin enviar", "count", expired)
+	}
+
+	pending, err := s.repo.ClaimStalePending(ctx, time.Now().Add(-getNotificationRetryAfter()), notificationSweepBatch)
+	if err != nil {
+		slog.Error("error al tomar notificaciones pendientes", "error", err)
+		return
+	}
+	for _, n := range pending {
+		slog.Info("reintentando notificación pendiente", "notification_id", n.ID, "channel", n.Channel)
+		s.send(ctx, n)
+	}
+}
+
+func (s *NotificationService) markFailed(ctx context.Context, n domain.Notification, reason string) {
+	slog.Warn("falló el envío de notificación", "notification_id", n.ID, "channel", n.Channel, "error", reason)
+	if err := s.repo.MarkFailed(ctx, n.ID, reason); err != nil {
+		slog.Error("error al marcar notificación fallida", "notification_id", n.ID, "error", err)
+	}
+}
+
+func getNotificationRetryAfter() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("NOTIFY_RETRY_AFTER_MINUTES"))
+	if err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultNotificationRetry
+}
+
+func getNotificationMaxAge() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv("NOTIFY_MAX_AGE_HOURS"))
+	if err == nil && hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return defaultNotificationMaxAge
+}
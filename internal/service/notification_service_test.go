@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"tracking/internal/notification"
+)
+
+func TestCapturedEmailsRedactsSensitiveBodies(t *testing.T) {
+	capture := notification.NewCaptureSender(10)
+	ctx := context.Background()
+	capture.Send(ctx, notification.Message{To: "cliente@example.com", Subject: "Pedido entregado", Body: "Tu pedido llegó"})
+	capture.Send(ctx, notification.Message{To: "admin@example.com", Subject: "Restablecer contraseña", Body: "https://app/reset?token=secreto", Sensitive: true})
+
+	svc := &NotificationService{senders: map[string]notification.Sender{notification.ChannelEmail: capture}}
+	emails := svc.CapturedEmails()
+	if len(emails) != 2 {
+		t.Fatalf("emails = %d, se esperaban 2", len(emails))
+	}
+
+	// Del más nuevo al más viejo
+	if emails[0].Body != "" || !emails[0].Redacted || emails[0].Subject != "Restablecer contraseña" {
+		t.Errorf("el email de reseteo no se ocultó: %+v", emails[0])
+	}
+	if emails[1].Body != "Tu pedido llegó" || emails[1].Redacted {
+		t.Errorf("el email del pedido no debería ocultarse: %+v", emails[1])
+	}
+}
@@ -3,12 +3,16 @@ package service
 import (
 	"context"
 	"errors"
+	"time"
 
+	"tracking/internal/domain"
 	"tracking/internal/dto"
 	"tracking/internal/repository"
 
 	"log/slog"
 	"tracking/internal/utils"
+
+	"github.com/jackc/pgx/v5"
 )
 
 const (
@@ -24,30 +28,62 @@ type OrderServiceInterface interface {
 	GetOrderById(ctx context.Context, id string) (dto.OrderResponse, error)
 	CompleteOrder(ctx context.Context, orderID string, driverID string) error
 	GetUserHistory(ctx context.Context, userID string) ([]dto.OrderResponse, error)
+	GetOrderTimeline(ctx context.Context, orderID string) ([]dto.OrderEventResponse, error)
+	PickUpOrder(ctx context.Context, orderID string, driverID string) error
+	CancelOrder(ctx context.Context, orderID string, userID string, role string, anyOrder bool) error
+	AddTip(ctx context.Context, orderID string, customerID string, amount float64) error
+	ListDeliverySlots(ctx context.Context, date string) ([]dto.DeliverySlotResponse, error)
 }
 type OrderService struct {
 	repo        repository.OrderRepositoryInterface
 	productRepo repository.ProductRepositoryInterface
 	userRepo    repository.UserRepositoryInterface
+	eventRepo   repository.OrderEventRepositoryInterface
+	addressRepo repository.AddressRepositoryInterface
+	storeRepo   repository.StoreRepositoryInterface
 }
 
-func NewOrderService(repo repository.OrderRepositoryInterface, prodRepo repository.ProductRepositoryInterface, userRepo repository.UserRepositoryInterface) *OrderService {
+func NewOrderService(repo repository.OrderRepositoryInterface, prodRepo repository.ProductRepositoryInterface, userRepo repository.UserRepositoryInterface, eventRepo repository.OrderEventRepositoryInterface, addressRepo repository.AddressRepositoryInterface, storeRepo repository.StoreRepositoryInterface) *OrderService {
 	return &OrderService{
 		repo:        repo,
 		productRepo: prodRepo,
 		userRepo:    userRepo,
+		eventRepo:   eventRepo,
+		addressRepo: addressRepo,
+		storeRepo:   storeRepo,
 	}
 }
 func (s *OrderService) CreateOrder(ctx context.Context, req dto.CreateOrderRequest, customerID string) (string, error) {
+	// Las cuentas sin email verificado no pueden pedir (evita pedidos de registros falsos)
+	customer, err := s.userRepo.GetByID(ctx, customerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", utils.ErrUserNotFound
+	}
+	if err != nil {
+		slog.Error("error al obtener cliente para el pedido", "user_id", customerID, "error", err)
+		return "", utils.ErrInternal
+	}
+	if customer.EmailVerifiedAt == nil {
+		return "", utils.ErrEmailNotVerified
+	}
+
 	order := utils.ToOrderDomain(req, customerID)
 
-	lat, lng, err := s.GetCoordinates(order.DestinationAddress)
+	now := time.Now()
+	cal, err := loadStoreCalendar(ctx, s.storeRepo, now, getScheduleMaxDaysAhead())
 	if err != nil {
-		slog.Error("error geocoding", "address", order.DestinationAddress, "error", err)
-		return "", utils.ErrInvalidAddress
+		return "", err
+	}
+	// Los pedidos inmediatos requieren el local abierto; los programados solo que no esté en pausa
+	if req.ScheduledFor == nil || cal.pause.Paused {
+		if err := storeUnavailableError(cal, now); err != nil {
+			return "", err
+		}
+	}
+
+	if err := s.resolveDestination(ctx, order); err != nil {
+		return "", err
 	}
-	order.DestLat = lat
-	order.DestLng = lng
 
 	order.OriginLat = defaultOriginLat
 	order.OriginLng = defaultOriginLng
@@ -65,7 +101,61 @@ func (s *OrderService) CreateOrder(ctx context.Context, req dto.CreateOrderReque
 
 	order.TotalPrice = totalPrice
 
-	return s.repo.CreateWithItems(ctx, order)
+	eventData := map[string]interface{}{
+		"total_price": order.TotalPrice,
+	}
+	if order.TipAmount > 0 {
+		eventData["tip_amount"] = order.TipAmount
+	}
+
+	if req.ScheduledFor != nil {
+		if err := validateDeliveryWindow(cal, *req.ScheduledFor, now); err != nil {
+			return "", err
+		}
+		start, end := req.ScheduledFor.Start.UTC(), req.ScheduledFor.End.UTC()
+		order.ScheduledStart = &start
+		order.ScheduledEnd = &end
+		eventData["scheduled_start"] = start
+		eventData["scheduled_end"] = end
+
+		event := newOrderEvent(domain.OrderEventCreated, customerID, eventData)
+		return s.repo.CreateScheduledWithItems(ctx, order, event, getSlotCapacity())
+	}
+
+	event := newOrderEvent(domain.OrderEventCreated, customerID, eventData)
+	return s.repo.CreateWithItems(ctx, order, event)
+}
+
+// resolveDestination completa las coordenadas del destino: con una dirección guardada usa las ya
+// geocodificadas y copia depto e indicaciones; con texto libre consulta a Nominatim.
+func (s *OrderService) resolveDestination(ctx context.Context, order *domain.Order) error {
+	if order.AddressID != "" {
+		address, err := s.addressRepo.GetByID(ctx, order.AddressID, order.CustomerID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return utils.ErrAddressNotFound
+		}
+		if err != nil {
+			return err
+		}
+		order.DestinationAddress = address.Address
+		order.Apartment = address.Apartment
+		order.DeliveryInstructions = address.Instructions
+		order.DestLat = address.Lat
+		order.DestLng = address.Lng
+		return nil
+	}
+
+	if order.DestinationAddress == "" {
+		return utils.ErrDestinationRequired
+	}
+	lat, lng, err := s.GetCoordinates(ctx, order.DestinationAddress)
+	if err != nil {
+		slog.Error("error geocoding", "address", order.DestinationAddress, "error", err)
+		return utils.ErrInvalidAddress
+	}
+	order.DestLat = lat
+	order.DestLng = lng
+	return nil
 }
 func (s *OrderService) GetPendingOrders(ctx context.Context) ([]dto.OrderResponse, error) {
 	orders, err := s.repo.GetPending(ctx)
@@ -101,7 +191,11 @@ func (s *OrderService) AcceptOrder(ctx context.Context, orderID string, driverID
 	if order.Status != "PENDING" {
 		return utils.ErrOrderNotAvailable
 	}
-	return s.repo.AcceptOrder(ctx, orderID, driverID)
+	// Un pedido programado no se puede tomar antes de que el scheduler lo libere
+	if order.ScheduledStart != nil && order.ReleasedAt == nil {
+		return utils.ErrOrderNotAvailable
+	}
+	return s.repo.AcceptOrder(ctx, orderID, driverID, newOrderEvent(domain.OrderEventAccepted, driverID, nil))
 }
 func (s *OrderService) GetOrderById(ctx context.Context, id string) (dto.OrderResponse, error) {
 	order, err := s.repo.GetOrderById(ctx, id)
@@ -133,11 +227,12 @@ func (s *OrderService) CompleteOrder(ctx context.Context, orderID string, driver
 		return utils.ErrUnauthorizedAction
 	}
 
-	if order.Status != "ASSIGNED" {
+	if order.Status != "ASSIGNED" && order.Status != "PICKED_UP" {
 		return utils.ErrInvalidState
 	}
 
-	err = s.repo.CompleteOrder(ctx, orderID, driverID)
+	earning := newDeliveryEarning(order, driverID, getCommissionModel())
+	err = s.repo.CompleteOrder(ctx, orderID, driverID, earning, newOrderEvent(domain.OrderEventDelivered, driverID, nil))
 	if err != nil {
 		slog.Error("error técnico al completar orden", "order_id", orderID, "error", err)
 		return utils.ErrInternal
@@ -145,6 +240,80 @@ func (s *OrderService) CompleteOrder(ctx context.Context, orderID string, driver
 
 	return nil
 }
+func (s *OrderService) PickUpOrder(ctx context.Context, orderID string, driverID string) error {
+	order, err := s.repo.GetOrderById(ctx, orderID)
+	if err != nil {
+		return utils.ErrOrderNotFound
+	}
+
+	if order.DriverID != driverID {
+		slog.Warn("intento de retirar orden ajena", "order_id", orderID, "driver_id", driverID)
+		return utils.ErrUnauthorizedAction
+	}
+
+	if order.Status != "ASSIGNED" {
+		return utils.ErrInvalidState
+	}
+
+	return s.repo.PickUpOrder(ctx, orderID, driverID, newOrderEvent(domain.OrderEventPickedUp, driverID, nil))
+}
+
+// CancelOrder permite al cliente cancelar su pedido mientras sigue PENDING.
+// Con anyOrder (permiso orders:cancel:any) se puede cancelar cualquier pedido que todavía no fue entregado.
+func (s *OrderService) CancelOrder(ctx context.Context, orderID string, userID string, role string, anyOrder bool) error {
+	order, err := s.repo.GetOrderById(ctx, orderID)
+	if err != nil {
+		return utils.ErrOrderNotFound
+	}
+
+	if !anyOrder {
+		if order.CustomerID != userID {
+			slog.Warn("intento de cancelar orden ajena", "order_id", orderID, "user_id", userID)
+			return utils.ErrUnauthorizedAction
+		}
+		if order.Status != "PENDING" {
+			return utils.ErrInvalidState
+		}
+	}
+
+	return s.repo.CancelOrder(ctx, orderID, userID, anyOrder, newOrderEvent(domain.OrderEventCancelled, userID, map[string]interface{}{
+		"previous_status": order.Status,
+		"cancelled_by":    role,
+	}))
+}
+
+// AddTip deja una propina para el repartidor después de la entrega. Se puede usar una sola vez
+// por pedido, aunque ya se haya dejado propina al pedir.
+func (s *OrderService) AddTip(ctx context.Context, orderID string, customerID string, amount float64) error {
+	order, err := s.repo.GetOrderById(ctx, orderID)
+	if err != nil {
+		return utils.ErrOrderNotFound
+	}
+
+	if order.CustomerID != customerID {
+		slog.Warn("intento de dejar propina en orden ajena", "order_id", orderID, "user_id", customerID)
+		return utils.ErrUnauthorizedAction
+	}
+	if order.Status != "DELIVERED" {
+		return utils.ErrTipNotAllowed
+	}
+	if order.TipAddedAt != nil {
+		return utils.ErrTipAlreadyAdded
+	}
+
+	earning := &domain.DriverEarning{
+		DriverID:  order.DriverID,
+		OrderID:   orderID,
+		Kind:      domain.EarningKindTip,
+		TipAmount: amount,
+		Amount:    amount,
+	}
+	return s.repo.AddTip(ctx, orderID, customerID, earning, newOrderEvent(domain.OrderEventTipAdded, customerID, map[string]interface{}{
+		"amount":    amount,
+		"driver_id": order.DriverID,
+	}))
+}
+
 func (s *OrderService) GetUserHistory(ctx context.Context, userID string) ([]dto.OrderResponse, error) {
 	orders, err := s.repo.GetHistory(ctx, userID)
 	if err != nil {
@@ -157,3 +326,20 @@ func (s *OrderService) GetUserHistory(ctx context.Context, userID string) ([]dto
 	}
 	return utils.SliceOrderDomainToOrderResponseListDto(orders), nil
 }
+func (s *OrderService) GetOrderTimeline(ctx context.Context, orderID string) ([]dto.OrderEventResponse, error) {
+	events, err := s.eventRepo.ListByOrder(ctx, orderID)
+	if err != nil {
+		slog.Error("error al obtener timeline", "order_id", orderID, "error", err)
+		return nil, utils.ErrInternal
+	}
+	return utils.SliceOrderEventDomainToResponseDto(events), nil
+}
+
+// newOrderEvent arma el evento que el repositorio guarda junto con la transición
+func newOrderEvent(eventType, actorID string, data map[string]interface{}) *domain.OrderEvent {
+	return &domain.OrderEvent{
+		Type:    eventType,
+		ActorID: actorID,
+		Data:    data,
+	}
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"time"
+	"tracking/internal/domain"
+	"tracking/internal/repository"
+)
+
+const (
+	outboxPollInterval    = time.Second
+	outboxBatchSize       = 100
+	outboxRetention       = 7 * 24 * time.Hour
+	outboxCleanupInterval = time.Hour
+
+	// Filas reclamadas por un relay que no las marcó en este tiempo se vuelven a publicar
+	outboxClaimTimeout = time.Minute
+
+	streamReadCount  = 50
+	streamReadBlock  = 5 * time.Second
+	streamClaimIdle  = time.Minute
+	streamRetryPause = time.Second
+)
+
+// OutboxRelay publica en Redis Streams los eventos que las transacciones dejaron en el outbox.
+// La entrega es at-least-once: si se cae entre XADD y el UPDATE, el evento se publica de nuevo
+// cuando vence su reclamo (outboxClaimTimeout).
+type OutboxRelay struct {
+	repo   repository.OutboxRepositoryInterface
+	stream repository.EventStreamRepositoryInterface
+}
+
+func NewOutboxRelay(repo repository.OutboxRepositoryInterface, stream repository.EventStreamRepositoryInterface) *OutboxRelay {
+	return &OutboxRelay{repo: repo, stream: stream}
+}
+
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+	lastCleanup := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.relayPending(ctx)
+
+			if time.Since(lastCleanup) > outboxCleanupInterval {
+				lastCleanup = time.Now()
+				if _, err := r.repo.DeletePublishedBefore(ctx, time.Now().Add(-outboxRetention)); err != nil {
+					slog.Error("error al limpiar outbox", "error", err)
+				}
+			}
+		}
+	}
+}
+
+func (r *OutboxRelay) relayPending(ctx context.Context) {
+	for {
+		published, claimed, err := r.relayBatch(ctx)
+		if err != nil {
+			slog.Error("error al publicar outbox", "error", err)
+			return
+		}
+		// Si el lote vino completo y salió entero probablemente quedan más pendientes
+		if claimed < outboxBatchSize || published < claimed {
+			return
+		}
+	}
+}
+
+// relayBatch reclama un lote, publica en orden y marca cada evento publicado. Si uno falla, ese y
+// los que siguen vuelven a PENDING para no desordenar los eventos.
+func (r *OutboxRelay) relayBatch(ctx context.Context) (int, int, error) {
+	entries, err := r.repo.ClaimPending(ctx, outboxBatchSize, time.Now().Add(-outboxClaimTimeout))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for i, entry := range entries {
+		var event domain.OrderEvent
+		publishErr := json.Unmarshal(entry.Payload, &event)
+		if publishErr == nil {
+			publishErr = r.stream.Append(ctx, event)
+		}
+
+		if publishErr != nil {
+			ids := make([]string, 0, len(entries)-i)
+			for _, rest := range entries[i:] {
+				ids = append(ids, rest.ID)
+			}
+			if err := r.repo.Release(ctx, ids, publishErr.Error()); err != nil {
+				return i, len(entries), err
+			}
+			return i, len(entries), nil
+		}
+
+		if err := r.repo.MarkPublished(ctx, entry.ID); err != nil {
+			return i, len(entries), err
+		}
+	}
+	return len(entries), len(entries), nil
+}
+
+// StreamConsumer lee el stream de eventos con su propio consumer group y se los pasa a handler.
+// Solo hace XACK cuando handler termina bien; los fallidos se reclaman y reintentan,
+// por lo que handler tiene que ser idempotente (el ID del evento sirve para deduplicar).
+type StreamConsumer struct {
+	stream   repository.EventStreamRepositoryInterface
+	group    string
+	consumer string
+	handler  EventPublisher
+}
+
+func NewStreamConsumer(stream repository.EventStreamRepositoryInterface, group string, handler EventPublisher) *StreamConsumer {
+	consumer, err := os.Hostname()
+	if err != nil || consumer == "" {
+		consumer = "tracking-api"
+	}
+	return &StreamConsumer{stream: stream, group: group, consumer: consumer, handler: handler}
+}
+
+func (c *StreamConsumer) Run(ctx context.Context) {
+	for {
+		if err := c.stream.EnsureGroup(ctx, c.group); err != nil {
+			slog.Error("error al crear consumer group", "group", c.group, "error", err)
+			if !sleepOrDone(ctx, streamRetryPause) {
+				return
+			}
+			continue
+		}
+		break
+	}
+
+	for ctx.Err() == nil {
+		stale, err := c.stream.ClaimStale(ctx, c.group, c.consumer, streamClaimIdle, streamReadCount)
+		if err != nil {
+			slog.Error("error al reclamar eventos pendientes", "group", c.group, "error", err)
+		}
+		c.handle(ctx, stale)
+
+		events, err := c.stream.ReadGroup(ctx, c.group, c.consumer, streamReadCount, streamReadBlock)
+		if err != nil {
+			slog.Error("error al leer stream de eventos", "group", c.group, "error", err)
+			if !sleepOrDone(ctx, streamRetryPause) {
+				return
+			}
+			continue
+		}
+		c.handle(ctx, events)
+	}
+}
+
+func (c *StreamConsumer) handle(ctx context.Context, events []repository.StreamEvent) {
+	for _, e := range events {
+		if err := c.process(ctx, e.Event); err != nil {
+			slog.Error("error al procesar evento", "group", c.group, "event_id", e.Event.ID, "type", e.Event.Type, "error", err)
+			continue
+		}
+
+		if err := c.stream.Ack(ctx, c.group, e.MessageID); err != nil {
+			slog.Error("error al confirmar evento", "group", c.group, "message_id", e.MessageID, "error", err)
+		}
+	}
+}
+
+// process ignora los eventos que el group ya procesó, así un duplicado del relay no se
+// vuelve a notificar.
+func (c *StreamConsumer) process(ctx context.Context, event domain.OrderEvent) error {
+	if event.ID == "" {
+		slog.Warn("evento ilegible descartado", "group", c.group)
+		return nil
+	}
+
+	done, err := c.stream.WasProcessed(ctx, c.group, event.ID)
+	if err != nil {
+		return err
+	}
+	if done {
+		return nil
+	}
+
+	if err := c.handler.Publish(ctx, event); err != nil {
+		return err
+	}
+	return c.stream.MarkProcessed(ctx, c.group, event.ID)
+}
+
+// LocationCleanupHandler borra la posición en vivo del driver cuando el pedido termina
+type LocationCleanupHandler struct {
+	repo repository.LocationRepositoryInterface
+}
+
+func NewLocationCleanupHandler(repo repository.LocationRepositoryInterface) *LocationCleanupHandler {
+	return &LocationCleanupHandler{repo: repo}
+}
+
+func (h *LocationCleanupHandler) Publish(ctx context.Context, event domain.OrderEvent) error {
+	var driverID string
+	switch event.Type {
+	case domain.OrderEventDelivered:
+		driverID = event.ActorID
+	case domain.OrderEventCancelled:
+		driverID, _ = event.Data["driver_id"].(string)
+	}
+	if driverID == "" {
+		return nil
+	}
+	return h.repo.DeleteDriverLocation(ctx, driverID)
+}
+
+func sleepOrDone(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
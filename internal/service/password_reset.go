@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+	"tracking/internal/notification"
+	"tracking/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultPasswordResetTTL = 30 * time.Minute
+	defaultPasswordResetURL = "http://localhost:8081/reset-password"
+
+	// Pedidos de reseteo por email y por hora; los que sobran se descartan en silencio
+	forgotPasswordLimitPerHour = 3
+
+	// Tiempo máximo para generar y enviar el link en segundo plano
+	forgotPasswordTimeout = 30 * time.Second
+)
+
+// ForgotPassword manda por email un link de un solo uso para elegir otra contraseña.
+// Responde al instante y siempre igual, exista o no la cuenta: el trabajo se hace en segundo
+// plano para que ni el código de respuesta ni la demora revelen qué emails están registrados.
+func (s *UserService) ForgotPassword(ctx context.Context, email string) error {
+	email = normalizeEmail(email)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), forgotPasswordTimeout)
+		defer cancel()
+		s.sendPasswordReset(ctx, email)
+	}()
+	return nil
+}
+
+// sendPasswordReset arma y envía el link. Los errores solo se registran en el log.
+func (s *UserService) sendPasswordReset(ctx context.Context, email string) {
+	count, _, err := s.guard.limiter.Hit(ctx, "forgot-password:"+email, time.Hour)
+	if err != nil {
+		slog.Error("error al contar pedidos de reseteo", "email", email, "error", err)
+		return
+	}
+	if count > forgotPasswordLimitPerHour {
+		slog.Warn("pedido de reseteo descartado por límite", "email", email)
+		return
+	}
+
+	user, err := s.repo.GetByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("error al buscar usuario para reseteo", "email", email, "error", err)
+		}
+		return
+	}
+	if !user.IsActive {
+		return
+	}
+
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		slog.Error("error al generar token de reseteo", "user_id", user.ID, "error", err)
+		return
+	}
+	if err := s.tokenRepo.StorePasswordReset(ctx, user.ID, tokenHash, getPasswordResetTTL()); err != nil {
+		slog.Error("error al guardar token de reseteo", "user_id", user.ID, "error", err)
+		return
+	}
+
+	subject, body, err := notification.Render(notification.TemplatePasswordReset, notification.DefaultLocale, notification.TemplateData{
+		Name: user.FullName,
+		Link: linkWithToken(getPasswordResetURL(), token),
+	})
+	if err != nil {
+		slog.Error("error al armar email de reseteo", "user_id", user.ID, "error", err)
+		return
+	}
+	if err := s.mailer.Send(ctx, notification.Message{To: user.Email, Subject: subject, Body: body, Sensitive: true}); err != nil {
+		slog.Error("error al enviar email de reseteo", "user_id", user.ID, "error", err)
+	}
+}
+
+// ResetPassword usa el token del email para fijar la contraseña nueva. También levanta un bloqueo
+// por intentos fallidos y cierra todas las sesiones.
+func (s *UserService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	userID, err := s.tokenRepo.ConsumePasswordReset(ctx, hashToken(token))
+	if err != nil {
+		slog.Error("error al validar token de reseteo", "error", err)
+		return utils.ErrInternal
+	}
+	if userID == "" {
+		return utils.ErrInvalidResetToken
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil || !user.IsActive {
+		return utils.ErrInvalidResetToken
+	}
+
+	if err := s.setPassword(ctx, userID, newPassword); err != nil {
+		return err
+	}
+	if err := s.guard.unlock(ctx, normalizeEmail(user.Email)); err != nil {
+		slog.Error("error al desbloquear cuenta tras reseteo", "user_id", userID, "error", err)
+	}
+	return nil
+}
+
+// ChangePassword cambia la contraseña de un usuario logueado, que tiene que confirmar la actual
+func (s *UserService) ChangePassword(ctx context.Context, userID, currentPassword, newPassword string) error {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return utils.ErrUserNotFound
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)); err != nil {
+		return utils.ErrWrongPassword
+	}
+	return s.setPassword(ctx, userID, newPassword)
+}
+
+// setPassword guarda el hash nuevo y revoca todos los tokens del usuario
+func (s *UserService) setPassword(ctx context.Context, userID, newPassword string) error {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return utils.ErrInternal
+	}
+
+	if err := s.repo.UpdatePassword(ctx, userID, string(hashed)); err != nil {
+		slog.Error("error al actualizar contraseña", "user_id", userID, "error", err)
+		return utils.ErrInternal
+	}
+	if err := s.revokeAllTokens(ctx, userID); err != nil {
+		slog.Error("error al revocar sesiones tras cambio de contraseña", "user_id", userID, "error", err)
+		return utils.ErrInternal
+	}
+	slog.Info("contraseña actualizada", "user_id", userID)
+	return nil
+}
+
+// newOpaqueToken genera un token aleatorio para mandar por email y el hash que se guarda
+func newOpaqueToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func linkWithToken(base, token string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base + "?token=" + token
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func getPasswordResetTTL() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("PASSWORD_RESET_TTL_MINUTES"))
+	if err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultPasswordResetTTL
+}
+
+func getPasswordResetURL() string {
+	if u := os.Getenv("PASSWORD_RESET_URL"); u != "" {
+		return u
+	}
+	return defaultPasswordResetURL
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"tracking/internal/domain"
+	"tracking/internal/dto"
+	"tracking/internal/policy"
+	"tracking/internal/repository"
+	"tracking/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+type PermissionServiceInterface interface {
+	ListPermissions(ctx context.Context) ([]domain.Permission, error)
+	ListRoles(ctx context.Context) ([]domain.Role, error)
+	GetRole(ctx context.Context, name string) (domain.Role, error)
+	CreateRole(ctx context.Context, req dto.CreateRoleRequest) (domain.Role, error)
+	SetRolePermissions(ctx context.Context, actorRole, name string, permissions []string) (domain.Role, error)
+	DeleteRole(ctx context.Context, name string) error
+	MyPermissions(ctx context.Context, role string) ([]string, error)
+}
+type PermissionService struct {
+	repo repository.PermissionRepositoryInterface
+}
+
+func NewPermissionService(repo repository.PermissionRepositoryInterface) *PermissionService {
+	return &PermissionService{repo: repo}
+}
+
+func (s *PermissionService) ListPermissions(ctx context.Context) ([]domain.Permission, error) {
+	permissions, err := s.repo.ListPermissions(ctx)
+	if err != nil {
+		slog.Error("error al listar permisos", "error", err)
+		return nil, utils.ErrInternal
+	}
+	return permissions, nil
+}
+
+func (s *PermissionService) ListRoles(ctx context.Context) ([]domain.Role, error) {
+	roles, err := s.repo.ListRoles(ctx)
+	if err != nil {
+		slog.Error("error al listar roles", "error", err)
+		return nil, utils.ErrInternal
+	}
+	return roles, nil
+}
+
+func (s *PermissionService) GetRole(ctx context.Context, name string) (domain.Role, error) {
+	role, err := s.repo.GetRole(ctx, name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Role{}, utils.ErrRoleNotFound
+	}
+	if err != nil {
+		slog.Error("error al obtener rol", "role", name, "error", err)
+		return domain.Role{}, utils.ErrInternal
+	}
+	return role, nil
+}
+
+// CreateRole agrega un rol (ej. dispatcher o support) con los permisos elegidos
+func (s *PermissionService) CreateRole(ctx context.Context, req dto.CreateRoleRequest) (domain.Role, error) {
+	if !roleNamePattern.MatchString(req.Name) {
+		return domain.Role{}, utils.ErrInvalidRoleName
+	}
+	if err := s.validatePermissions(ctx, req.Permissions); err != nil {
+		return domain.Role{}, err
+	}
+
+	err := s.repo.CreateRole(ctx, domain.Role{Name: req.Name, Description: req.Description, Permissions: req.Permissions})
+	if errors.Is(err, repository.ErrRoleExists) {
+		return domain.Role{}, utils.ErrRoleAlreadyExists
+	}
+	if err != nil {
+		slog.Error("error al crear rol", "role", req.Name, "error", err)
+		return domain.Role{}, utils.ErrInternal
+	}
+	slog.Info("rol creado", "role", req.Name, "permissions", req.Permissions)
+	return s.GetRole(ctx, req.Name)
+}
+
+// SetRolePermissions reemplaza los permisos del rol. El rol admin no se edita: siempre tiene todo,
+// así nadie puede quedarse sin acceso a esta misma pantalla. Tampoco se editan los permisos del rol
+// propio, para que un rol con roles:manage no pueda darse a sí mismo todo lo demás.
+func (s *PermissionService) SetRolePermissions(ctx context.Context, actorRole, name string, permissions []string) (domain.Role, error) {
+	if name == domain.AdminRole {
+		return domain.Role{}, utils.ErrAdminRoleLocked
+	}
+	if name == actorRole {
+		return domain.Role{}, utils.ErrOwnRoleLocked
+	}
+	if _, err := s.GetRole(ctx, name); err != nil {
+		return domain.Role{}, err
+	}
+	if err := s.validatePermissions(ctx, permissions); err != nil {
+		return domain.Role{}, err
+	}
+
+	if err := s.repo.SetRolePermissions(ctx, name, permissions); err != nil {
+		slog.Error("error al actualizar permisos del rol", "role", name, "error", err)
+		return domain.Role{}, utils.ErrInternal
+	}
+	policy.Invalidate()
+	slog.Info("permisos del rol actualizados", "role", name, "permissions", permissions)
+	return s.GetRole(ctx, name)
+}
+
+// DeleteRole borra un rol creado por un admin. Los roles de sistema y los que tienen usuarios no se borran.
+func (s *PermissionService) DeleteRole(ctx context.Context, name string) error {
+	role, err := s.GetRole(ctx, name)
+	if err != nil {
+		return err
+	}
+	if role.IsSystem {
+		return utils.ErrSystemRole
+	}
+	if role.UserCount > 0 {
+		return utils.ErrRoleInUse
+	}
+
+	if err := s.repo.DeleteRole(ctx, name); err != nil {
+		slog.Error("error al borrar rol", "role", name, "error", err)
+		return utils.ErrInternal
+	}
+	policy.Invalidate()
+	return nil
+}
+
+// MyPermissions devuelve los permisos del rol, para que el frontend muestre solo lo que se puede hacer
+func (s *PermissionService) MyPermissions(ctx context.Context, role string) ([]string, error) {
+	permissions, err := s.repo.RolePermissions(ctx, role)
+	if err != nil {
+		slog.Error("error al obtener permisos del rol", "role", role, "error", err)
+		return nil, utils.ErrInternal
+	}
+	return permissions, nil
+}
+
+func (s *PermissionService) validatePermissions(ctx context.Context, permissions []string) error {
+	catalog, err := s.ListPermissions(ctx)
+	if err != nil {
+		return err
+	}
+	known := map[string]bool{}
+	for _, p := range catalog {
+		known[p.Name] = true
+	}
+
+	seen := map[string]bool{}
+	for _, p := range permissions {
+		if !known[p] || seen[p] {
+			return utils.ErrInvalidPermission
+		}
+		seen[p] = true
+	}
+	return nil
+}
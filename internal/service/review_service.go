@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+	"tracking/internal/domain"
+	"tracking/internal/dto"
+	"tracking/internal/repository"
+	"tracking/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const defaultReviewWindow = 72 * time.Hour
+
+type ReviewServiceInterface interface {
+	CreateReview(ctx context.Context, orderID, customerID string, req dto.CreateReviewRequest) (domain.Review, error)
+	GetOrderReview(ctx context.Context, orderID, userID string, canReadAny bool) (domain.Review, error)
+	ListReviews(ctx context.Context, driverID string, hidden *bool) ([]domain.Review, error)
+	HideReview(ctx context.Context, reviewID, reason, adminID string) (domain.Review, error)
+	UnhideReview(ctx context.Context, reviewID string) (domain.Review, error)
+	GetDriverRating(ctx context.Context, driverID string) (domain.RatingSummary, error)
+}
+type ReviewService struct {
+	repo      repository.ReviewRepositoryInterface
+	orderRepo repository.OrderRepositoryInterface
+	eventRepo repository.OrderEventRepositoryInterface
+}
+
+func NewReviewService(repo repository.ReviewRepositoryInterface, orderRepo repository.OrderRepositoryInterface, eventRepo repository.OrderEventRepositoryInterface) *ReviewService {
+	return &ReviewService{
+		repo:      repo,
+		orderRepo: orderRepo,
+		eventRepo: eventRepo,
+	}
+}
+
+// CreateReview deja la única reseña del pedido. Solo el cliente dueño, con el pedido entregado
+// y dentro de REVIEW_WINDOW_HOURS desde la entrega.
+func (s *ReviewService) CreateReview(ctx context.Context, orderID, customerID string, req dto.CreateReviewRequest) (domain.Review, error) {
+	if err := validateReviewTags(req.Tags); err != nil {
+		return domain.Review{}, err
+	}
+
+	order, err := s.orderRepo.GetOrderById(ctx, orderID)
+	if err != nil {
+		return domain.Review{}, utils.ErrOrderNotFound
+	}
+	if order.CustomerID != customerID {
+		return domain.Review{}, utils.ErrUnauthorizedAction
+	}
+	if order.Status != "DELIVERED" {
+		return domain.Review{}, utils.ErrReviewNotAllowed
+	}
+
+	deliveredAt, err := s.deliveredAt(ctx, order)
+	if err != nil {
+		return domain.Review{}, err
+	}
+	if time.Since(deliveredAt) > getReviewWindow() {
+		return domain.Review{}, utils.ErrReviewWindowExpired
+	}
+
+	tags := req.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	review := domain.Review{
+		OrderID:      orderID,
+		CustomerID:   customerID,
+		DriverID:     order.DriverID,
+		DriverScore:  req.DriverScore,
+		ProductScore: req.ProductScore,
+		Tags:         tags,
+		Comment:      req.Comment,
+	}
+	if err := s.repo.Create(ctx, &review); err != nil {
+		if errors.Is(err, repository.ErrReviewExists) {
+			return domain.Review{}, utils.ErrReviewAlreadyExists
+		}
+		slog.Error("error al guardar reseña", "order_id", orderID, "error", err)
+		return domain.Review{}, utils.ErrInternal
+	}
+	return review, nil
+}
+
+// GetOrderReview devuelve la reseña al cliente del pedido, al repartidor que lo entregó o a quien
+// tenga reviews:read:any (canReadAny). Las reseñas ocultas solo se ven con ese permiso.
+func (s *ReviewService) GetOrderReview(ctx context.Context, orderID, userID string, canReadAny bool) (domain.Review, error) {
+	order, err := s.orderRepo.GetOrderById(ctx, orderID)
+	if err != nil {
+		return domain.Review{}, utils.ErrOrderNotFound
+	}
+	if !canReadAny && order.CustomerID != userID && order.DriverID != userID {
+		return domain.Review{}, utils.ErrUnauthorizedAction
+	}
+
+	review, err := s.repo.GetByOrder(ctx, orderID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Review{}, utils.ErrReviewNotFound
+	}
+	if err != nil {
+		slog.Error("error al obtener reseña", "order_id", orderID, "error", err)
+		return domain.Review{}, utils.ErrInternal
+	}
+	if review.IsHidden && !canReadAny {
+		return domain.Review{}, utils.ErrReviewNotFound
+	}
+	return review, nil
+}
+
+func (s *ReviewService) ListReviews(ctx context.Context, driverID string, hidden *bool) ([]domain.Review, error) {
+	reviews, err := s.repo.List(ctx, driverID, hidden)
+	if err != nil {
+		slog.Error("error al listar reseñas", "error", err)
+		return nil, utils.ErrInternal
+	}
+	if reviews == nil {
+		reviews = []domain.Review{}
+	}
+	return reviews, nil
+}
+
+// HideReview oculta una reseña abusiva; deja de contar en los promedios
+func (s *ReviewService) HideReview(ctx context.Context, reviewID, reason, adminID string) (domain.Review, error) {
+	return s.setHidden(ctx, reviewID, true, reason, adminID)
+}
+
+func (s *ReviewService) UnhideReview(ctx context.Context, reviewID string) (domain.Review, error) {
+	return s.setHidden(ctx, reviewID, false, "", "")
+}
+
+func (s *ReviewService) GetDriverRating(ctx context.Context, driverID string) (domain.RatingSummary, error) {
+	summary, err := s.repo.GetDriverRating(ctx, driverID)
+	if err != nil {
+		slog.Error("error al obtener calificación del repartidor", "driver_id", driverID, "error", err)
+		return summary, utils.ErrInternal
+	}
+	return summary, nil
+}
+
+func (s *ReviewService) setHidden(ctx context.Context, reviewID string, hidden bool, reason, adminID string) (domain.Review, error) {
+	review, err := s.repo.SetHidden(ctx, reviewID, hidden, reason, adminID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Review{}, utils.ErrReviewNotFound
+	}
+	if err != nil {
+		slog.Error("error al moderar reseña", "review_id", reviewID, "error", err)
+		return domain.Review{}, utils.ErrInternal
+	}
+	return review, nil
+}
+
+// deliveredAt toma la hora del evento ORDER_DELIVERED del timeline
+func (s *ReviewService) deliveredAt(ctx context.Context, order domain.Order) (time.Time, error) {
+	events, err := s.eventRepo.ListByOrder(ctx, order.ID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, e := range events {
+		if e.Type == domain.OrderEventDelivered {
+			return e.CreatedAt, nil
+		}
+	}
+	// Pedidos entregados antes de que existiera el timeline
+	return order.CreatedAt, nil
+}
+
+func validateReviewTags(tags []string) error {
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		if seen[tag] || !isReviewTag(tag) {
+			return utils.ErrInvalidReviewTag
+		}
+		seen[tag] = true
+	}
+	return nil
+}
+
+func isReviewTag(tag string) bool {
+	for _, t := range domain.ReviewTags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func getReviewWindow() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv("REVIEW_WINDOW_HOURS"))
+	if err == nil && hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return defaultReviewWindow
+}
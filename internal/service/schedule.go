@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+	"tracking/internal/dto"
+	"tracking/internal/utils"
+)
+
+const (
+	defaultSlotDuration         = 30 * time.Minute
+	defaultSlotCapacity         = 5
+	defaultScheduleMinLead      = time.Hour
+	defaultScheduleMaxDaysAhead = 7
+	defaultScheduleReleaseLead  = 30 * time.Minute
+	scheduleReleaseInterval     = 30 * time.Second
+)
+
+// validateDeliveryWindow exige una franja exacta del tamaño configurado, alineada (20:00, 20:30...),
+// con anticipación mínima, dentro del horizonte permitido y dentro del horario del local.
+func validateDeliveryWindow(cal storeCalendar, window dto.DeliveryWindowRequest, now time.Time) error {
+	slot := getSlotDuration()
+	start := window.Start.In(storeLocation)
+
+	if window.End.Sub(window.Start) != slot {
+		return utils.ErrInvalidDeliveryWindow
+	}
+	if start.Sub(startOfDay(start))%slot != 0 {
+		return utils.ErrInvalidDeliveryWindow
+	}
+	if start.Before(now.Add(getScheduleMinLead())) || start.After(now.AddDate(0, 0, getScheduleMaxDaysAhead())) {
+		return utils.ErrInvalidDeliveryWindow
+	}
+	if !cal.windowOpen(window.Start, window.End) {
+		return utils.ErrDeliveryWindowClosed
+	}
+	return nil
+}
+
+// ListDeliverySlots devuelve las franjas de un día (AAAA-MM-DD, hora local del local) que todavía
+// se pueden reservar, con el lugar que les queda.
+func (s *OrderService) ListDeliverySlots(ctx context.Context, date string) ([]dto.DeliverySlotResponse, error) {
+	day, err := time.ParseInLocation("2006-01-02", date, storeLocation)
+	if err != nil {
+		return nil, utils.ErrInvalidDate
+	}
+
+	cal, err := loadStoreCalendar(ctx, s.storeRepo, day, 1)
+	if err != nil {
+		slog.Error("error al cargar horarios del local", "error", err)
+		return nil, utils.ErrInternal
+	}
+
+	counts, err := s.repo.CountScheduledBetween(ctx, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		slog.Error("error al contar pedidos programados", "date", date, "error", err)
+		return nil, utils.ErrInternal
+	}
+
+	slot := getSlotDuration()
+	capacity := getSlotCapacity()
+	now := time.Now()
+
+	// Las franjas de la madrugada pueden venir del horario nocturno del día anterior
+	nextDay := day.AddDate(0, 0, 1)
+	slots := []dto.DeliverySlotResponse{}
+	for _, opening := range daysAround(day) {
+		ranges, _ := cal.rangesOn(opening)
+		for _, r := range ranges {
+			for start := opening.Add(r.opens); !start.Add(slot).After(opening.Add(r.closes)); start = start.Add(slot) {
+				if start.Before(day) || !start.Before(nextDay) {
+					continue
+				}
+				window := dto.DeliveryWindowRequest{Start: start, End: start.Add(slot)}
+				if validateDeliveryWindow(cal, window, now) != nil {
+					continue
+				}
+
+				remaining := capacity - counts[start.Unix()]
+				if remaining < 0 {
+					remaining = 0
+				}
+				slots = append(slots, dto.DeliverySlotResponse{Start: window.Start, End: window.End, Remaining: remaining})
+			}
+		}
+	}
+	return slots, nil
+}
+
+// RunScheduledOrderReleaser libera periódicamente los pedidos programados cuya franja está por empezar
+func (s *OrderService) RunScheduledOrderReleaser(ctx context.Context) {
+	ticker := time.NewTicker(scheduleReleaseInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			released, err := s.repo.ReleaseDueScheduled(ctx, time.Now().Add(getScheduleReleaseLead()))
+			if err != nil {
+				slog.Error("error al liberar pedidos programados", "error", err)
+				continue
+			}
+			if released > 0 {
+				slog.Info("pedidos programados liberados", "count", released)
+			}
+		}
+	}
+}
+
+func getSlotDuration() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("SCHEDULE_SLOT_MINUTES"))
+	if err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultSlotDuration
+}
+
+func getSlotCapacity() int {
+	capacity, err := strconv.Atoi(os.Getenv("SCHEDULE_SLOT_CAPACITY"))
+	if err == nil && capacity > 0 {
+		return capacity
+	}
+	return defaultSlotCapacity
+}
+
+func getScheduleMinLead() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("SCHEDULE_MIN_LEAD_MINUTES"))
+	if err == nil && minutes >= 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultScheduleMinLead
+}
+
+func getScheduleMaxDaysAhead() int {
+	days, err := strconv.Atoi(os.Getenv("SCHEDULE_MAX_DAYS_AHEAD"))
+	if err == nil && days > 0 {
+		return days
+	}
+	return defaultScheduleMaxDaysAhead
+}
+
+// getScheduleReleaseLead es cuánto antes del inicio de la franja el pedido pasa a verse como pendiente
+func getScheduleReleaseLead() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("SCHEDULE_RELEASE_LEAD_MINUTES"))
+	if err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultScheduleReleaseLead
+}
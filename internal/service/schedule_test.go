@@ -0,0 +1,59 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"tracking/internal/dto"
+	"tracking/internal/utils"
+)
+
+func TestValidateDeliveryWindow(t *testing.T) {
+	t.Setenv("SCHEDULE_SLOT_MINUTES", "")
+	t.Setenv("SCHEDULE_MIN_LEAD_MINUTES", "")
+	t.Setenv("SCHEDULE_MAX_DAYS_AHEAD", "")
+
+	cal := testCalendar(t)
+	now := at(19, 9, 0)
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		wantErr    error
+	}{
+		{"franja válida", at(19, 12, 0), at(19, 12, 30), nil},
+		{"franja nocturna después de medianoche", at(20, 0, 30), at(20, 1, 0), nil},
+		{"duración distinta a la franja", at(19, 12, 0), at(19, 13, 0), utils.ErrInvalidDeliveryWindow},
+		{"no alineada", at(19, 12, 10), at(19, 12, 40), utils.ErrInvalidDeliveryWindow},
+		{"sin la anticipación mínima", at(19, 9, 30), at(19, 10, 0), utils.ErrInvalidDeliveryWindow},
+		{"en el pasado", at(19, 8, 0), at(19, 8, 30), utils.ErrInvalidDeliveryWindow},
+		{"más allá del horizonte", at(27, 12, 0), at(27, 12, 30), utils.ErrInvalidDeliveryWindow},
+		{"con el local cerrado", at(19, 17, 0), at(19, 17, 30), utils.ErrDeliveryWindowClosed},
+		{"empieza al cerrar", at(19, 15, 0), at(19, 15, 30), utils.ErrDeliveryWindowClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDeliveryWindow(cal, dto.DeliveryWindowRequest{Start: tt.start, End: tt.end}, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, se esperaba %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDeliveryWindowCustomSlot(t *testing.T) {
+	t.Setenv("SCHEDULE_SLOT_MINUTES", "60")
+	t.Setenv("SCHEDULE_MIN_LEAD_MINUTES", "0")
+	t.Setenv("SCHEDULE_MAX_DAYS_AHEAD", "")
+
+	cal := testCalendar(t)
+	now := at(19, 9, 0)
+
+	if err := validateDeliveryWindow(cal, dto.DeliveryWindowRequest{Start: at(19, 11, 0), End: at(19, 12, 0)}, now); err != nil {
+		t.Errorf("franja de una hora: error = %v", err)
+	}
+	if err := validateDeliveryWindow(cal, dto.DeliveryWindowRequest{Start: at(19, 11, 30), End: at(19, 12, 30)}, now); !errors.Is(err, utils.ErrInvalidDeliveryWindow) {
+		t.Errorf("franja desalineada: error = %v, se esperaba ErrInvalidDeliveryWindow", err)
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"tracking/internal/auth"
+	"tracking/internal/domain"
+	"tracking/internal/dto"
+	"tracking/internal/repository"
+	"tracking/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const defaultShareLinkTTL = 4 * time.Hour
+
+type ShareServiceInterface interface {
+	CreateShareLink(ctx context.Context, orderID, customerID string, ttl time.Duration) (dto.ShareLinkResponse, error)
+	ListShareLinks(ctx context.Context, orderID, customerID string) ([]dto.ShareLinkResponse, error)
+	RevokeShareLink(ctx context.Context, orderID, linkID, customerID string) error
+	GetPublicTracking(ctx context.Context, token string) (dto.PublicTrackingResponse, error)
+}
+type ShareService struct {
+	repo      repository.ShareLinkRepositoryInterface
+	orderRepo repository.OrderRepositoryInterface
+	locRepo   repository.LocationRepositoryInterface
+}
+
+func NewShareService(repo repository.ShareLinkRepositoryInterface, orderRepo repository.OrderRepositoryInterface, locRepo repository.LocationRepositoryInterface) *ShareService {
+	return &ShareService{repo: repo, orderRepo: orderRepo, locRepo: locRepo}
+}
+
+// CreateShareLink genera un token firmado para seguir el pedido. Si ttl es 0 se usa el configurado.
+func (s *ShareService) CreateShareLink(ctx context.Context, orderID, customerID string, ttl time.Duration) (dto.ShareLinkResponse, error) {
+	order, err := s.ownedOrder(ctx, orderID, customerID)
+	if err != nil {
+		return dto.ShareLinkResponse{}, err
+	}
+	if order.Status == "DELIVERED" || order.Status == "CANCELLED" {
+		return dto.ShareLinkResponse{}, utils.ErrInvalidState
+	}
+
+	if ttl <= 0 {
+		ttl = getShareLinkTTL()
+	}
+
+	link := domain.ShareLink{
+		OrderID:   orderID,
+		CreatedBy: customerID,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.repo.Create(ctx, &link); err != nil {
+		slog.Error("error al crear link de seguimiento", "order_id", orderID, "error", err)
+		return dto.ShareLinkResponse{}, utils.ErrInternal
+	}
+
+	token, err := auth.GenerateShareToken(link.ID, link.ExpiresAt)
+	if err != nil {
+		return dto.ShareLinkResponse{}, err
+	}
+
+	response := utils.ToShareLinkResponse(link)
+	response.Token = token
+	return response, nil
+}
+
+func (s *ShareService) ListShareLinks(ctx context.Context, orderID, customerID string) ([]dto.ShareLinkResponse, error) {
+	if _, err := s.ownedOrder(ctx, orderID, customerID); err != nil {
+		return nil, err
+	}
+
+	links, err := s.repo.ListByOrder(ctx, orderID)
+	if err != nil {
+		slog.Error("error al listar links de seguimiento", "order_id", orderID, "error", err)
+		return nil, utils.ErrInternal
+	}
+	return utils.SliceShareLinkDomainToResponseDto(links), nil
+}
+
+func (s *ShareService) RevokeShareLink(ctx context.Context, orderID, linkID, customerID string) error {
+	if _, err := s.ownedOrder(ctx, orderID, customerID); err != nil {
+		return err
+	}
+
+	err := s.repo.Revoke(ctx, linkID, orderID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return utils.ErrShareLinkNotFound
+	}
+	return err
+}
+
+// GetPublicTracking arma la vista pública del pedido: sin datos del cliente ni ID del pedido,
+// solo estado, nombre de pila del driver, ETA y una posición aproximada.
+func (s *ShareService) GetPublicTracking(ctx context.Context, token string) (dto.PublicTrackingResponse, error) {
+	linkID, err := auth.ValidateShareToken(token)
+	if err != nil {
+		return dto.PublicTrackingResponse{}, utils.ErrShareLinkNotFound
+	}
+
+	link, err := s.repo.GetByID(ctx, linkID)
+	if err != nil || !link.IsUsable(time.Now()) {
+		return dto.PublicTrackingResponse{}, utils.ErrShareLinkNotFound
+	}
+
+	order, err := s.orderRepo.GetOrderById(ctx, link.OrderID)
+	if err != nil {
+		return dto.PublicTrackingResponse{}, utils.ErrShareLinkNotFound
+	}
+
+	response := dto.PublicTrackingResponse{Status: order.Status}
+	if order.DriverID == "" {
+		return response, nil
+	}
+
+	if parts := strings.Fields(order.DriverName); len(parts) > 0 {
+		response.DriverFirstName = parts[0]
+	}
+
+	if order.Status != "ASSIGNED" && order.Status != "PICKED_UP" {
+		return response, nil
+	}
+
+	location, err := s.locRepo.GetDriverLocation(ctx, order.DriverID)
+	if err != nil {
+		return response, nil
+	}
+
+	response.DriverLocation = &dto.CoarseLocation{
+		Lat: coarseCoordinate(location.Lat),
+		Lng: coarseCoordinate(location.Lng),
+	}
+	if !location.RecordedAt.IsZero() {
+		updatedAt := location.RecordedAt
+		response.UpdatedAt = &updatedAt
+	}
+
+	// Antes de retirar el pedido el driver todavía tiene que pasar por el local
+	distance := distanceMeters(location.Lat, location.Lng, order.DestLat, order.DestLng)
+	if order.Status == "ASSIGNED" {
+		distance = distanceMeters(location.Lat, location.Lng, order.OriginLat, order.OriginLng) +
+			distanceMeters(order.OriginLat, order.OriginLng, order.DestLat, order.DestLng)
+	}
+	eta := estimateETAMinutes(distance)
+	response.EtaMinutes = &eta
+
+	return response, nil
+}
+
+func (s *ShareService) ownedOrder(ctx context.Context, orderID, customerID string) (domain.Order, error) {
+	order, err := s.orderRepo.GetOrderById(ctx, orderID)
+	if err != nil {
+		return order, utils.ErrOrderNotFound
+	}
+	if order.CustomerID != customerID {
+		slog.Warn("intento de compartir orden ajena", "order_id", orderID, "customer_id", customerID)
+		return order, utils.ErrUnauthorizedAction
+	}
+	return order, nil
+}
+
+func getShareLinkTTL() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("SHARE_LINK_TTL_MINUTES"))
+	if err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultShareLinkTTL
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"time"
+	_ "time/tzdata" // la imagen del contenedor no trae la base de zonas horarias
+	"tracking/internal/domain"
+	"tracking/internal/repository"
+)
+
+const storeTimezone = "America/Argentina/Buenos_Aires"
+
+// Hasta cuántos días hacia adelante se busca la próxima apertura
+const storeLookaheadDays = 14
+
+// storeLocation es la zona horaria en la que se evalúan los horarios del local
+var storeLocation = loadStoreLocation()
+
+func loadStoreLocation() *time.Location {
+	loc, err := time.LoadLocation(storeTimezone)
+	if err != nil {
+		return time.FixedZone("ART", -3*60*60)
+	}
+	return loc
+}
+
+// openingRange es un rango de apertura como desplazamiento desde la medianoche local. En los
+// horarios nocturnos (ej. 20:00 a 01:00) closes pasa de 24h: el rango termina al día siguiente.
+type openingRange struct {
+	opens  time.Duration
+	closes time.Duration
+}
+
+// storeCalendar junta la semana tipo, las excepciones de un período y la pausa manual
+type storeCalendar struct {
+	weekly     map[time.Weekday][]openingRange
+	exceptions map[string]domain.StoreException
+	pause      domain.StorePause
+}
+
+// loadStoreCalendar lee lo necesario para evaluar el horario desde from hasta days días después
+func loadStoreCalendar(ctx context.Context, repo repository.StoreRepositoryInterface, from time.Time, days int) (storeCalendar, error) {
+	cal := storeCalendar{
+		weekly:     map[time.Weekday][]openingRange{},
+		exceptions: map[string]domain.StoreException{},
+	}
+
+	hours, err := repo.ListWeeklyHours(ctx)
+	if err != nil {
+		return cal, err
+	}
+	for _, h := range hours {
+		r, ok := parseRange(h.OpensAt, h.ClosesAt)
+		if ok {
+			cal.weekly[time.Weekday(h.Weekday)] = append(cal.weekly[time.Weekday(h.Weekday)], r)
+		}
+	}
+
+	// Desde el día anterior: su horario nocturno puede seguir abierto pasada la medianoche
+	from = from.In(storeLocation)
+	exceptions, err := repo.ListExceptions(ctx, from.AddDate(0, 0, -1), from.AddDate(0, 0, days))
+	if err != nil {
+		return cal, err
+	}
+	for _, e := range exceptions {
+		cal.exceptions[e.Date] = e
+	}
+
+	cal.pause, err = repo.GetPause(ctx)
+	return cal, err
+}
+
+// rangesOn devuelve los rangos de apertura de un día local; si una excepción lo cierra, devuelve su motivo
+func (c storeCalendar) rangesOn(day time.Time) ([]openingRange, string) {
+	if e, ok := c.exceptions[day.Format("2006-01-02")]; ok {
+		if e.Closed {
+			return nil, e.Reason
+		}
+		if r, ok := parseRange(e.OpensAt, e.ClosesAt); ok {
+			return []openingRange{r}, ""
+		}
+		return nil, e.Reason
+	}
+	return c.weekly[day.Weekday()], ""
+}
+
+// openAt indica si el horario (sin contar la pausa) tiene al local abierto en t y hasta cuándo
+func (c storeCalendar) openAt(t time.Time) (time.Time, bool) {
+	t = t.In(storeLocation)
+	for _, day := range daysAround(t) {
+		ranges, _ := c.rangesOn(day)
+		for _, r := range ranges {
+			if t.Sub(day) >= r.opens && t.Sub(day) < r.closes {
+				return day.Add(r.closes), true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// windowOpen indica si la franja completa cae dentro de un mismo rango de apertura
+func (c storeCalendar) windowOpen(start, end time.Time) bool {
+	start = start.In(storeLocation)
+	for _, day := range daysAround(start) {
+		ranges, _ := c.rangesOn(day)
+		for _, r := range ranges {
+			if start.Sub(day) >= r.opens && end.Sub(day) <= r.closes {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// daysAround devuelve el día anterior y el de t: los rangos que pueden contener a t
+func daysAround(t time.Time) []time.Time {
+	day := startOfDay(t)
+	return []time.Time{day.AddDate(0, 0, -1), day}
+}
+
+// nextOpening es el próximo inicio de rango posterior a after, o nil si no hay ninguno cerca
+func (c storeCalendar) nextOpening(after time.Time) *time.Time {
+	after = after.In(storeLocation)
+	day := startOfDay(after)
+	for i := 0; i <= storeLookaheadDays; i++ {
+		ranges, _ := c.rangesOn(day)
+		for _, r := range ranges {
+			if opens := day.Add(r.opens); opens.After(after) {
+				return &opens
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return nil
+}
+
+// parseRange interpreta un cierre igual o anterior a la apertura como del día siguiente
+func parseRange(opensAt, closesAt string) (openingRange, bool) {
+	opens, errOpen := parseClock(opensAt)
+	closes, errClose := parseClock(closesAt)
+	if errOpen != nil || errClose != nil {
+		return openingRange{}, false
+	}
+	if closes <= opens {
+		closes += 24 * time.Hour
+	}
+	return openingRange{opens: opens, closes: closes}, true
+}
+
+// rangesOverlap compara dos rangos de la semana tipo. Un rango nocturno del sábado sigue
+// el domingo, así que también se compara corriéndolo una semana.
+func rangesOverlap(dayA int, a openingRange, dayB int, b openingRange) bool {
+	const week = 7 * 24 * time.Hour
+	startA := time.Duration(dayA)*24*time.Hour + a.opens
+	endA := time.Duration(dayA)*24*time.Hour + a.closes
+	startB := time.Duration(dayB)*24*time.Hour + b.opens
+	endB := time.Duration(dayB)*24*time.Hour + b.closes
+	for _, shift := range []time.Duration{-week, 0, week} {
+		if startA < endB+shift && startB+shift < endA {
+			return true
+		}
+	}
+	return false
+}
+
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
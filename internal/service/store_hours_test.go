@@ -0,0 +1,193 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"tracking/internal/domain"
+	"tracking/internal/utils"
+)
+
+// at arma una hora local del local. El 19/10/2026 es lunes.
+func at(day, hour, minute int) time.Time {
+	return time.Date(2026, time.October, day, hour, minute, 0, 0, storeLocation)
+}
+
+func mustRange(t *testing.T, opensAt, closesAt string) openingRange {
+	t.Helper()
+	r, ok := parseRange(opensAt, closesAt)
+	if !ok {
+		t.Fatalf("parseRange(%s, %s) inválido", opensAt, closesAt)
+	}
+	return r
+}
+
+// testCalendar abre todos los días de 11:00 a 15:00 y de 20:00 a 01:00 (nocturno)
+func testCalendar(t *testing.T) storeCalendar {
+	t.Helper()
+	cal := storeCalendar{
+		weekly:     map[time.Weekday][]openingRange{},
+		exceptions: map[string]domain.StoreException{},
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		cal.weekly[d] = []openingRange{mustRange(t, "11:00", "15:00"), mustRange(t, "20:00", "01:00")}
+	}
+	return cal
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		opens, closes string
+		wantOK        bool
+		want          openingRange
+	}{
+		{"11:00", "15:00", true, openingRange{11 * time.Hour, 15 * time.Hour}},
+		{"20:00", "01:00", true, openingRange{20 * time.Hour, 25 * time.Hour}},
+		{"00:00", "00:00", true, openingRange{0, 24 * time.Hour}},
+		{"25:00", "01:00", false, openingRange{}},
+		{"11:00", "", false, openingRange{}},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseRange(tt.opens, tt.closes)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("parseRange(%q, %q) = %+v, %v; se esperaba %+v, %v", tt.opens, tt.closes, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestStoreCalendarOpenAt(t *testing.T) {
+	cal := testCalendar(t)
+	cal.exceptions["2026-10-21"] = domain.StoreException{Date: "2026-10-21", Closed: true, Reason: "Feriado"}
+	cal.exceptions["2026-10-22"] = domain.StoreException{Date: "2026-10-22", OpensAt: "18:00", ClosesAt: "22:00"}
+
+	tests := []struct {
+		name      string
+		t         time.Time
+		wantOpen  bool
+		wantUntil time.Time
+	}{
+		{"mediodía", at(19, 12, 0), true, at(19, 15, 0)},
+		{"justo al cerrar", at(19, 15, 0), false, time.Time{}},
+		{"entre turnos", at(19, 17, 0), false, time.Time{}},
+		{"noche", at(19, 23, 0), true, at(20, 1, 0)},
+		{"madrugada del turno nocturno anterior", at(20, 0, 30), true, at(20, 1, 0)},
+		{"después del cierre nocturno", at(20, 1, 30), false, time.Time{}},
+		{"feriado", at(21, 12, 0), false, time.Time{}},
+		{"madrugada después de un feriado", at(22, 0, 30), false, time.Time{}},
+		{"horario especial", at(22, 19, 0), true, at(22, 22, 0)},
+		{"fuera del horario especial", at(22, 12, 0), false, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			until, open := cal.openAt(tt.t)
+			if open != tt.wantOpen || !until.Equal(tt.wantUntil) {
+				t.Errorf("openAt = %v, %v; se esperaba %v, %v", until, open, tt.wantUntil, tt.wantOpen)
+			}
+		})
+	}
+}
+
+func TestStoreCalendarWindowOpen(t *testing.T) {
+	cal := testCalendar(t)
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       bool
+	}{
+		{"dentro del turno", at(19, 12, 0), at(19, 12, 30), true},
+		{"termina al cerrar", at(19, 14, 30), at(19, 15, 0), true},
+		{"pasa el cierre", at(19, 14, 45), at(19, 15, 15), false},
+		{"cruza la medianoche", at(19, 23, 45), at(20, 0, 15), true},
+		{"madrugada del turno nocturno", at(20, 0, 30), at(20, 1, 0), true},
+		{"después del cierre nocturno", at(20, 1, 0), at(20, 1, 30), false},
+	}
+
+	for _, tt := range tests {
+		if got := cal.windowOpen(tt.start, tt.end); got != tt.want {
+			t.Errorf("%s: windowOpen = %v, se esperaba %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStoreCalendarNextOpening(t *testing.T) {
+	cal := testCalendar(t)
+	cal.exceptions["2026-10-20"] = domain.StoreException{Date: "2026-10-20", Closed: true}
+
+	tests := []struct {
+		name  string
+		after time.Time
+		want  time.Time
+	}{
+		{"antes de abrir", at(19, 9, 0), at(19, 11, 0)},
+		{"entre turnos", at(19, 16, 0), at(19, 20, 0)},
+		{"saltea el día cerrado", at(20, 2, 0), at(21, 11, 0)},
+	}
+
+	for _, tt := range tests {
+		got := cal.nextOpening(tt.after)
+		if got == nil || !got.Equal(tt.want) {
+			t.Errorf("%s: nextOpening = %v, se esperaba %v", tt.name, got, tt.want)
+		}
+	}
+
+	empty := storeCalendar{weekly: map[time.Weekday][]openingRange{}, exceptions: map[string]domain.StoreException{}}
+	if got := empty.nextOpening(at(19, 9, 0)); got != nil {
+		t.Errorf("sin horarios nextOpening = %v, se esperaba nil", got)
+	}
+}
+
+func TestRangesOverlap(t *testing.T) {
+	tests := []struct {
+		name         string
+		dayA         int
+		opensA, clsA string
+		dayB         int
+		opensB, clsB string
+		want         bool
+	}{
+		{"turnos separados", 1, "11:00", "15:00", 1, "20:00", "23:00", false},
+		{"se pisan el mismo día", 1, "11:00", "15:00", 1, "14:00", "18:00", true},
+		{"contiguos", 1, "11:00", "15:00", 1, "15:00", "18:00", false},
+		{"nocturno pisa el día siguiente", 1, "20:00", "02:00", 2, "01:00", "05:00", true},
+		{"nocturno sin pisar el día siguiente", 1, "20:00", "02:00", 2, "11:00", "15:00", false},
+		{"nocturno del sábado pisa el domingo", 6, "22:00", "03:00", 0, "02:00", "06:00", true},
+		{"mismo horario otro día", 1, "11:00", "15:00", 2, "11:00", "15:00", false},
+	}
+
+	for _, tt := range tests {
+		a := mustRange(t, tt.opensA, tt.clsA)
+		b := mustRange(t, tt.opensB, tt.clsB)
+		if got := rangesOverlap(tt.dayA, a, tt.dayB, b); got != tt.want {
+			t.Errorf("%s: rangesOverlap = %v, se esperaba %v", tt.name, got, tt.want)
+		}
+		if got := rangesOverlap(tt.dayB, b, tt.dayA, a); got != tt.want {
+			t.Errorf("%s (invertido): rangesOverlap = %v, se esperaba %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStoreUnavailableError(t *testing.T) {
+	cal := testCalendar(t)
+
+	if err := storeUnavailableError(cal, at(19, 12, 0)); err != nil {
+		t.Errorf("con el local abierto se esperaba nil, fue %v", err)
+	}
+
+	var appErr *utils.AppError
+	err := storeUnavailableError(cal, at(19, 17, 0))
+	if !errors.As(err, &appErr) || appErr.Code != StoreClosedCode {
+		t.Fatalf("error = %v, se esperaba %s", err, StoreClosedCode)
+	}
+	if appErr.Details["next_opens_at"] != at(19, 20, 0).Format(time.RFC3339) {
+		t.Errorf("next_opens_at = %q", appErr.Details["next_opens_at"])
+	}
+
+	cal.pause = domain.StorePause{Paused: true, Reason: "Sin repartidores"}
+	err = storeUnavailableError(cal, at(19, 12, 0))
+	if !errors.As(err, &appErr) || appErr.Code != StorePausedCode || appErr.Details["reason"] != "Sin repartidores" {
+		t.Errorf("error = %v, se esperaba %s con el motivo", err, StorePausedCode)
+	}
+}
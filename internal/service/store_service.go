@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+	"tracking/internal/domain"
+	"tracking/internal/dto"
+	"tracking/internal/repository"
+	"tracking/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Códigos de AppError que recibe el cliente cuando no se pueden tomar pedidos
+const (
+	StoreClosedCode = "STORE_CLOSED"
+	StorePausedCode = "STORE_PAUSED"
+)
+
+type StoreServiceInterface interface {
+	GetStatus(ctx context.Context) (dto.StoreStatusResponse, error)
+	GetWeeklyHours(ctx context.Context) ([]domain.StoreHours, error)
+	UpdateWeeklyHours(ctx context.Context, req dto.UpdateStoreHoursRequest) ([]domain.StoreHours, error)
+	ListExceptions(ctx context.Context) ([]domain.StoreException, error)
+	SaveException(ctx context.Context, req dto.StoreExceptionRequest) (domain.StoreException, error)
+	DeleteException(ctx context.Context, date string) error
+	Pause(ctx context.Context, reason, adminID string) error
+	Resume(ctx context.Context) error
+}
+type StoreService struct {
+	repo repository.StoreRepositoryInterface
+}
+
+func NewStoreService(repo repository.StoreRepositoryInterface) *StoreService {
+	return &StoreService{repo: repo}
+}
+
+func (s *StoreService) GetStatus(ctx context.Context) (dto.StoreStatusResponse, error) {
+	now := time.Now()
+	cal, err := loadStoreCalendar(ctx, s.repo, now, storeLookaheadDays)
+	if err != nil {
+		slog.Error("error al cargar horarios del local", "error", err)
+		return dto.StoreStatusResponse{}, utils.ErrInternal
+	}
+
+	status := dto.StoreStatusResponse{Timezone: storeTimezone}
+	if cal.pause.Paused {
+		status.Paused = true
+		status.Reason = cal.pause.Reason
+		return status, nil
+	}
+
+	if closesAt, open := cal.openAt(now); open {
+		status.IsOpen = true
+		status.ClosesAt = &closesAt
+		return status, nil
+	}
+
+	_, status.Reason = cal.rangesOn(startOfDay(now.In(storeLocation)))
+	status.NextOpensAt = cal.nextOpening(now)
+	return status, nil
+}
+
+func (s *StoreService) GetWeeklyHours(ctx context.Context) ([]domain.StoreHours, error) {
+	hours, err := s.repo.ListWeeklyHours(ctx)
+	if err != nil {
+		slog.Error("error al listar horarios", "error", err)
+		return nil, utils.ErrInternal
+	}
+	if hours == nil {
+		hours = []domain.StoreHours{}
+	}
+	return hours, nil
+}
+
+func (s *StoreService) UpdateWeeklyHours(ctx context.Context, req dto.UpdateStoreHoursRequest) ([]domain.StoreHours, error) {
+	hours := make([]domain.StoreHours, 0, len(req.Hours))
+	var ranges []openingRange
+	for _, item := range req.Hours {
+		r, ok := parseRange(item.OpensAt, item.ClosesAt)
+		if !ok {
+			return nil, utils.ErrInvalidStoreHours
+		}
+		// Los rangos no pueden superponerse, tampoco un nocturno con el del día siguiente
+		for i, other := range ranges {
+			if rangesOverlap(item.Weekday, r, hours[i].Weekday, other) {
+				return nil, utils.ErrInvalidStoreHours
+			}
+		}
+		ranges = append(ranges, r)
+		hours = append(hours, domain.StoreHours{Weekday: item.Weekday, OpensAt: item.OpensAt, ClosesAt: item.ClosesAt})
+	}
+
+	if err := s.repo.ReplaceWeeklyHours(ctx, hours); err != nil {
+		slog.Error("error al guardar horarios", "error", err)
+		return nil, utils.ErrInternal
+	}
+	return s.GetWeeklyHours(ctx)
+}
+
+// ListExceptions devuelve las excepciones desde hoy hasta un año adelante
+func (s *StoreService) ListExceptions(ctx context.Context) ([]domain.StoreException, error) {
+	today := startOfDay(time.Now().In(storeLocation))
+	exceptions, err := s.repo.ListExceptions(ctx, today, today.AddDate(1, 0, 0))
+	if err != nil {
+		slog.Error("error al listar excepciones de horario", "error", err)
+		return nil, utils.ErrInternal
+	}
+	if exceptions == nil {
+		exceptions = []domain.StoreException{}
+	}
+	return exceptions, nil
+}
+
+func (s *StoreService) SaveException(ctx context.Context, req dto.StoreExceptionRequest) (domain.StoreException, error) {
+	if _, err := time.ParseInLocation("2006-01-02", req.Date, storeLocation); err != nil {
+		return domain.StoreException{}, utils.ErrInvalidDate
+	}
+
+	exception := domain.StoreException{Date: req.Date, Closed: req.Closed, Reason: req.Reason}
+	if !req.Closed {
+		if _, ok := parseRange(req.OpensAt, req.ClosesAt); !ok {
+			return domain.StoreException{}, utils.ErrInvalidStoreHours
+		}
+		exception.OpensAt = req.OpensAt
+		exception.ClosesAt = req.ClosesAt
+	}
+
+	if err := s.repo.UpsertException(ctx, &exception); err != nil {
+		slog.Error("error al guardar excepción de horario", "date", req.Date, "error", err)
+		return domain.StoreException{}, utils.ErrInternal
+	}
+	return exception, nil
+}
+
+func (s *StoreService) DeleteException(ctx context.Context, date string) error {
+	err := s.repo.DeleteException(ctx, date)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return utils.ErrStoreExceptionNotFound
+	}
+	if err != nil {
+		slog.Error("error al borrar excepción de horario", "date", date, "error", err)
+		return utils.ErrInternal
+	}
+	return nil
+}
+
+func (s *StoreService) Pause(ctx context.Context, reason, adminID string) error {
+	if err := s.repo.SetPause(ctx, true, reason, adminID); err != nil {
+		slog.Error("error al pausar pedidos", "error", err)
+		return utils.ErrInternal
+	}
+	slog.Info("pedidos pausados", "admin_id", adminID, "reason", reason)
+	return nil
+}
+
+func (s *StoreService) Resume(ctx context.Context) error {
+	if err := s.repo.SetPause(ctx, false, "", ""); err != nil {
+		slog.Error("error al reanudar pedidos", "error", err)
+		return utils.ErrInternal
+	}
+	return nil
+}
+
+// storeUnavailableError arma el AppError para un pedido inmediato con el local cerrado o en pausa
+func storeUnavailableError(cal storeCalendar, now time.Time) error {
+	if cal.pause.Paused {
+		appErr := utils.NewAppError(StorePausedCode, "El local pausó la toma de pedidos", http.StatusUnprocessableEntity, nil)
+		if cal.pause.Reason != "" {
+			appErr.Details["reason"] = cal.pause.Reason
+		}
+		return appErr
+	}
+
+	if _, open := cal.openAt(now); open {
+		return nil
+	}
+
+	appErr := utils.NewAppError(StoreClosedCode, "El local está cerrado", http.StatusUnprocessableEntity, nil)
+	if next := cal.nextOpening(now); next != nil {
+		appErr.Details["next_opens_at"] = next.Format(time.RFC3339)
+	}
+	return appErr
+}
@@ -3,34 +3,76 @@ package service
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"os"
 	"strings"
+	"sync"
+	"time"
 	"tracking/internal/auth"
 	"tracking/internal/domain"
+	"tracking/internal/notification"
 	"tracking/internal/repository"
+	"tracking/internal/utils"
 
+	"github.com/jackc/pgx/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserServiceInterface interface {
 	Register(ctx context.Context, email, password, fullName, role string) (*domain.User, error)
-	Login(ctx context.Context, email, password string) (*domain.User, string, string, error)
+	Login(ctx context.Context, email, password, userAgent, ip string) (*domain.LoginResult, error)
 	Refresh(ctx context.Context, refreshToken string) (*domain.User, string, string, error)
+	ListSessions(ctx context.Context, userID, currentSessionID string) ([]domain.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	Logout(ctx context.Context, userID, sessionID, accessJTI string, accessExpiresAt time.Time) error
+	LogoutAll(ctx context.Context, userID string) error
 	BootstrapAdmin(ctx context.Context, email, password, fullName, secret string) (*domain.User, error)
-	ListUsers(ctx context.Context, role string, active *bool) ([]domain.User, error)
-	DeactivateUser(ctx context.Context, actorUserID, targetUserID string) error
+	ListUsers(ctx context.Context, filter domain.UserFilter) (domain.UserPage, error)
+	GetUserDetail(ctx context.Context, userID string) (domain.UserDetail, error)
+	DeactivateUser(ctx context.Context, actorUserID, actorRole, targetUserID string) error
+	ReactivateUser(ctx context.Context, actorRole, userID string) (*domain.User, error)
+	ChangeRole(ctx context.Context, actorUserID, actorRole, targetUserID, role string) (*domain.User, error)
+	UpdateProfile(ctx context.Context, actorRole, userID, fullName, email string) (*domain.User, error)
+	UnlockUser(ctx context.Context, actorRole, userID string) error
+	ListLoginAttempts(ctx context.Context, email, ip string, limit int) ([]domain.LoginAttempt, error)
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	ChangePassword(ctx context.Context, userID, currentPassword, newPassword string) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, userID string) error
+	StartMFAEnrollment(ctx context.Context, mfaToken string) (domain.TOTPSetup, error)
+	VerifyMFA(ctx context.Context, mfaToken, code, recoveryCode, userAgent, ip string) (*domain.LoginResult, error)
+	GetMFAStatus(ctx context.Context, userID, role string) (domain.MFAStatus, error)
+	StartTOTPEnrollment(ctx context.Context, userID string) (domain.TOTPSetup, error)
+	ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userID, role, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error)
+	ResetMFA(ctx context.Context, actorRole, userID string) error
 }
 type UserService struct {
-	repo      repository.UserRepositoryInterface
-	tokenRepo repository.TokenRepositoryInterface
+	repo       repository.UserRepositoryInterface
+	tokenRepo  repository.TokenRepositoryInterface
+	mfaRepo    repository.MFARepositoryInterface
+	apiKeyRepo repository.APIKeyRepositoryInterface
+	guard      loginGuard
+	mailer     notification.Sender
 }
 
-func NewUserService(repo repository.UserRepositoryInterface, tokenRepo repository.TokenRepositoryInterface) *UserService {
-	return &UserService{repo: repo, tokenRepo: tokenRepo}
+func NewUserService(repo repository.UserRepositoryInterface, tokenRepo repository.TokenRepositoryInterface, mfaRepo repository.MFARepositoryInterface, apiKeyRepo repository.APIKeyRepositoryInterface, limiter repository.RateLimitRepositoryInterface, attemptRepo repository.LoginAttemptRepositoryInterface, mailer notification.Sender) *UserService {
+	return &UserService{
+		repo:       repo,
+		tokenRepo:  tokenRepo,
+		mfaRepo:    mfaRepo,
+		apiKeyRepo: apiKeyRepo,
+		guard:      loginGuard{limiter: limiter, attempts: attemptRepo},
+		mailer:     mailer,
+	}
 }
 
 const DefaultRole = "customer"
 
+// Register crea la cuenta sin verificar y manda el link de verificación. Si el envío falla el
+// registro sigue siendo válido; el usuario puede pedir otro link.
 func (s *UserService) Register(ctx context.Context, email, password, fullName, role string) (*domain.User, error) {
 	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -47,36 +89,115 @@ func (s *UserService) Register(ctx context.Context, email, password, fullName, r
 		FullName:     fullName,
 		Role:         finalRole,
 	}
-	err = s.repo.Create(ctx, user)
-	return user, err
+	if err := s.repo.Create(ctx, user); err != nil {
+		return user, err
+	}
+
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		slog.Warn("no se pudo enviar el email de verificación", "user_id", user.ID, "error", err)
+	}
+	return user, nil
 }
-func (s *UserService) Login(ctx context.Context, email, password string) (*domain.User, string, string, error) {
+
+// Login abre una sesión nueva para el dispositivo; las sesiones de otros dispositivos siguen vigentes.
+// Los fallos repetidos por email o IP imponen una espera y luego un bloqueo temporal.
+// Si el usuario tiene o debe configurar doble factor, devuelve un desafío en lugar de los tokens.
+func (s *UserService) Login(ctx context.Context, email, password, userAgent, ip string) (*domain.LoginResult, error) {
+	attempt := domain.LoginAttempt{Email: normalizeEmail(email), IP: ip, UserAgent: userAgent}
+
+	if err := s.guard.check(ctx, attempt.Email, ip); err != nil {
+		var rateErr *utils.RateLimitError
+		if errors.As(err, &rateErr) {
+			attempt.Outcome = domain.LoginOutcomeBlocked
+			s.guard.record(ctx, attempt)
+			return nil, err
+		}
+		slog.Error("error al consultar bloqueos de login", "email", attempt.Email, "ip", ip, "error", err)
+		return nil, utils.ErrLoginUnavailable
+	}
+
 	user, err := s.repo.GetByEmail(ctx, email)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		slog.Error("error al buscar usuario para login", "email", attempt.Email, "error", err)
+		return nil, utils.ErrLoginUnavailable
+	}
+	if err == nil {
+		attempt.UserID = user.ID
+		err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	} else {
+		// Misma demora que con un email existente, para no revelar qué cuentas existen
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+	}
 	if err != nil {
-		return nil, "", "", err
+		s.guard.registerFailure(ctx, attempt.Email, ip)
+		attempt.Outcome = domain.LoginOutcomeFailed
+		s.guard.record(ctx, attempt)
+		return nil, utils.ErrInvalidCredentials
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	s.guard.registerSuccess(ctx, attempt.Email)
+	attempt.Outcome = domain.LoginOutcomeSuccess
+	s.guard.record(ctx, attempt)
+
+	challenge, err := s.mfaChallenge(ctx, user)
 	if err != nil {
-		return nil, "", "", err
+		return nil, err
 	}
+	if challenge != nil {
+		return challenge, nil
+	}
+
+	return s.issueSession(ctx, user, userAgent, ip)
+}
 
-	accessToken, err := auth.GenerateAccessToken(user.ID, user.Role)
+// dummyPasswordHash es un hash con el mismo costo que los reales, para comparar cuando el email no existe
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
 	if err != nil {
-		return nil, "", "", err
+		panic(err)
 	}
+	return hash
+})
 
-	refreshToken, err := auth.GenerateRefreshToken(user.ID)
+// issueSession emite access y refresh token de una sesión nueva
+func (s *UserService) issueSession(ctx context.Context, user *domain.User, userAgent, ip string) (*domain.LoginResult, error) {
+	sessionID, err := auth.GenerateSessionID()
 	if err != nil {
-		return nil, "", "", err
+		return nil, err
 	}
 
-	err = s.tokenRepo.StoreRefreshToken(ctx, user.ID, refreshToken, auth.GetRefreshTokenTTL())
+	version, err := s.tokenRepo.GetTokenVersion(ctx, user.ID)
 	if err != nil {
-		return nil, "", "", err
+		return nil, err
+	}
+
+	accessToken, err := auth.GenerateAccessToken(user.ID, user.Role, sessionID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, jti, err := auth.GenerateRefreshToken(user.ID, sessionID)
+	if err != nil {
+		return nil, err
 	}
 
-	return user, accessToken, refreshToken, nil
+	now := time.Now()
+	ttl := auth.GetRefreshTokenTTL()
+	err = s.tokenRepo.CreateSession(ctx, domain.Session{
+		ID:         sessionID,
+		UserID:     user.ID,
+		CurrentJTI: jti,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.LoginResult{User: user, AccessToken: accessToken, RefreshToken: refreshToken}, nil
 }
 
 func (s *UserService) Refresh(ctx context.Context, refreshToken string) (*domain.User, string, string, error) {
@@ -85,12 +206,8 @@ func (s *UserService) Refresh(ctx context.Context, refreshToken string) (*domain
 		return nil, "", "", err
 	}
 
-	isValid, err := s.tokenRepo.IsValidRefreshToken(ctx, claims.UserID, refreshToken)
-	if err != nil {
-		return nil, "", "", err
-	}
-	if !isValid {
-		return nil, "", "", errors.New("refresh token inválido")
+	if claims.SessionID == "" {
+		return nil, "", "", utils.ErrInvalidRefreshToken
 	}
 
 	user, err := s.repo.GetByID(ctx, claims.UserID)
@@ -101,24 +218,62 @@ func (s *UserService) Refresh(ctx context.Context, refreshToken string) (*domain
 		return nil, "", "", errors.New("usuario inactivo")
 	}
 
-	newAccessToken, err := auth.GenerateAccessToken(user.ID, user.Role)
+	version, err := s.tokenRepo.GetTokenVersion(ctx, user.ID)
 	if err != nil {
 		return nil, "", "", err
 	}
 
-	newRefreshToken, err := auth.GenerateRefreshToken(user.ID)
+	newAccessToken, err := auth.GenerateAccessToken(user.ID, user.Role, claims.SessionID, version)
 	if err != nil {
 		return nil, "", "", err
 	}
 
-	err = s.tokenRepo.StoreRefreshToken(ctx, user.ID, newRefreshToken, auth.GetRefreshTokenTTL())
+	newRefreshToken, newJTI, err := auth.GenerateRefreshToken(user.ID, claims.SessionID)
 	if err != nil {
 		return nil, "", "", err
 	}
 
+	result, err := s.tokenRepo.RotateSession(ctx, user.ID, claims.SessionID, claims.ID, newJTI, auth.GetRefreshTokenTTL())
+	if err != nil {
+		return nil, "", "", err
+	}
+	switch result {
+	case repository.RotateReused:
+		slog.Warn("reuso de refresh token, sesión revocada", "user_id", user.ID, "session_id", claims.SessionID)
+		return nil, "", "", utils.ErrInvalidRefreshToken
+	case repository.RotateNotFound:
+		return nil, "", "", utils.ErrInvalidRefreshToken
+	}
+
 	return user, newAccessToken, newRefreshToken, nil
 }
 
+// ListSessions devuelve los dispositivos logueados del usuario y marca el de la request actual
+func (s *UserService) ListSessions(ctx context.Context, userID, currentSessionID string) ([]domain.Session, error) {
+	sessions, err := s.tokenRepo.ListSessions(ctx, userID)
+	if err != nil {
+		slog.Error("error al listar sesiones", "user_id", userID, "error", err)
+		return nil, utils.ErrInternal
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession cierra una sesión: su refresh token deja de servir
+func (s *UserService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	deleted, err := s.tokenRepo.DeleteSession(ctx, userID, sessionID)
+	if err != nil {
+		slog.Error("error al revocar sesión", "user_id", userID, "session_id", sessionID, "error", err)
+		return utils.ErrInternal
+	}
+	if !deleted {
+		return utils.ErrSessionNotFound
+	}
+	return nil
+}
+
 func (s *UserService) BootstrapAdmin(ctx context.Context, email, password, fullName, secret string) (*domain.User, error) {
 	expectedSecret := os.Getenv("ADMIN_BOOTSTRAP_SECRET")
 	if expectedSecret == "" || secret != expectedSecret {
@@ -153,14 +308,88 @@ func (s *UserService) BootstrapAdmin(ctx context.Context, email, password, fullN
 	return user, nil
 }
 
-func (s *UserService) ListUsers(ctx context.Context, role string, active *bool) ([]domain.User, error) {
-	return s.repo.ListUsers(ctx, role, active)
-}
-
-func (s *UserService) DeactivateUser(ctx context.Context, actorUserID, targetUserID string) error {
+// DeactivateUser desactiva al usuario y revoca en el acto todos sus tokens
+func (s *UserService) DeactivateUser(ctx context.Context, actorUserID, actorRole, targetUserID string) error {
 	if actorUserID == targetUserID {
 		return errors.New("no puedes desactivarte a vos mismo")
 	}
+	user, err := s.getUser(ctx, targetUserID)
+	if err != nil {
+		return err
+	}
+	if err := guardAdminTarget(actorRole, user); err != nil {
+		return err
+	}
 
-	return s.repo.DeactivateUser(ctx, targetUserID)
+	if err := s.repo.DeactivateUser(ctx, targetUserID); err != nil {
+		return err
+	}
+	return s.revokeAllTokens(ctx, targetUserID)
+}
+
+// Logout cierra la sesión actual: revoca su refresh token y el access token de la request
+func (s *UserService) Logout(ctx context.Context, userID, sessionID, accessJTI string, accessExpiresAt time.Time) error {
+	if err := s.tokenRepo.RevokeAccessToken(ctx, accessJTI, time.Until(accessExpiresAt)); err != nil {
+		slog.Error("error al revocar access token", "user_id", userID, "error", err)
+		return utils.ErrInternal
+	}
+	if sessionID != "" {
+		if _, err := s.tokenRepo.DeleteSession(ctx, userID, sessionID); err != nil {
+			slog.Error("error al cerrar sesión", "user_id", userID, "session_id", sessionID, "error", err)
+			return utils.ErrInternal
+		}
+	}
+	return nil
+}
+
+// LogoutAll cierra todas las sesiones del usuario en todos sus dispositivos
+func (s *UserService) LogoutAll(ctx context.Context, userID string) error {
+	if err := s.revokeAllTokens(ctx, userID); err != nil {
+		slog.Error("error al cerrar todas las sesiones", "user_id", userID, "error", err)
+		return utils.ErrInternal
+	}
+	return nil
+}
+
+// revokeAllTokens sube la versión de tokens (invalida los access tokens emitidos) y borra las sesiones
+func (s *UserService) revokeAllTokens(ctx context.Context, userID string) error {
+	if _, err := s.tokenRepo.BumpTokenVersion(ctx, userID); err != nil {
+		return err
+	}
+	return s.tokenRepo.DeleteAllSessions(ctx, userID)
+}
+
+// UnlockUser levanta el bloqueo por intentos fallidos de la cuenta
+func (s *UserService) UnlockUser(ctx context.Context, actorRole, userID string) error {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := guardAdminTarget(actorRole, user); err != nil {
+		return err
+	}
+
+	email := normalizeEmail(user.Email)
+	if err := s.guard.unlock(ctx, email); err != nil {
+		slog.Error("error al desbloquear cuenta", "user_id", userID, "error", err)
+		return utils.ErrInternal
+	}
+	if err := s.unlockMFA(ctx, userID); err != nil {
+		slog.Error("error al levantar bloqueo de doble factor", "user_id", userID, "error", err)
+		return utils.ErrInternal
+	}
+	s.guard.record(ctx, domain.LoginAttempt{Email: email, UserID: userID, Outcome: domain.LoginOutcomeUnlocked})
+	return nil
+}
+
+func (s *UserService) ListLoginAttempts(ctx context.Context, email, ip string, limit int) ([]domain.LoginAttempt, error) {
+	attempts, err := s.guard.attempts.List(ctx, normalizeEmail(email), ip, limit)
+	if err != nil {
+		slog.Error("error al listar intentos de login", "error", err)
+		return nil, utils.ErrInternal
+	}
+	if attempts == nil {
+		attempts = []domain.LoginAttempt{}
+	}
+	return attempts, nil
 }
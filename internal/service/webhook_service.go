@@ -0,0 +1,375 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+	"tracking/internal/domain"
+	"tracking/internal/dto"
+	"tracking/internal/repository"
+	"tracking/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultWebhookMaxAttempts = 8
+	defaultWebhookRetryBase   = 30 * time.Second
+	maxWebhookRetryDelay      = 6 * time.Hour
+	webhookPollInterval       = 5 * time.Second
+	webhookClaimBatch         = 20
+	webhookClaimLease         = time.Minute
+
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+type WebhookServiceInterface interface {
+	CreateSubscription(ctx context.Context, req dto.CreateWebhookRequest, adminID string) (dto.WebhookResponse, error)
+	ListSubscriptions(ctx context.Context) ([]dto.WebhookResponse, error)
+	UpdateSubscription(ctx context.Context, id string, req dto.UpdateWebhookRequest) (dto.WebhookResponse, error)
+	DeleteSubscription(ctx context.Context, id string) error
+	ListDeliveries(ctx context.Context, subscriptionID, status string) ([]domain.WebhookDelivery, error)
+	RetryDelivery(ctx context.Context, deliveryID string) error
+	Publish(ctx context.Context, event domain.OrderEvent) error
+}
+type WebhookService struct {
+	repo   repository.WebhookRepositoryInterface
+	client *http.Client
+}
+
+func NewWebhookService(repo repository.WebhookRepositoryInterface) *WebhookService {
+	return &WebhookService{
+		repo:   repo,
+		client: newWebhookClient(),
+	}
+}
+
+// webhookPayload es el JSON que recibe el endpoint suscripto
+type webhookPayload struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	OrderID    string                 `json:"order_id"`
+	ActorID    string                 `json:"actor_id,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	OccurredAt time.Time              `json:"occurred_at"`
+}
+
+func (s *WebhookService) CreateSubscription(ctx context.Context, req dto.CreateWebhookRequest, adminID string) (dto.WebhookResponse, error) {
+	if err := validateWebhook(ctx, req.URL, req.Events); err != nil {
+		return dto.WebhookResponse{}, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		generated, err := generateWebhookSecret()
+		if err != nil {
+			return dto.WebhookResponse{}, err
+		}
+		secret = generated
+	}
+
+	sub := domain.WebhookSubscription{
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    req.Events,
+		CreatedBy: adminID,
+	}
+	if err := s.repo.CreateSubscription(ctx, &sub); err != nil {
+		slog.Error("error al crear webhook", "url", req.URL, "error", err)
+		return dto.WebhookResponse{}, utils.ErrInternal
+	}
+
+	// El secret solo se muestra al crear la suscripción
+	response := utils.ToWebhookResponse(sub)
+	response.Secret = secret
+	return response, nil
+}
+
+func (s *WebhookService) ListSubscriptions(ctx context.Context) ([]dto.WebhookResponse, error) {
+	subs, err := s.repo.ListSubscriptions(ctx)
+	if err != nil {
+		slog.Error("error al listar webhooks", "error", err)
+		return nil, utils.ErrInternal
+	}
+	return utils.SliceWebhookDomainToResponseDto(subs), nil
+}
+
+func (s *WebhookService) UpdateSubscription(ctx context.Context, id string, req dto.UpdateWebhookRequest) (dto.WebhookResponse, error) {
+	current, err := s.repo.GetSubscription(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return dto.WebhookResponse{}, utils.ErrWebhookNotFound
+	}
+	if err != nil {
+		return dto.WebhookResponse{}, err
+	}
+
+	if req.URL != nil {
+		current.URL = *req.URL
+	}
+	if req.Events != nil {
+		current.Events = req.Events
+	}
+	if req.IsActive != nil {
+		current.IsActive = *req.IsActive
+	}
+
+	if err := validateWebhook(ctx, current.URL, current.Events); err != nil {
+		return dto.WebhookResponse{}, err
+	}
+
+	updated, err := s.repo.UpdateSubscription(ctx, current)
+	if err != nil {
+		return dto.WebhookResponse{}, err
+	}
+	return utils.ToWebhookResponse(updated), nil
+}
+
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id string) error {
+	err := s.repo.DeleteSubscription(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return utils.ErrWebhookNotFound
+	}
+	return err
+}
+
+func (s *WebhookService) ListDeliveries(ctx context.Context, subscriptionID, status string) ([]domain.WebhookDelivery, error) {
+	if _, err := s.repo.GetSubscription(ctx, subscriptionID); err != nil {
+		return nil, utils.ErrWebhookNotFound
+	}
+
+	deliveries, err := s.repo.ListDeliveries(ctx, subscriptionID, status)
+	if err != nil {
+		slog.Error("error al listar entregas de webhook", "subscription_id", subscriptionID, "error", err)
+		return nil, utils.ErrInternal
+	}
+	if deliveries == nil {
+		return []domain.WebhookDelivery{}, nil
+	}
+	return deliveries, nil
+}
+
+// RetryDelivery reencola una entrega que quedó en dead-letter
+func (s *WebhookService) RetryDelivery(ctx context.Context, deliveryID string) error {
+	err := s.repo.RequeueDelivery(ctx, deliveryID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return utils.ErrWebhookNotFound
+	}
+	return err
+}
+
+// Publish encola una entrega por cada suscripción activa que escucha el tipo de evento.
+// El envío real lo hace RunDeliveryWorker.
+func (s *WebhookService) Publish(ctx context.Context, event domain.OrderEvent) error {
+	subs, err := s.repo.ListActiveForEvent(ctx, event.Type)
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(webhookPayload{
+		ID:         event.ID,
+		Type:       event.Type,
+		OrderID:    event.OrderID,
+		ActorID:    event.ActorID,
+		Data:       event.Data,
+		OccurredAt: event.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subs {
+		delivery := domain.WebhookDelivery{
+			SubscriptionID: sub.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        payload,
+		}
+		if _, err := s.repo.EnqueueDelivery(ctx, &delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunDeliveryWorker envía las entregas pendientes hasta que se cancele el contexto
+func (s *WebhookService) RunDeliveryWorker(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.processDueDeliveries(ctx)
+		}
+	}
+}
+
+func (s *WebhookService) processDueDeliveries(ctx context.Context) {
+	deliveries, err := s.repo.ClaimDueDeliveries(ctx, webhookClaimBatch, webhookClaimLease)
+	if err != nil {
+		slog.Error("error al tomar entregas de webhook", "error", err)
+		return
+	}
+
+	subs := make(map[string]domain.WebhookSubscription)
+	for _, d := range deliveries {
+		sub, ok := subs[d.SubscriptionID]
+		if !ok {
+			sub, err = s.repo.GetSubscription(ctx, d.SubscriptionID)
+			if err != nil {
+				slog.Error("suscripción de webhook no encontrada", "subscription_id", d.SubscriptionID, "error", err)
+				continue
+			}
+			subs[d.SubscriptionID] = sub
+		}
+		s.attemptDelivery(ctx, sub, d)
+	}
+}
+
+func (s *WebhookService) attemptDelivery(ctx context.Context, sub domain.WebhookSubscription, d domain.WebhookDelivery) {
+	attempts := d.Attempts + 1
+
+	statusCode, err := s.send(ctx, sub, d)
+	if err == nil {
+		if markErr := s.repo.MarkDelivered(ctx, d.ID, attempts, statusCode); markErr != nil {
+			slog.Error("error al marcar webhook entregado", "delivery_id", d.ID, "error", markErr)
+		}
+		return
+	}
+
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+
+	dead := attempts >= getWebhookMaxAttempts() || !sub.IsActive
+	nextAttempt := time.Now().Add(webhookBackoff(attempts))
+	if dead {
+		slog.Warn("webhook enviado a dead-letter", "delivery_id", d.ID, "subscription_id", sub.ID, "attempts", attempts, "error", err)
+	}
+
+	if markErr := s.repo.MarkFailed(ctx, d.ID, attempts, code, err.Error(), nextAttempt, dead); markErr != nil {
+		slog.Error("error al registrar fallo de webhook", "delivery_id", d.ID, "error", markErr)
+	}
+}
+
+// send hace el POST firmado. La firma es HMAC-SHA256 de "<timestamp>.<body>" con el secret de la suscripción.
+func (s *WebhookService) send(ctx context.Context, sub domain.WebhookSubscription, d domain.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TrackingApp-Webhooks")
+	req.Header.Set(WebhookEventHeader, d.EventType)
+	req.Header.Set(WebhookDeliveryHeader, d.ID)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhookPayload(sub.Secret, timestamp, d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("el endpoint respondió %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhookPayload calcula la firma que el receptor debe verificar
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff duplica la espera en cada intento fallido, con un tope
+func webhookBackoff(attempts int) time.Duration {
+	delay := getWebhookRetryBase()
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxWebhookRetryDelay {
+			return maxWebhookRetryDelay
+		}
+	}
+	return delay
+}
+
+func validateWebhook(ctx context.Context, rawURL string, events []string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return utils.ErrInvalidWebhookURL
+	}
+	if err := checkWebhookHost(ctx, parsed.Hostname()); err != nil {
+		return utils.ErrWebhookURLNotPublic
+	}
+
+	if len(events) == 0 {
+		return utils.ErrInvalidWebhookEvent
+	}
+	for _, e := range events {
+		valid := false
+		for _, known := range domain.OrderEventTypes {
+			if e == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return utils.ErrInvalidWebhookEvent
+		}
+	}
+	return nil
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func getWebhookMaxAttempts() int {
+	attempts, err := strconv.Atoi(os.Getenv("WEBHOOK_MAX_ATTEMPTS"))
+	if err == nil && attempts > 0 {
+		return attempts
+	}
+	return defaultWebhookMaxAttempts
+}
+
+func getWebhookRetryBase() time.Duration {
+	seconds, err := strconv.Atoi(os.Getenv("WEBHOOK_RETRY_BASE_SECONDS"))
+	if err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return defaultWebhookRetryBase
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	body := []byte(`{"type":"ORDER_DELIVERED"}`)
+	const want = "31edc113a873f72c13d64cd7025a4ca658449823ce347602f5bb6b0f4ce60aba"
+
+	if got := SignWebhookPayload("whsec_test", "1700000000", body); got != want {
+		t.Errorf("firma = %s, se esperaba %s", got, want)
+	}
+	// El timestamp forma parte de lo firmado: una entrega reenviada con otro timestamp no verifica
+	if got := SignWebhookPayload("whsec_test", "1700000001", body); got == want {
+		t.Error("la firma no depende del timestamp")
+	}
+	if got := SignWebhookPayload("otro_secret", "1700000000", body); got == want {
+		t.Error("la firma no depende del secret")
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	t.Setenv("WEBHOOK_RETRY_BASE_SECONDS", "")
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{6, 16 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, maxWebhookRetryDelay},
+		{50, maxWebhookRetryDelay},
+	}
+
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.attempts); got != tt.want {
+			t.Errorf("webhookBackoff(%d) = %v, se esperaba %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookBackoffCustomBase(t *testing.T) {
+	t.Setenv("WEBHOOK_RETRY_BASE_SECONDS", "5")
+
+	if got := webhookBackoff(3); got != 20*time.Second {
+		t.Errorf("webhookBackoff(3) = %v, se esperaba 20s", got)
+	}
+}
+
+func TestWebhookIPAllowed(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE", "")
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.0.0.5", false},
+		{"172.16.3.4", false},
+		{"192.168.1.10", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"224.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := webhookIPAllowed(netip.MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("webhookIPAllowed(%s) = %v, se esperaba %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookIPAllowedPrivateOverride(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE", "true")
+
+	if !webhookIPAllowed(netip.MustParseAddr("127.0.0.1")) {
+		t.Error("con WEBHOOK_ALLOW_PRIVATE=true se debería aceptar loopback")
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Rangos a los que un webhook nunca puede apuntar: la red interna, loopback y la metadata del
+// proveedor cloud (169.254.169.254). Se chequea al registrar y de nuevo al conectar, porque el DNS
+// de un host público puede cambiar después de registrarlo.
+var blockedWebhookPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+var errWebhookTargetBlocked = errors.New("el destino del webhook es una dirección interna")
+
+// webhookIPAllowed rechaza loopback, link-local, redes privadas y otras direcciones no públicas
+func webhookIPAllowed(ip netip.Addr) bool {
+	if allowPrivateWebhooks() {
+		return true
+	}
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blockedWebhookPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkWebhookHost resuelve el host y exige que todas sus direcciones sean públicas
+func checkWebhookHost(ctx context.Context, host string) error {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if !webhookIPAllowed(ip) {
+			return errWebhookTargetBlocked
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return errWebhookTargetBlocked
+	}
+	for _, ip := range addrs {
+		if !webhookIPAllowed(ip) {
+			return errWebhookTargetBlocked
+		}
+	}
+	return nil
+}
+
+// newWebhookClient valida cada conexión contra la IP ya resuelta, incluidas las de los redirects.
+// No usa el proxy del entorno: con proxy la IP que se valida sería la del proxy.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil || !webhookIPAllowed(ip) {
+				return errWebhookTargetBlocked
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
+
+// allowPrivateWebhooks habilita destinos internos para desarrollo local (WEBHOOK_ALLOW_PRIVATE=true)
+func allowPrivateWebhooks() bool {
+	allowed, _ := strconv.ParseBool(os.Getenv("WEBHOOK_ALLOW_PRIVATE"))
+	return allowed
+}
@@ -0,0 +1,57 @@
+// Package statement genera los resúmenes de ganancias descargables (CSV y PDF)
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+	"tracking/internal/domain"
+)
+
+const dateLayout = "2006-01-02"
+
+// WriteCSV escribe una fila por pedido y una fila final con los totales
+func WriteCSV(w io.Writer, report domain.EarningsReport) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{"order_id", "delivered_at", "destination_address", "distance_km", "base_amount", "distance_amount", "tip_amount", "total", "paid_amount"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, o := range report.Orders {
+		row := []string{
+			o.OrderID,
+			o.DeliveredAt.In(report.From.Location()).Format(time.RFC3339),
+			o.DestinationAddress,
+			amount(o.DistanceKm),
+			amount(o.BaseAmount),
+			amount(o.DistanceAmount),
+			amount(o.TipAmount),
+			amount(o.Total),
+			amount(o.PaidAmount),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	s := report.Summary
+	totals := []string{"TOTAL", "", "", amount(s.DistanceKm), amount(s.BaseAmount), amount(s.DistanceAmount), amount(s.TipAmount), amount(s.Total), amount(s.Paid)}
+	if err := cw.Write(totals); err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// Filename arma el nombre del archivo a partir del período (to es exclusivo)
+func Filename(report domain.EarningsReport, ext string) string {
+	return "ganancias_" + report.From.Format(dateLayout) + "_" + report.To.AddDate(0, 0, -1).Format(dateLayout) + "." + ext
+}
+
+func amount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
@@ -0,0 +1,139 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"tracking/internal/domain"
+)
+
+// Página A4 en puntos, con Courier para que las columnas queden alineadas
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	marginLeft   = 40
+	marginTop    = 60
+	fontSize     = 9
+	lineHeight   = 12
+	linesPerPage = (pageHeight - 2*marginTop) / lineHeight
+)
+
+// WritePDF genera un PDF simple (solo texto) sin dependencias externas
+func WritePDF(w io.Writer, report domain.EarningsReport) error {
+	lines := statementLines(report)
+
+	var pages [][]string
+	for len(lines) > linesPerPage {
+		pages = append(pages, lines[:linesPerPage])
+		lines = lines[linesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	// Objetos: 1 catálogo, 2 árbol de páginas, 3 fuente y luego página + contenido por cada hoja
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+	)
+	for i, page := range pages {
+		content := pageContent(page, i+1, len(pages))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+				pageWidth, pageHeight, 5+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func statementLines(report domain.EarningsReport) []string {
+	loc := report.From.Location()
+	s := report.Summary
+
+	lines := []string{
+		"RESUMEN DE GANANCIAS",
+		"",
+		"Repartidor: " + report.DriverName,
+		fmt.Sprintf("Periodo:    %s al %s", report.From.Format("02/01/2006"), report.To.AddDate(0, 0, -1).Format("02/01/2006")),
+		"",
+		fmt.Sprintf("%-16s %-22s %7s %9s %9s %9s %10s", "Fecha", "Destino", "Km", "Base", "Km $", "Propina", "Total"),
+		strings.Repeat("-", 88),
+	}
+	for _, o := range report.Orders {
+		lines = append(lines, fmt.Sprintf("%-16s %-22s %7.2f %9.2f %9.2f %9.2f %10.2f",
+			o.DeliveredAt.In(loc).Format("02/01/2006 15:04"),
+			truncate(o.DestinationAddress, 22),
+			o.DistanceKm, o.BaseAmount, o.DistanceAmount, o.TipAmount, o.Total,
+		))
+	}
+	lines = append(lines,
+		strings.Repeat("-", 88),
+		fmt.Sprintf("%-16s %-22s %7.2f %9.2f %9.2f %9.2f %10.2f", "TOTAL", fmt.Sprintf("%d entregas", s.Deliveries),
+			s.DistanceKm, s.BaseAmount, s.DistanceAmount, s.TipAmount, s.Total),
+		"",
+		fmt.Sprintf("Pagado:    %12.2f", s.Paid),
+		fmt.Sprintf("Pendiente: %12.2f", s.Pending),
+	)
+	return lines
+}
+
+func pageContent(lines []string, page, total int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", fontSize, lineHeight, marginLeft, pageHeight-marginTop)
+	for _, line := range lines {
+		fmt.Fprintf(&b, "(%s) '\n", pdfString(line))
+	}
+	fmt.Fprintf(&b, "ET\nBT\n/F1 %d Tf\n%d %d Td\n(%s) Tj\nET", fontSize, marginLeft, marginTop/2,
+		pdfString(fmt.Sprintf("Pagina %d de %d", page, total)))
+	return b.String()
+}
+
+// pdfString pasa el texto a WinAnsi (Latin-1 para los acentos del español) y escapa los paréntesis
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x80:
+			b.WriteRune(r)
+		case r <= 0xFF:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "."
+}
@@ -0,0 +1,173 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"tracking/internal/domain"
+)
+
+func testReport(orders int) domain.EarningsReport {
+	loc := time.FixedZone("ART", -3*60*60)
+	report := domain.EarningsReport{
+		DriverID:   "driver-1",
+		DriverName: "José Pérez",
+		From:       time.Date(2026, time.October, 1, 0, 0, 0, 0, loc),
+		To:         time.Date(2026, time.November, 1, 0, 0, 0, 0, loc),
+	}
+	for i := 0; i < orders; i++ {
+		report.Orders = append(report.Orders, domain.OrderEarning{
+			OrderID:            fmt.Sprintf("order-%d", i+1),
+			DestinationAddress: "Bv. Roca 1234, Rafaela",
+			DeliveredAt:        time.Date(2026, time.October, 5, 15, 30, 0, 0, time.UTC),
+			DistanceKm:         2.6,
+			BaseAmount:         800,
+			DistanceAmount:     650,
+			TipAmount:          100.5,
+			Total:              1550.5,
+			PaidAmount:         0,
+		})
+		report.Summary.Deliveries++
+		report.Summary.DistanceKm += 2.6
+		report.Summary.BaseAmount += 800
+		report.Summary.DistanceAmount += 650
+		report.Summary.TipAmount += 100.5
+		report.Summary.Total += 1550.5
+		report.Summary.Pending += 1550.5
+	}
+	return report
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, testReport(2)); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("CSV inválido: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("filas = %d, se esperaban 4 (encabezado, 2 pedidos y totales)", len(rows))
+	}
+	if rows[0][0] != "order_id" || rows[0][8] != "paid_amount" {
+		t.Errorf("encabezado = %v", rows[0])
+	}
+
+	want := []string{"order-1", "2026-10-05T12:30:00-03:00", "Bv. Roca 1234, Rafaela", "2.60", "800.00", "650.00", "100.50", "1550.50", "0.00"}
+	if strings.Join(rows[1], "|") != strings.Join(want, "|") {
+		t.Errorf("fila = %v, se esperaba %v", rows[1], want)
+	}
+
+	wantTotals := []string{"TOTAL", "", "", "5.20", "1600.00", "1300.00", "201.00", "3101.00", "0.00"}
+	if strings.Join(rows[3], "|") != strings.Join(wantTotals, "|") {
+		t.Errorf("totales = %v, se esperaba %v", rows[3], wantTotals)
+	}
+}
+
+func TestFilename(t *testing.T) {
+	if got := Filename(testReport(0), "pdf"); got != "ganancias_2026-10-01_2026-10-31.pdf" {
+		t.Errorf("Filename = %s", got)
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	tests := []struct {
+		name      string
+		orders    int
+		wantPages int
+	}{
+		{"sin pedidos", 0, 1},
+		{"una página", 10, 1},
+		{"varias páginas", 2 * linesPerPage, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WritePDF(&buf, testReport(tt.orders)); err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			pdf := buf.Bytes()
+
+			if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+				t.Fatalf("encabezado o cierre del PDF inválidos")
+			}
+			if got := bytes.Count(pdf, []byte("/Type /Page ")); got != tt.wantPages {
+				t.Errorf("páginas = %d, se esperaban %d", got, tt.wantPages)
+			}
+			if !bytes.Contains(pdf, []byte(fmt.Sprintf("/Count %d", tt.wantPages))) {
+				t.Errorf("el árbol de páginas no declara %d páginas", tt.wantPages)
+			}
+			checkXref(t, pdf)
+		})
+	}
+}
+
+// checkXref verifica que startxref y cada entrada de la tabla apunten al lugar correcto
+func checkXref(t *testing.T, pdf []byte) {
+	t.Helper()
+
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if m == nil {
+		t.Fatal("falta startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(pdf[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref = %d no apunta a la tabla xref", xref)
+	}
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n \n`).FindAllSubmatch(pdf[xref:], -1)
+	if len(entries) == 0 {
+		t.Fatal("la tabla xref no tiene entradas")
+	}
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(pdf[off:], []byte(want)) {
+			t.Errorf("la entrada %d apunta a %d, que no es el objeto %d", i+1, off, i+1)
+		}
+	}
+}
+
+func TestPdfString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Total", "Total"},
+		{"(nota)", `\(nota\)`},
+		{`C:\ruta`, `C:\\ruta`},
+		{"Pérez", `P\351rez`},
+		{"año", `a\361o`},
+		{"€ 100", "? 100"},
+	}
+
+	for _, tt := range tests {
+		if got := pdfString(tt.in); got != tt.want {
+			t.Errorf("pdfString(%q) = %q, se esperaba %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"Roca 1234", 22, "Roca 1234"},
+		{"Bv. Santa Fe 1234, Rafaela", 10, "Bv. Santa."},
+		{"Güemes ñandú", 6, "Güeme."},
+	}
+
+	for _, tt := range tests {
+		if got := truncate(tt.in, tt.max); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, se esperaba %q", tt.in, tt.max, got, tt.want)
+		}
+	}
+}
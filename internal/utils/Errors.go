@@ -3,6 +3,7 @@ package utils
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -20,6 +21,14 @@ var (
 	ErrInvalidState        = errors.New("la acción no es válida para el estado actual del pedido")
 )
 
+// Errores del tracking en tiempo real
+var (
+	ErrImpossibleLocationJump = errors.New("la ubicación reportada implica una velocidad imposible")
+	ErrInvalidLocationBatch   = errors.New("el lote de ubicaciones contiene fixes inválidos")
+	ErrStaleLocationFix       = errors.New("la ubicación no es posterior a la última registrada")
+	ErrShareLinkNotFound      = errors.New("link de seguimiento inválido, vencido o revocado")
+)
+
 // ErrorResponse es la estructura estándar para todas las respuestas de error
 type ErrorResponse struct {
 	Code       string            `json:"code"`
@@ -105,3 +114,154 @@ func HandleValidationErrors(err error) *AppError {
 	// Fallback para errores que no son de validación
 	return ValidationError(map[string]string{"error": "Error en la estructura del JSON"})
 }
+
+// Errores de webhooks
+var (
+	ErrWebhookNotFound     = errors.New("webhook no encontrado")
+	ErrInvalidWebhookEvent = errors.New("tipo de evento de webhook inválido")
+	ErrInvalidWebhookURL   = errors.New("la URL del webhook debe ser http o https")
+	ErrWebhookURLNotPublic = errors.New("la URL del webhook debe resolver a una dirección pública")
+)
+
+// Errores de notificaciones
+var (
+	ErrInvalidLocale     = errors.New("idioma no soportado")
+	ErrPhoneRequired     = errors.New("se necesita un teléfono para activar los SMS")
+	ErrPushTokenRequired = errors.New("se necesita un token de dispositivo para activar las notificaciones push")
+)
+
+// Errores de la libreta de direcciones
+var (
+	ErrAddressNotFound     = errors.New("dirección no encontrada")
+	ErrAddressLabelTaken   = errors.New("ya tenés una dirección con esa etiqueta")
+	ErrAddressOutsideArea  = errors.New("la ubicación está fuera del área de entrega")
+	ErrDestinationRequired = errors.New("se requiere destination_address o address_id")
+)
+
+// Errores de geocoding
+var (
+	ErrGeoRateLimited       = errors.New("demasiadas búsquedas, probá de nuevo en unos segundos")
+	ErrGeocodingUnavailable = errors.New("el servicio de direcciones no está disponible")
+)
+
+// RateLimitError indica que se superó un límite y cuánto falta para poder reintentar
+type RateLimitError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// Errores de pedidos programados
+var (
+	ErrInvalidDeliveryWindow = errors.New("la franja de entrega no es válida")
+	ErrDeliveryWindowClosed  = errors.New("la franja de entrega está fuera del horario del local")
+	ErrDeliverySlotFull      = errors.New("la franja de entrega no tiene más lugar")
+	ErrInvalidDate           = errors.New("fecha inválida, usar el formato AAAA-MM-DD")
+)
+
+// Errores de horarios del local
+var (
+	ErrInvalidStoreHours      = errors.New("horario inválido: usar HH:MM, cierre posterior a la apertura y sin rangos superpuestos")
+	ErrStoreExceptionNotFound = errors.New("no hay una excepción de horario para esa fecha")
+)
+
+// Errores de reseñas
+var (
+	ErrReviewNotAllowed    = errors.New("solo se pueden calificar pedidos entregados")
+	ErrReviewWindowExpired = errors.New("venció el plazo para calificar este pedido")
+	ErrReviewAlreadyExists = errors.New("el pedido ya fue calificado")
+	ErrReviewNotFound      = errors.New("reseña no encontrada")
+	ErrInvalidReviewTag    = errors.New("etiqueta de reseña inválida")
+)
+
+// Errores de propinas
+var (
+	ErrTipNotAllowed   = errors.New("solo se puede dejar propina en pedidos entregados")
+	ErrTipAlreadyAdded = errors.New("ya se dejó una propina después de la entrega")
+)
+
+// Errores de ganancias y liquidaciones
+var (
+	ErrDriverNotFound         = errors.New("repartidor no encontrado")
+	ErrInvalidPeriod          = errors.New("período inválido")
+	ErrNoEarningsToPay        = errors.New("no hay ganancias pendientes de liquidar")
+	ErrPayoutNotFound         = errors.New("liquidación no encontrada")
+	ErrInvalidStatementFormat = errors.New("formato de resumen inválido, usar csv o pdf")
+)
+
+// Errores de sesiones
+var (
+	ErrInvalidRefreshToken = errors.New("refresh token inválido o expirado")
+	ErrSessionNotFound     = errors.New("sesión no encontrada")
+)
+
+// Errores de login
+var (
+	ErrInvalidCredentials   = errors.New("credenciales inválidas")
+	ErrTooManyLoginAttempts = errors.New("demasiados intentos fallidos, probá de nuevo más tarde")
+	ErrUserNotFound         = errors.New("usuario no encontrado")
+	ErrLoginUnavailable     = errors.New("el inicio de sesión no está disponible, probá de nuevo en unos minutos")
+)
+
+// Errores de contraseñas
+var (
+	ErrInvalidResetToken = errors.New("el link para restablecer la contraseña es inválido o venció")
+	ErrWrongPassword     = errors.New("la contraseña actual no es correcta")
+)
+
+// Errores de verificación de email
+var (
+	ErrEmailNotVerified          = errors.New("tenés que verificar tu email antes de hacer pedidos")
+	ErrEmailAlreadyVerified      = errors.New("el email ya está verificado")
+	ErrInvalidVerificationToken  = errors.New("el link de verificación es inválido o venció")
+	ErrTooManyVerificationEmails = errors.New("demasiados reenvíos del email de verificación, intentá más tarde")
+)
+
+// Errores de doble factor
+var (
+	ErrInvalidMFAToken    = errors.New("el desafío de doble factor es inválido o venció, iniciá sesión de nuevo")
+	ErrInvalidMFACode     = errors.New("código de verificación inválido")
+	ErrTooManyMFAAttempts = errors.New("demasiados códigos inválidos, iniciá sesión de nuevo")
+	ErrMFANotEnrolled     = errors.New("el doble factor no está configurado")
+	ErrMFAAlreadyEnabled  = errors.New("el doble factor ya está activado")
+	ErrMFARequired        = errors.New("el doble factor es obligatorio para tu rol")
+)
+
+// Errores de API keys
+var (
+	ErrInvalidAPIKey      = errors.New("API key inválida, vencida o revocada")
+	ErrAPIKeyIPNotAllowed = errors.New("la API key no está habilitada para esta IP")
+	ErrAPIKeyScopeMissing = errors.New("la API key no tiene permiso para esta operación")
+	ErrAPIKeyNotFound     = errors.New("API key no encontrada")
+	ErrInvalidAPIKeyScope = errors.New("scope de API key inválido o repetido")
+	ErrInvalidAllowedIP   = errors.New("IP o rango CIDR inválido en allowed_ips")
+	ErrAPIKeyAdminAccount = errors.New("no se pueden emitir API keys para cuentas admin")
+)
+
+// Errores de roles y permisos
+var (
+	ErrRoleNotFound      = errors.New("rol no encontrado")
+	ErrRoleAlreadyExists = errors.New("ya existe un rol con ese nombre")
+	ErrInvalidRoleName   = errors.New("el nombre del rol debe ser en minúsculas, letras, números o guion bajo")
+	ErrInvalidPermission = errors.New("permiso inexistente o repetido")
+	ErrOwnRoleLocked     = errors.New("no podés editar los permisos de tu propio rol")
+	ErrAdminRoleLocked   = errors.New("el rol admin tiene todos los permisos y no se puede editar")
+	ErrSystemRole        = errors.New("los roles de sistema no se pueden borrar")
+	ErrRoleInUse         = errors.New("el rol tiene usuarios asignados")
+)
+
+// Errores de administración de usuarios
+var (
+	ErrUserAlreadyActive   = errors.New("el usuario ya está activo")
+	ErrCannotChangeOwnRole = errors.New("no podés cambiar tu propio rol")
+	ErrAdminRoleChange     = errors.New("solo un admin puede dar o quitar el rol admin")
+	ErrAdminTarget         = errors.New("solo un admin puede administrar otra cuenta admin")
+	ErrEmailTaken          = errors.New("el email ya está en uso por otra cuenta")
+)
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"tracking/internal/domain"
+	"tracking/internal/dto"
+)
+
+func ToAddressResponse(a domain.Address) dto.AddressResponse {
+	return dto.AddressResponse{
+		ID:           a.ID,
+		Label:        a.Label,
+		Address:      a.Address,
+		Apartment:    a.Apartment,
+		Instructions: a.Instructions,
+		Lat:          a.Lat,
+		Lng:          a.Lng,
+		PinAdjusted:  a.PinAdjusted,
+		CreatedAt:    a.CreatedAt,
+		UpdatedAt:    a.UpdatedAt,
+	}
+}
+
+func SliceAddressDomainToResponseDto(addresses []domain.Address) []dto.AddressResponse {
+	res := make([]dto.AddressResponse, len(addresses))
+	for i, a := range addresses {
+		res[i] = ToAddressResponse(a)
+	}
+	return res
+}
@@ -14,15 +14,20 @@ func OrderDomainToResponseOrderDto(order domain.Order, customerName string, item
 		})
 	}
 	return dto.OrderResponse{
-		ID:                 order.ID,
-		DriverID:           order.DriverID,
-		CustomerID:         order.CustomerID,
-		CustomerName:       order.CustomerName,
-		DestinationAddress: order.DestinationAddress,
-		TotalPrice:         order.TotalPrice,
-		Status:             order.Status,
-		Items:              itemsDto,
-		CreatedAt:          order.CreatedAt,
+		ID:                   order.ID,
+		DriverID:             order.DriverID,
+		CustomerID:           order.CustomerID,
+		CustomerName:         order.CustomerName,
+		DestinationAddress:   order.DestinationAddress,
+		Apartment:            order.Apartment,
+		DeliveryInstructions: order.DeliveryInstructions,
+		TotalPrice:           order.TotalPrice,
+		TipAmount:            order.TipAmount,
+		ScheduledStart:       order.ScheduledStart,
+		ScheduledEnd:         order.ScheduledEnd,
+		Status:               order.Status,
+		Items:                itemsDto,
+		CreatedAt:            order.CreatedAt,
 	}
 }
 
@@ -38,6 +43,17 @@ func SliceOrderDomainToOrderResponseListDto(orders []domain.Order) []dto.OrderRe
 	return res
 }
 
+func SliceOrderEventDomainToResponseDto(events []domain.OrderEvent) []dto.OrderEventResponse {
+	res := make([]dto.OrderEventResponse, len(events))
+	for i, e := range events {
+		res[i] = dto.OrderEventResponse{
+			Type:      e.Type,
+			Data:      e.Data,
+			CreatedAt: e.CreatedAt,
+		}
+	}
+	return res
+}
 
 func ToOrderDomain(req dto.CreateOrderRequest, customerID string) *domain.Order {
 	var items []domain.OrderItem
@@ -51,6 +67,8 @@ func ToOrderDomain(req dto.CreateOrderRequest, customerID string) *domain.Order
 	return &domain.Order{
 		CustomerID:         customerID,
 		DestinationAddress: req.DestinationAddress,
+		AddressID:          req.AddressID,
+		TipAmount:          req.TipAmount,
 		Status:             "PENDING",
 		Items:              items,
 	}
@@ -16,11 +16,13 @@ func ToProductDomain(req dto.UpsertProductRequest) domain.Product {
 
 func ToProductResponse(p domain.Product) dto.ProductResponse {
 	return dto.ProductResponse{
-		ID:          p.ID,
-		Name:        p.Name,
-		Price:       p.Price,
-		Description: p.Description,
-		IsActive:    p.IsActive,
+		ID:            p.ID,
+		Name:          p.Name,
+		Price:         p.Price,
+		Description:   p.Description,
+		IsActive:      p.IsActive,
+		RatingAverage: p.Rating.Average,
+		RatingCount:   p.Rating.Count,
 	}
 }
 
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"tracking/internal/domain"
+	"tracking/internal/dto"
+)
+
+func ToShareLinkResponse(l domain.ShareLink) dto.ShareLinkResponse {
+	return dto.ShareLinkResponse{
+		ID:        l.ID,
+		ExpiresAt: l.ExpiresAt,
+		RevokedAt: l.RevokedAt,
+		CreatedAt: l.CreatedAt,
+	}
+}
+
+func SliceShareLinkDomainToResponseDto(links []domain.ShareLink) []dto.ShareLinkResponse {
+	res := make([]dto.ShareLinkResponse, len(links))
+	for i, l := range links {
+		res[i] = ToShareLinkResponse(l)
+	}
+	return res
+}
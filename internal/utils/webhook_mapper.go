@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"tracking/internal/domain"
+	"tracking/internal/dto"
+)
+
+func ToWebhookResponse(w domain.WebhookSubscription) dto.WebhookResponse {
+	return dto.WebhookResponse{
+		ID:        w.ID,
+		URL:       w.URL,
+		Events:    w.Events,
+		IsActive:  w.IsActive,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+func SliceWebhookDomainToResponseDto(subs []domain.WebhookSubscription) []dto.WebhookResponse {
+	res := make([]dto.WebhookResponse, len(subs))
+	for i, w := range subs {
+		res[i] = ToWebhookResponse(w)
+	}
+	return res
+}